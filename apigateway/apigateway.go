@@ -0,0 +1,138 @@
+// Package apigateway provides an adapter to run routers on AWS Lambda behind Amazon API Gateway.
+//
+// This is a separate package so that applications that do not use AWS Lambda do not have to depend on aws-lambda-go.
+//
+//	r, err := interactionrouter.New(interactionrouter.WithSigningSecret("YOUR_SIGNING_SECRET"))
+//	// ...
+//	lambda.Start(apigateway.Handler(r))
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// Handler returns a Lambda handler that converts an API Gateway proxy request into an `*http.Request`,
+// passes it to h, and converts the response back into an API Gateway proxy response.
+//
+// The body is decoded when `IsBase64Encoded` is set, and headers are copied as they are,
+// so signature verification works in the same way as plain HTTP servers.
+//
+// h is typically `*eventrouter.Router` or `*interactionrouter.Router`.
+func Handler(h http.Handler) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, proxyReq events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req, err := NewRequest(ctx, proxyReq)
+		if err != nil {
+			return events.APIGatewayProxyResponse{}, err
+		}
+		w := newResponseWriter()
+		h.ServeHTTP(w, req)
+		return w.proxyResponse(), nil
+	}
+}
+
+// NewRequest reconstructs an `*http.Request` from an API Gateway proxy request.
+func NewRequest(ctx context.Context, proxyReq events.APIGatewayProxyRequest) (*http.Request, error) {
+	body := []byte(proxyReq.Body)
+	if proxyReq.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(proxyReq.Body)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to decode base64-encoded body")
+		}
+	}
+
+	u := url.URL{Path: proxyReq.Path}
+	query := url.Values{}
+	for k, vs := range proxyReq.MultiValueQueryStringParameters {
+		query[k] = append([]string{}, vs...)
+	}
+	for k, v := range proxyReq.QueryStringParameters {
+		if _, ok := query[k]; !ok {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	method := proxyReq.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to build request")
+	}
+	for k, vs := range proxyReq.MultiValueHeaders {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	for k, v := range proxyReq.Headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	req.RemoteAddr = proxyReq.RequestContext.Identity.SourceIP
+	return req, nil
+}
+
+type responseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: http.Header{}}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(p)
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.statusCode != 0 {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+func (w *responseWriter) proxyResponse() events.APIGatewayProxyResponse {
+	statusCode := w.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	res := events.APIGatewayProxyResponse{
+		StatusCode:        statusCode,
+		Headers:           map[string]string{},
+		MultiValueHeaders: map[string][]string{},
+	}
+	for k, vs := range w.header {
+		if len(vs) == 0 {
+			continue
+		}
+		res.Headers[k] = vs[0]
+		res.MultiValueHeaders[k] = append([]string{}, vs...)
+	}
+	if utf8.Valid(w.body.Bytes()) {
+		res.Body = w.body.String()
+	} else {
+		res.Body = base64.StdEncoding.EncodeToString(w.body.Bytes())
+		res.IsBase64Encoded = true
+	}
+	return res
+}
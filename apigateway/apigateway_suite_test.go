@@ -0,0 +1,13 @@
+package apigateway_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestApigateway(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Apigateway Suite")
+}
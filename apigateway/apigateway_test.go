@@ -0,0 +1,147 @@
+package apigateway_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	"github.com/genkami/go-slack-event-router/apigateway"
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/internal/testutils"
+)
+
+var _ = Describe("APIGateway", func() {
+	Describe("Handler", func() {
+		var (
+			r                *ir.Router
+			numHandlerCalled int
+			signingSecret    = "THE_SIGNING_SECRET"
+			payload          = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(signingSecret))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled = 0
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			}))
+		})
+
+		newProxyRequest := func(body []byte, base64Encoded bool) events.APIGatewayProxyRequest {
+			h := http.Header{}
+			err := testutils.AddSignature(h, []byte(signingSecret), body, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			proxyReq := events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodPost,
+				Path:       "/slack/interactions",
+				Headers: map[string]string{
+					"Content-Type":              "application/x-www-form-urlencoded",
+					"X-Slack-Signature":         h.Get("X-Slack-Signature"),
+					"X-Slack-Request-Timestamp": h.Get("X-Slack-Request-Timestamp"),
+				},
+				Body: string(body),
+			}
+			if base64Encoded {
+				proxyReq.Body = base64.StdEncoding.EncodeToString(body)
+				proxyReq.IsBase64Encoded = true
+			}
+			return proxyReq
+		}
+
+		buildRequestBody := func() []byte {
+			form := url.Values{}
+			form.Set("payload", payload)
+			return []byte(form.Encode())
+		}
+
+		Context("when the body is base64-encoded", func() {
+			It("decodes the body and calls the handler", func() {
+				handler := apigateway.Handler(r)
+				res, err := handler(context.Background(), newProxyRequest(buildRequestBody(), true))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the body is not base64-encoded", func() {
+			It("calls the handler", func() {
+				handler := apigateway.Handler(r)
+				res, err := handler(context.Background(), newProxyRequest(buildRequestBody(), false))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with Unauthorized", func() {
+				handler := apigateway.Handler(r)
+				proxyReq := newProxyRequest(buildRequestBody(), true)
+				proxyReq.Headers["X-Slack-Signature"] = "v0=0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+				res, err := handler(context.Background(), proxyReq)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the handler writes a response", func() {
+			It("maps the response into the proxy response", func() {
+				h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"ok":true}`))
+				})
+				res, err := apigateway.Handler(h)(context.Background(), events.APIGatewayProxyRequest{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.StatusCode).To(Equal(http.StatusCreated))
+				Expect(res.Headers).To(HaveKeyWithValue("Content-Type", "application/json"))
+				Expect(res.Body).To(Equal(`{"ok":true}`))
+				Expect(res.IsBase64Encoded).To(BeFalse())
+			})
+		})
+
+		Context("when the body is not a valid base64 string", func() {
+			It("returns an error", func() {
+				proxyReq := events.APIGatewayProxyRequest{Body: "!!!", IsBase64Encoded: true}
+				_, err := apigateway.Handler(r)(context.Background(), proxyReq)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("NewRequest", func() {
+		It("reconstructs headers, query parameters and body", func() {
+			proxyReq := events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodPost,
+				Path:                  "/slack/events",
+				Headers:               map[string]string{"X-Slack-Retry-Num": "1"},
+				MultiValueHeaders:     map[string][]string{"X-Multi": {"a", "b"}},
+				QueryStringParameters: map[string]string{"foo": "bar"},
+				Body:                  base64.StdEncoding.EncodeToString([]byte("hello")),
+				IsBase64Encoded:       true,
+			}
+			req, err := apigateway.NewRequest(context.Background(), proxyReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.URL.Path).To(Equal("/slack/events"))
+			Expect(req.URL.Query().Get("foo")).To(Equal("bar"))
+			Expect(req.Header.Get("X-Slack-Retry-Num")).To(Equal("1"))
+			Expect(req.Header.Values("X-Multi")).To(Equal([]string{"a", "b"}))
+			body, err := io.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal("hello"))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+// Package apphome provides handlers to process `app_home_opened` events.
+//
+// For more details, see https://api.slack.com/events/app_home_opened.
+package apphome
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/errors"
+)
+
+// Tabs of App Home.
+const (
+	TabHome     = "home"
+	TabMessages = "messages"
+)
+
+// Handler processes `app_home_opened` events.
+type Handler interface {
+	HandleAppHomeOpenedEvent(context.Context, *slackevents.AppHomeOpenedEvent) error
+}
+
+type HandlerFunc func(context.Context, *slackevents.AppHomeOpenedEvent) error
+
+func (f HandlerFunc) HandleAppHomeOpenedEvent(ctx context.Context, e *slackevents.AppHomeOpenedEvent) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type tabPredicate struct {
+	tabs []string
+}
+
+// Tab is a predicate that is considered to be "true" if and only if a user opened one of the given tabs, i.e. TabHome or TabMessages.
+//
+// It panics if no tab is given.
+func Tab(tabs ...string) Predicate {
+	if len(tabs) == 0 {
+		panic("apphome.Tab: at least one tab must be given")
+	}
+	return &tabPredicate{tabs: tabs}
+}
+
+func (p *tabPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppHomeOpenedEvent) error {
+		if !contains(p.tabs, e.Tab) {
+			return errors.NotInterested
+		}
+		return h.HandleAppHomeOpenedEvent(ctx, e)
+	})
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	for _, p := range preds {
+		h = p.Wrap(h)
+	}
+	return h
+}
+
+// ViewPublisher is a client that publishes views to App Home. `*slack.Client` implements this.
+type ViewPublisher interface {
+	PublishViewContext(ctx context.Context, userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error)
+}
+
+// ErrNoClient is returned from PublishView when no client is given.
+var ErrNoClient = stderrors.New("no client is given to the Router")
+
+type contextKey int
+
+const (
+	publisherKey contextKey = iota
+)
+
+type publisher struct {
+	client ViewPublisher
+	event  *slackevents.AppHomeOpenedEvent
+}
+
+// WithClient returns a copy of ctx that carries client, which PublishView uses to publish views for the user of the event e.
+//
+// `eventrouter.Router.OnAppHomeOpened` sets this automatically if `eventrouter.WithClient` is given,
+// so this is needed only when handlers are called without the Router, e.g. in tests.
+func WithClient(ctx context.Context, client ViewPublisher, e *slackevents.AppHomeOpenedEvent) context.Context {
+	return context.WithValue(ctx, publisherKey, &publisher{client: client, event: e})
+}
+
+// PublishView publishes the view to the home tab of the user who opened App Home, by calling `views.publish`.
+//
+// If the user has seen a view published before, its hash is also sent, so the call fails if the view has been updated since the event was sent.
+// It returns ErrNoClient if no client is given (see WithClient).
+func PublishView(ctx context.Context, view slack.HomeTabViewRequest) (*slack.ViewResponse, error) {
+	p, ok := ctx.Value(publisherKey).(*publisher)
+	if !ok || p.client == nil {
+		return nil, ErrNoClient
+	}
+	return p.client.PublishViewContext(ctx, p.event.User, view, p.event.View.Hash)
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package apphome_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestApphome(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Apphome Suite")
+}
@@ -0,0 +1,90 @@
+package apphome_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/apphome"
+	"github.com/genkami/go-slack-event-router/errors"
+)
+
+type stubPublisher struct {
+	userID string
+	view   slack.HomeTabViewRequest
+	hash   string
+}
+
+func (p *stubPublisher) PublishViewContext(_ context.Context, userID string, view slack.HomeTabViewRequest, hash string) (*slack.ViewResponse, error) {
+	p.userID = userID
+	p.view = view
+	p.hash = hash
+	return &slack.ViewResponse{View: slack.View{ID: "V12345", Type: slack.VTHomeTab}}, nil
+}
+
+var _ = Describe("AppHome", func() {
+	var (
+		numHandlerCalled int
+		innerHandler     = apphome.HandlerFunc(func(_ context.Context, _ *slackevents.AppHomeOpenedEvent) error {
+			numHandlerCalled++
+			return nil
+		})
+		ctx context.Context
+	)
+	BeforeEach(func() {
+		numHandlerCalled = 0
+		ctx = context.Background()
+	})
+
+	Describe("Tab", func() {
+		Context("when the user opened the given tab", func() {
+			It("calls the inner handler", func() {
+				h := apphome.Tab(apphome.TabHome).Wrap(innerHandler)
+				err := h.HandleAppHomeOpenedEvent(ctx, &slackevents.AppHomeOpenedEvent{User: "U12345", Tab: "home"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the user opened another tab", func() {
+			It("does not call the inner handler", func() {
+				h := apphome.Tab(apphome.TabHome).Wrap(innerHandler)
+				err := h.HandleAppHomeOpenedEvent(ctx, &slackevents.AppHomeOpenedEvent{User: "U12345", Tab: "messages"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no tab is given", func() {
+			It("panics", func() {
+				Expect(func() { apphome.Tab() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("PublishView", func() {
+		Context("when a client is given", func() {
+			It("publishes the view for the user with the hash of the current view", func() {
+				client := &stubPublisher{}
+				e := &slackevents.AppHomeOpenedEvent{User: "U12345", Tab: "home", View: slack.View{Hash: "156772938.1827394"}}
+				view := slack.HomeTabViewRequest{Type: slack.VTHomeTab, CallbackID: "home"}
+				resp, err := apphome.PublishView(apphome.WithClient(ctx, client, e), view)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.View.ID).To(Equal("V12345"))
+				Expect(client.userID).To(Equal("U12345"))
+				Expect(client.view).To(Equal(view))
+				Expect(client.hash).To(Equal("156772938.1827394"))
+			})
+		})
+
+		Context("when no client is given", func() {
+			It("returns ErrNoClient", func() {
+				_, err := apphome.PublishView(ctx, slack.HomeTabViewRequest{Type: slack.VTHomeTab})
+				Expect(err).To(MatchError(apphome.ErrNoClient))
+			})
+		})
+	})
+})
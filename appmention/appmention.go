@@ -10,8 +10,16 @@ import (
 	"github.com/slack-go/slack/slackevents"
 
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/predicates"
 )
 
+var leadingMention = regexp.MustCompile(`^\s*<@[^>]+>\s*`)
+
+// TrimMention removes the leading mention (e.g. `<@U12345678>`) and surrounding spaces from the given text.
+func TrimMention(text string) string {
+	return leadingMention.ReplaceAllString(text, "")
+}
+
 // Handler processes `app_mention` events.
 type Handler interface {
 	HandleAppMentionEvent(context.Context, *slackevents.AppMentionEvent) error
@@ -46,18 +54,66 @@ func (p *inChannelPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+type inChannelsPredicate struct {
+	ids []string
+}
+
+// InChannel is a predicate that is considered to be "true" if and only if an event happened in one of the given channels.
+//
+// It panics if no channel ID is given.
+func InChannel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("appmention.InChannel: at least one channel ID must be given")
+	}
+	return &inChannelsPredicate{ids: ids}
+}
+
+func (p *inChannelsPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		if !contains(p.ids, e.Channel) {
+			return errors.NotInterested
+		}
+		return h.HandleAppMentionEvent(ctx, e)
+	})
+}
+
+type fromUserPredicate struct {
+	ids []string
+}
+
+// FromUser is a predicate that is considered to be "true" if and only if the app is mentioned by one of the given users.
+//
+// It panics if no user ID is given.
+func FromUser(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("appmention.FromUser: at least one user ID must be given")
+	}
+	return &fromUserPredicate{ids: ids}
+}
+
+func (p *fromUserPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		if e.User == "" || !contains(p.ids, e.User) {
+			return errors.NotInterested
+		}
+		return h.HandleAppMentionEvent(ctx, e)
+	})
+}
+
 type textRegexpPredicate struct {
 	re *regexp.Regexp
 }
 
 // TextRegexp is a predicate that is considered to be "true" if and only if a text of a message matches to the given regexp.
+//
+// The leading mention is removed from the text before matching (see TrimMention), so `^deploy` matches to `<@U12345678> deploy`.
 func TextRegexp(re *regexp.Regexp) Predicate {
 	return &textRegexpPredicate{re: re}
 }
 
 func (p *textRegexpPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
-		idx := p.re.FindStringIndex(e.Text)
+		idx := p.re.FindStringIndex(TrimMention(e.Text))
 		if len(idx) == 0 {
 			return errors.NotInterested
 		}
@@ -65,6 +121,98 @@ func (p *textRegexpPredicate) Wrap(h Handler) Handler {
 	})
 }
 
+type stripMentionPredicate struct{}
+
+// StripMention is a predicate that is always considered to be "true".
+// It removes the leading mention from the text of a message and makes the rest available via TextWithoutMention.
+//
+// This is useful to parse commands like `@mybot deploy production`.
+func StripMention() Predicate {
+	return &stripMentionPredicate{}
+}
+
+func (p *stripMentionPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		ctx = context.WithValue(ctx, textWithoutMentionKey, TrimMention(e.Text))
+		return h.HandleAppMentionEvent(ctx, e)
+	})
+}
+
+type contextKey int
+
+const (
+	textWithoutMentionKey contextKey = iota
+)
+
+// TextWithoutMention returns the text of a message without the leading mention.
+//
+// It returns the second value false if StripMention is not applied to the handler.
+func TextWithoutMention(ctx context.Context) (string, bool) {
+	text, ok := ctx.Value(textWithoutMentionKey).(string)
+	return text, ok
+}
+
+type notPredicate struct {
+	pred Predicate
+}
+
+// Not is a predicate that is considered to be "true" if and only if the given Predicate is considered to be "false".
+//
+// If the given Predicate returns an error other than `errors.NotInterested`, the error is returned as is.
+func Not(pred Predicate) Predicate {
+	return &notPredicate{pred: pred}
+}
+
+func (p *notPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		return predicates.Not(ctx, probe(p.pred, e), handle(h, e))
+	})
+}
+
+type anyPredicate struct {
+	preds []Predicate
+}
+
+// Any is a predicate that is considered to be "true" if and only if at least one of the given Predicates is considered to be "true".
+//
+// The given Predicates are evaluated in order without calling the inner handler, and the evaluation stops at the first one that is considered to be "true".
+// Then the inner handler is called exactly once.
+// If one of the given Predicates returns an error other than `errors.NotInterested`, the evaluation stops and the error is returned as is.
+//
+// It panics if no Predicate is given.
+func Any(preds ...Predicate) Predicate {
+	if len(preds) == 0 {
+		panic("appmention.Any: at least one predicate must be given")
+	}
+	return &anyPredicate{preds: preds}
+}
+
+func (p *anyPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.AppMentionEvent) error {
+		probes := make([]predicates.Probe, 0, len(p.preds))
+		for _, pred := range p.preds {
+			probes = append(probes, probe(pred, e))
+		}
+		return predicates.Any(ctx, probes, handle(h, e))
+	})
+}
+
+// probe converts the given Predicate into a Probe for the event.
+func probe(pred Predicate, e *slackevents.AppMentionEvent) predicates.Probe {
+	return func(ctx context.Context, next func(context.Context) error) error {
+		return pred.Wrap(HandlerFunc(func(innerCtx context.Context, _ *slackevents.AppMentionEvent) error {
+			return next(innerCtx)
+		})).HandleAppMentionEvent(ctx, e)
+	}
+}
+
+// handle returns a function that calls h with the event.
+func handle(h Handler, e *slackevents.AppMentionEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return h.HandleAppMentionEvent(ctx, e)
+	}
+}
+
 // Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func Build(h Handler, preds ...Predicate) Handler {
 	for _, p := range preds {
@@ -72,3 +220,12 @@ func Build(h Handler, preds ...Predicate) Handler {
 	}
 	return h
 }
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
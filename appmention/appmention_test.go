@@ -151,5 +151,156 @@ var _ = Describe("AppMention", func() {
 				Expect(numHandlerCalled).To(Equal(0))
 			})
 		})
+
+		Context("When the text after the mention matches to the pattern", func() {
+			It("calls the inner handler", func() {
+				h := appmention.TextRegexp(regexp.MustCompile(`^deploy\b`)).Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{
+					Text: "<@U12345678> deploy production",
+				}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("InChannel", func() {
+		Context("When the event's channel is one of the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := appmention.InChannel("XXX", "YYY").Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{
+					Channel: "YYY",
+				}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("When the event's channel is not any of the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := appmention.InChannel("XXX", "YYY").Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{
+					Channel: "ZZZ",
+				}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("When no channel is given", func() {
+			It("panics", func() {
+				Expect(func() { appmention.InChannel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromUser", func() {
+		Context("When the event's user is one of the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := appmention.FromUser("UXXX", "UYYY").Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{
+					User: "UXXX",
+				}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("When the event's user is not any of the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := appmention.FromUser("UXXX", "UYYY").Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{
+					User: "UZZZ",
+				}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("StripMention", func() {
+		It("passes the text without the leading mention to the inner handler", func() {
+			var text string
+			var ok bool
+			h := appmention.StripMention().Wrap(appmention.HandlerFunc(func(ctx context.Context, _ *slackevents.AppMentionEvent) error {
+				text, ok = appmention.TextWithoutMention(ctx)
+				return nil
+			}))
+			e := &slackevents.AppMentionEvent{
+				Text: "<@U12345678>  deploy <@U87654321>",
+			}
+			err := h.HandleAppMentionEvent(ctx, e)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(text).To(Equal("deploy <@U87654321>"))
+		})
+
+		Context("When StripMention is not applied", func() {
+			It("TextWithoutMention returns false", func() {
+				_, ok := appmention.TextWithoutMention(ctx)
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Not", func() {
+		Context("when the given predicate does not match", func() {
+			It("calls the inner handler", func() {
+				h := appmention.Not(appmention.Channel("C12345")).Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{Channel: "C67890"}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the given predicate matches", func() {
+			It("does not call the inner handler", func() {
+				h := appmention.Not(appmention.Channel("C12345")).Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{Channel: "C12345"}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Any", func() {
+		Context("when one of the given predicates matches", func() {
+			It("calls the inner handler exactly once with the context of the matched predicate", func() {
+				var text string
+				h := appmention.Any(appmention.Channel("C12345"), appmention.StripMention()).Wrap(appmention.HandlerFunc(func(ctx context.Context, _ *slackevents.AppMentionEvent) error {
+					numHandlerCalled++
+					text, _ = appmention.TextWithoutMention(ctx)
+					return nil
+				}))
+				e := &slackevents.AppMentionEvent{Channel: "C67890", Text: "<@UBOT> hello"}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(text).To(Equal("hello"))
+			})
+		})
+
+		Context("when none of the given predicates match", func() {
+			It("does not call the inner handler", func() {
+				h := appmention.Any(appmention.Channel("C12345"), appmention.FromUser("U12345")).Wrap(innerHandler)
+				e := &slackevents.AppMentionEvent{Channel: "C67890", User: "U67890"}
+				err := h.HandleAppMentionEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no predicate is given", func() {
+			It("panics", func() {
+				Expect(func() { appmention.Any() }).To(Panic())
+			})
+		})
 	})
 })
@@ -0,0 +1,409 @@
+// Package command provides a way to dispatch slash commands sent from Slack.
+//
+// For more details, see https://api.slack.com/interactivity/slash-commands.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/signature"
+)
+
+// Handler processes slash commands sent from Slack.
+//
+// Handlers may return a message, which is sent to Slack as the synchronous response to the command.
+// Set its ResponseType to `slack.ResponseTypeInChannel` to make the message visible to everyone in the channel,
+// or `slack.ResponseTypeEphemeral` (or leave it empty) to make it visible only to the user who invoked the command.
+// If a handler returns nil, the Router responds with an empty body, which means that the command is acknowledged without any messages.
+type Handler interface {
+	HandleSlashCommand(context.Context, *slack.SlashCommand) (*slack.Msg, error)
+}
+
+type HandlerFunc func(context.Context, *slack.SlashCommand) (*slack.Msg, error)
+
+func (f HandlerFunc) HandleSlashCommand(ctx context.Context, cmd *slack.SlashCommand) (*slack.Msg, error) {
+	return f(ctx, cmd)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming commands.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type namePredicate struct {
+	name string
+}
+
+// Name is a predicate that is considered to be "true" if and only if the name of a command equals to the given one, e.g. "/deploy".
+func Name(name string) Predicate {
+	return &namePredicate{name: name}
+}
+
+func (p *namePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) (*slack.Msg, error) {
+		if cmd.Command != p.name {
+			return nil, routererrors.NotInterested
+		}
+		return h.HandleSlashCommand(ctx, cmd)
+	})
+}
+
+type textRegexpPredicate struct {
+	re *regexp.Regexp
+}
+
+// TextRegexp is a predicate that is considered to be "true" if and only if the text following the name of a command matches to the given regexp.
+//
+// The submatches of the regexp are passed to the inner handler through the context. They can be retrieved with Submatches.
+func TextRegexp(re *regexp.Regexp) Predicate {
+	return &textRegexpPredicate{re: re}
+}
+
+func (p *textRegexpPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, cmd *slack.SlashCommand) (*slack.Msg, error) {
+		submatches := p.re.FindStringSubmatch(cmd.Text)
+		if submatches == nil {
+			return nil, routererrors.NotInterested
+		}
+		ctx = context.WithValue(ctx, submatchesKey, submatches)
+		return h.HandleSlashCommand(ctx, cmd)
+	})
+}
+
+type contextKey int
+
+const (
+	submatchesKey contextKey = iota
+)
+
+// Submatches returns the submatches of the regexp given to TextRegexp, in the same form as `regexp.Regexp.FindStringSubmatch`.
+//
+// It returns nil if the context is not passed from TextRegexp.
+func Submatches(ctx context.Context) []string {
+	submatches, _ := ctx.Value(submatchesKey).([]string)
+	return submatches
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	for _, p := range preds {
+		h = p.Wrap(h)
+	}
+	return h
+}
+
+// Option configures the Router.
+type Option interface {
+	apply(*Router)
+}
+
+type optionFunc func(*Router)
+
+func (f optionFunc) apply(r *Router) {
+	f(r)
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful to test your handlers, but do not use this in production environments.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(r *Router) {
+		r.skipVerification = true
+	})
+}
+
+// WithSigningSecret sets a signing token to verify requests from Slack.
+//
+// New returns an error if token is empty, which typically means that the secret is read from an unset environment variable.
+//
+// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func WithSigningSecret(token string) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecret = token
+		if token == "" {
+			r.hasEmptySigningSecret = true
+		}
+	})
+}
+
+// WithSigningSecrets sets signing tokens to verify requests from Slack.
+//
+// A request is considered to be valid if it is signed with any of the given tokens and the one given to WithSigningSecret.
+// This is useful to rotate signing secrets without downtime.
+func WithSigningSecrets(tokens ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecrets = append(r.signingSecrets, tokens...)
+		for _, t := range tokens {
+			if t == "" {
+				r.hasEmptySigningSecret = true
+			}
+		}
+	})
+}
+
+// WithSigningSecretFunc sets a function that returns the current signing secret, so that secrets can be rotated without restarting the process.
+//
+// The secret returned from f is cached for ttl to avoid calling f (e.g. fetching the secret from a vault) for every request.
+// Note that it may take up to ttl for a rotated secret to take effect. If ttl is zero, f is called for every request.
+// If f returns an error, the Router responds with Internal Server Error. Errors are not cached.
+//
+// This can be used together with WithSigningSecret and WithSigningSecrets.
+func WithSigningSecretFunc(f func() ([]byte, error), ttl time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecretFunc = f
+		r.signingSecretTTL = ttl
+	})
+}
+
+// WithSignatureTolerance sets the maximum difference between the request timestamp and the current time.
+// If this is not set, signature.DefaultTolerance is used. New returns an error if d is negative.
+func WithSignatureTolerance(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signatureTolerance = d
+	})
+}
+
+// WithMaxBodyBytes limits the size of request bodies to n bytes.
+//
+// The Router responds with Request Entity Too Large to requests whose bodies exceed the limit.
+// If this is not set, the limit is 1 MiB, which is large enough for any requests from Slack.
+func WithMaxBodyBytes(n int64) Option {
+	return optionFunc(func(r *Router) {
+		r.maxBodyBytes = n
+	})
+}
+
+// If VerboseResponse is set, the Router shows error details when it fails to process requests.
+func VerboseResponse() Option {
+	return optionFunc(func(r *Router) {
+		r.verboseResponse = true
+	})
+}
+
+// Logger is a logger that the Router writes logs to.
+//
+// `*slog.Logger` satisfies this interface.
+type Logger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(_ string, _ ...interface{})  {}
+func (nopLogger) Error(_ string, _ ...interface{}) {}
+
+// WithLogger sets a logger.
+//
+// The Router logs failures of signature verification and malformed requests at the info level, and errors returned from handlers at the error level.
+// If this is not set, the Router does not write any logs.
+func WithLogger(l Logger) Option {
+	return optionFunc(func(r *Router) {
+		r.logger = l
+	})
+}
+
+// Router is an http.Handler that processes slash commands from Slack.
+//
+// All handlers must be registered before the Router starts serving, i.e. before ServeHTTP is called for the first time.
+// Registering handlers after that panics.
+//
+// For more details, see https://api.slack.com/interactivity/slash-commands.
+type Router struct {
+	signingSecret         string
+	signingSecrets        []string
+	signingSecretFunc     func() ([]byte, error)
+	signingSecretTTL      time.Duration
+	hasEmptySigningSecret bool
+	skipVerification      bool
+	signatureTolerance    time.Duration
+	handlers              []Handler
+	fallbackHandler       Handler
+	verboseResponse       bool
+	maxBodyBytes          int64
+	logger                Logger
+	httpHandler           http.Handler
+	registry              routerutils.Registry
+}
+
+// New creates a new Router.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func New(opts ...Option) (*Router, error) {
+	r := &Router{
+		maxBodyBytes: routerutils.DefaultMaxBodyBytes,
+		logger:       nopLogger{},
+	}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	if r.logger == nil {
+		r.logger = nopLogger{}
+	}
+	if r.maxBodyBytes <= 0 {
+		return nil, errors.New("WithMaxBodyBytes must be positive")
+	}
+	if r.hasEmptySigningSecret {
+		return nil, errors.New("WithSigningSecret is given an empty signing secret; make sure that the secret is set correctly")
+	}
+	if r.signingSecretTTL < 0 {
+		return nil, errors.New("WithSigningSecretFunc must not be given a negative TTL")
+	}
+	if r.signatureTolerance < 0 {
+		return nil, errors.New("WithSignatureTolerance must not be negative")
+	}
+	hasSigningSecret := r.signingSecret != "" || len(r.signingSecrets) > 0 || r.signingSecretFunc != nil
+	if !hasSigningSecret && !r.skipVerification {
+		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
+	}
+	if hasSigningSecret && r.skipVerification {
+		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
+	}
+
+	r.httpHandler = http.HandlerFunc(r.serveHTTP)
+	if !r.skipVerification {
+		r.httpHandler = &signature.Middleware{
+			SigningSecret:     r.signingSecret,
+			SigningSecrets:    r.signingSecrets,
+			SigningSecretFunc: signature.CacheSecret(r.signingSecretFunc, r.signingSecretTTL),
+			Tolerance:         r.signatureTolerance,
+			VerboseResponse:   r.verboseResponse,
+			Handler:           r.httpHandler,
+			OnError: func(_ *http.Request, err error) {
+				r.logger.Info("failed to verify request signature", "error", err)
+			},
+		}
+	}
+	return r, nil
+}
+
+// On registers a handler for slash commands.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Handlers may return `routererrors.NotInterested` (or its equivalents in the sense of `errors.Is`). In such case the Router falls back to other handlers.
+//
+// Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
+//
+// If any other errors are returned, the Router responds with Internal Server Error.
+func (r *Router) On(h Handler, preds ...Predicate) {
+	h = Build(h, preds...)
+	r.registry.Register("command.Router.On", func() {
+		r.handlers = append(r.handlers, h)
+	})
+}
+
+// OnCommand registers a handler that processes the command with the given name, e.g. "/deploy".
+//
+// This is equivalent to `On(h, Name(name), preds...)`.
+func (r *Router) OnCommand(name string, h Handler, preds ...Predicate) {
+	preds = append([]Predicate{Name(name)}, preds...)
+	r.On(h, preds...)
+}
+
+// SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming command.
+//
+// If more than one handlers are registered, the last one will be used.
+func (r *Router) SetFallback(h Handler) {
+	r.registry.Register("command.Router.SetFallback", func() {
+		r.fallbackHandler = h
+	})
+}
+
+// ServeHTTP processes a slash command.
+//
+// Handlers can no longer be registered once ServeHTTP is called.
+func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	router.registry.Freeze()
+	routerutils.LimitBody(req, router.maxBodyBytes)
+	defer routerutils.ReleaseBody(req)
+	router.httpHandler.ServeHTTP(w, req)
+}
+
+func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if !routerutils.IsForm(req) {
+		router.logger.Info("unexpected Content-Type", "contentType", req.Header.Get("Content-Type"))
+		router.respondWithError(w, errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "unexpected Content-Type"))
+		return
+	}
+	body, err := routerutils.ReadBody(req)
+	if err != nil {
+		router.logger.Info("failed to read body", "error", err)
+		router.respondWithError(w, err)
+		return
+	}
+	// Parse the body by ourselves rather than using req.FormValue, which reads the body again.
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		router.logger.Info("failed to parse form", "error", err)
+		router.respondWithError(w, errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), err.Error()))
+		return
+	}
+	cmd := parseSlashCommand(form)
+	if cmd.Command == "" {
+		router.logger.Info("missing command")
+		router.respondWithError(w, errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "missing command"))
+		return
+	}
+
+	msg, err := router.dispatch(req.Context(), cmd)
+	if err != nil && !errors.Is(err, routererrors.NotInterested) {
+		router.logger.Error("handler returned an error", "error", err, "command", cmd.Command)
+		router.respondWithError(w, err)
+		return
+	}
+	if msg == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(msg)
+}
+
+func (r *Router) dispatch(ctx context.Context, cmd *slack.SlashCommand) (msg *slack.Msg, err error) {
+	defer routerutils.RecoverPanic(&err)
+	for _, h := range r.handlers {
+		msg, err = h.HandleSlashCommand(ctx, cmd)
+		if !errors.Is(err, routererrors.NotInterested) {
+			return msg, err
+		}
+	}
+	if r.fallbackHandler == nil {
+		return nil, routererrors.NotInterested
+	}
+	return r.fallbackHandler.HandleSlashCommand(ctx, cmd)
+}
+
+func (r *Router) respondWithError(w http.ResponseWriter, err error) {
+	routerutils.RespondWithError(w, err, r.verboseResponse)
+}
+
+// parseSlashCommand does the same thing as slack.SlashCommandParse, except that it takes an already parsed form.
+func parseSlashCommand(form url.Values) *slack.SlashCommand {
+	return &slack.SlashCommand{
+		Token:          form.Get("token"),
+		TeamID:         form.Get("team_id"),
+		TeamDomain:     form.Get("team_domain"),
+		EnterpriseID:   form.Get("enterprise_id"),
+		EnterpriseName: form.Get("enterprise_name"),
+		ChannelID:      form.Get("channel_id"),
+		ChannelName:    form.Get("channel_name"),
+		UserID:         form.Get("user_id"),
+		UserName:       form.Get("user_name"),
+		Command:        form.Get("command"),
+		Text:           form.Get("text"),
+		ResponseURL:    form.Get("response_url"),
+		TriggerID:      form.Get("trigger_id"),
+		APIAppID:       form.Get("api_app_id"),
+	}
+}
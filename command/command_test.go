@@ -0,0 +1,427 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	"github.com/genkami/go-slack-event-router/command"
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/testutils"
+)
+
+var _ = Describe("Command", func() {
+	Describe("Name", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+				numHandlerCalled++
+				return nil, nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the name of the command matches to the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := command.Name("/deploy").Wrap(innerHandler)
+				_, err := h.HandleSlashCommand(ctx, &slack.SlashCommand{Command: "/deploy"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the name of the command differs from the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := command.Name("/deploy").Wrap(innerHandler)
+				_, err := h.HandleSlashCommand(ctx, &slack.SlashCommand{Command: "/rollback"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("TextRegexp", func() {
+		var (
+			numHandlerCalled int
+			submatches       []string
+			innerHandler     = command.HandlerFunc(func(ctx context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+				numHandlerCalled++
+				submatches = command.Submatches(ctx)
+				return nil, nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			submatches = nil
+			ctx = context.Background()
+		})
+
+		Context("when the text of the command matches to the regexp", func() {
+			It("calls the inner handler with the submatches", func() {
+				h := command.TextRegexp(regexp.MustCompile(`^(\w+) to (\w+)$`)).Wrap(innerHandler)
+				_, err := h.HandleSlashCommand(ctx, &slack.SlashCommand{Command: "/deploy", Text: "api to production"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(submatches).To(Equal([]string{"api to production", "api", "production"}))
+			})
+		})
+
+		Context("when the text of the command does not match to the regexp", func() {
+			It("does not call the inner handler", func() {
+				h := command.TextRegexp(regexp.MustCompile(`^(\w+) to (\w+)$`)).Wrap(innerHandler)
+				_, err := h.HandleSlashCommand(ctx, &slack.SlashCommand{Command: "/deploy", Text: "api"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("New", func() {
+		Context("when neither WithSigningSecret nor InsecureSkipVerification is given", func() {
+			It("returns an error", func() {
+				_, err := command.New()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when both WithSigningSecret and InsecureSkipVerification are given", func() {
+			It("returns an error", func() {
+				_, err := command.New(command.WithSigningSecret("THE_TOKEN"), command.InsecureSkipVerification())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when an empty signing secret is given", func() {
+			It("returns an error", func() {
+				_, err := command.New(command.WithSigningSecret(""))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a negative signature tolerance is given", func() {
+			It("returns an error", func() {
+				_, err := command.New(command.WithSigningSecret("THE_TOKEN"), command.WithSignatureTolerance(-time.Minute))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("ServeHTTP", func() {
+		var (
+			r       *command.Router
+			token   = "THE_TOKEN"
+			content = url.Values{
+				"command":    []string{"/deploy"},
+				"text":       []string{"api"},
+				"channel_id": []string{"C12345"},
+				"user_id":    []string{"U12345"},
+			}
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = command.New(command.WithSigningSecret(token), command.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is valid", func() {
+			It("responds with the message returned from the handler", func() {
+				var received *slack.SlashCommand
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, cmd *slack.SlashCommand) (*slack.Msg, error) {
+					received = cmd
+					return &slack.Msg{ResponseType: slack.ResponseTypeInChannel, Text: "deploying"}, nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Result().Header.Get("Content-Type")).To(Equal("application/json"))
+				var msg slack.Msg
+				Expect(json.NewDecoder(w.Body).Decode(&msg)).To(Succeed())
+				Expect(msg.ResponseType).To(Equal(slack.ResponseTypeInChannel))
+				Expect(msg.Text).To(Equal("deploying"))
+				Expect(received.Command).To(Equal("/deploy"))
+				Expect(received.Text).To(Equal("api"))
+				Expect(received.ChannelID).To(Equal("C12345"))
+				Expect(received.UserID).To(Equal("U12345"))
+			})
+
+			It("responds with an empty body when the handler returns no message", func() {
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return nil, nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.Len()).To(Equal(0))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with Unauthorized without calling handlers", func() {
+				numHandlerCalled := 0
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					numHandlerCalled++
+					return nil, nil
+				}))
+				req, err := NewSignedRequest("WRONG_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when more than one handlers are registered", func() {
+			It("calls the first handler that is interested in the command", func() {
+				called := ""
+				r.OnCommand("/rollback", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					called = "rollback"
+					return nil, nil
+				}))
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					called = "deploy"
+					return nil, nil
+				}))
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					called = "deploy (second)"
+					return nil, nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(called).To(Equal("deploy"))
+			})
+		})
+
+		Context("when no handler is interested in the command", func() {
+			It("calls the fallback handler", func() {
+				r.SetFallback(command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return &slack.Msg{ResponseType: slack.ResponseTypeEphemeral, Text: "unknown command"}, nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				var msg slack.Msg
+				Expect(json.NewDecoder(w.Body).Decode(&msg)).To(Succeed())
+				Expect(msg.ResponseType).To(Equal(slack.ResponseTypeEphemeral))
+				Expect(msg.Text).To(Equal("unknown command"))
+			})
+		})
+
+		Context("when the handler returns an HttpError", func() {
+			It("responds with the corresponding status code", func() {
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return nil, routererrors.HttpError(http.StatusForbidden)
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("when the handler panics", func() {
+			It("responds with Internal Server Error", func() {
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					panic("oops")
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("when the command is missing", func() {
+			It("responds with Bad Request", func() {
+				req, err := NewSignedRequest(token, url.Values{"text": []string{"api"}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the Content-Type has parameters", func() {
+			It("accepts the request", func() {
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return nil, nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the Content-Type is not a form", func() {
+			It("responds with Bad Request", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithSigningSecretFunc", func() {
+		var (
+			content = url.Values{"command": []string{"/deploy"}}
+			secret  string
+			f       = func() ([]byte, error) { return []byte(secret), nil }
+		)
+		BeforeEach(func() {
+			secret = "THE_TOKEN"
+		})
+
+		Context("when the request is signed with the secret returned from the function", func() {
+			It("accepts the request", func() {
+				r, err := command.New(command.WithSigningSecretFunc(f, 0))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest("THE_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the secret is rotated", func() {
+			It("rejects requests signed with the old secret", func() {
+				r, err := command.New(command.WithSigningSecretFunc(f, 0))
+				Expect(err).NotTo(HaveOccurred())
+				secret = "THE_NEW_TOKEN"
+				req, err := NewSignedRequest("THE_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("when a negative TTL is given", func() {
+			It("returns an error", func() {
+				_, err := command.New(command.WithSigningSecretFunc(f, -time.Second))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WithLogger", func() {
+		var (
+			token  = "THE_TOKEN"
+			logger *recordingLogger
+			r      *command.Router
+		)
+		BeforeEach(func() {
+			logger = &recordingLogger{}
+			var err error
+			r, err = command.New(command.WithSigningSecret(token), command.WithLogger(logger))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is invalid", func() {
+			It("logs the failure at the info level", func() {
+				req, err := NewSignedRequest("WRONG_TOKEN", url.Values{"command": []string{"/deploy"}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(logger.entries).To(ConsistOf(logEntry{level: "info", msg: "failed to verify request signature"}))
+			})
+		})
+
+		Context("when the handler returns an error", func() {
+			It("logs the error at the error level", func() {
+				r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return nil, routererrors.ErrServiceUnavailable
+				}))
+				req, err := NewSignedRequest(token, url.Values{"command": []string{"/deploy"}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(logger.entries).To(ConsistOf(logEntry{level: "error", msg: "handler returned an error"}))
+			})
+		})
+	})
+
+	Describe("Registration", func() {
+		Context("when handlers are registered after ServeHTTP is called", func() {
+			It("panics", func() {
+				token := "THE_TOKEN"
+				r, err := command.New(command.WithSigningSecret(token))
+				Expect(err).NotTo(HaveOccurred())
+				h := command.HandlerFunc(func(_ context.Context, _ *slack.SlashCommand) (*slack.Msg, error) {
+					return nil, nil
+				})
+				r.OnCommand("/deploy", h)
+				req, err := NewSignedRequest(token, url.Values{"command": []string{"/deploy"}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(func() { r.OnCommand("/rollback", h) }).To(Panic())
+				Expect(func() { r.SetFallback(h) }).To(Panic())
+			})
+		})
+	})
+})
+
+type logEntry struct {
+	level string
+	msg   string
+}
+
+type recordingLogger struct {
+	entries []logEntry
+}
+
+func (l *recordingLogger) Info(msg string, _ ...interface{}) {
+	l.entries = append(l.entries, logEntry{level: "info", msg: msg})
+}
+
+func (l *recordingLogger) Error(msg string, _ ...interface{}) {
+	l.entries = append(l.entries, logEntry{level: "error", msg: msg})
+}
+
+func NewSignedRequest(signingSecret string, content url.Values, ts *time.Time) (*http.Request, error) {
+	var now time.Time
+	if ts == nil {
+		now = time.Now()
+	} else {
+		now = *ts
+	}
+	body := []byte(content.Encode())
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/command", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := testutils.AddSignature(req.Header, []byte(signingSecret), body, now); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,120 @@
+// Package dedup provides stores to detect events that Slack delivers more than once.
+//
+// Stores in this package can be used with `eventrouter.WithDedupStore`.
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory LRU store of event IDs.
+//
+// It is safe for concurrent use. Note that it is not shared between processes,
+// so you might want to implement `eventrouter.DedupStore` on top of external storage (e.g. Redis) if you run more than one replica.
+type MemoryStore struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	id     string
+	seenAt time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore that remembers at most `size` IDs for `ttl`.
+//
+// When the store is full, the least recently seen ID is forgotten. If `ttl` is zero, IDs never expire.
+//
+// It panics if `size` is not positive or `ttl` is negative.
+func NewMemoryStore(size int, ttl time.Duration) *MemoryStore {
+	if size <= 0 {
+		panic("dedup.NewMemoryStore: size must be positive")
+	}
+	if ttl < 0 {
+		panic("dedup.NewMemoryStore: ttl must not be negative")
+	}
+	return &MemoryStore{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Seen reports whether the given ID has been seen within the TTL, and records it.
+func (s *MemoryStore) Seen(id string) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.contains(id, now) {
+		s.ll.MoveToFront(s.items[id])
+		return true
+	}
+	s.add(id, now)
+	return false
+}
+
+// Contains reports whether the given ID has been recorded within the TTL. Unlike Seen, it does not record the ID.
+//
+// This can be used with Add as `message.SeenStore`.
+func (s *MemoryStore) Contains(id string) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contains(id, now)
+}
+
+// Add records the given ID.
+func (s *MemoryStore) Add(id string) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(id, now)
+}
+
+func (s *MemoryStore) contains(id string, now time.Time) bool {
+	elem, ok := s.items[id]
+	if !ok {
+		return false
+	}
+	e := elem.Value.(*entry)
+	return s.ttl == 0 || now.Sub(e.seenAt) < s.ttl
+}
+
+func (s *MemoryStore) add(id string, now time.Time) {
+	if elem, ok := s.items[id]; ok {
+		elem.Value.(*entry).seenAt = now
+		s.ll.MoveToFront(elem)
+		return
+	}
+	s.items[id] = s.ll.PushFront(&entry{id: id, seenAt: now})
+	if s.ll.Len() > s.size {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*entry).id)
+	}
+}
+
+// Forget removes the given ID from the store.
+func (s *MemoryStore) Forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[id]; ok {
+		s.ll.Remove(elem)
+		delete(s.items, id)
+	}
+}
+
+// Len returns the number of IDs in the store, including expired ones that have not been evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
@@ -0,0 +1,123 @@
+package dedup_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/dedup"
+)
+
+var _ = Describe("Dedup", func() {
+	Describe("MemoryStore", func() {
+		Context("when an ID is seen for the first time", func() {
+			It("returns false", func() {
+				s := dedup.NewMemoryStore(10, time.Minute)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				Expect(s.Seen("Ev0002")).To(BeFalse())
+			})
+		})
+
+		Context("when an ID is seen again within the TTL", func() {
+			It("returns true", func() {
+				s := dedup.NewMemoryStore(10, time.Minute)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+			})
+		})
+
+		Context("when an ID is seen again after the TTL", func() {
+			It("returns false", func() {
+				s := dedup.NewMemoryStore(10, 50*time.Millisecond)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				time.Sleep(100 * time.Millisecond)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+			})
+		})
+
+		Context("when the TTL is zero", func() {
+			It("never forgets IDs unless they are evicted", func() {
+				s := dedup.NewMemoryStore(10, 0)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				time.Sleep(10 * time.Millisecond)
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+			})
+		})
+
+		Context("when an ID is checked with Contains", func() {
+			It("is not recorded until Add is called", func() {
+				s := dedup.NewMemoryStore(10, time.Minute)
+				Expect(s.Contains("Ev0001")).To(BeFalse())
+				Expect(s.Contains("Ev0001")).To(BeFalse())
+				s.Add("Ev0001")
+				Expect(s.Contains("Ev0001")).To(BeTrue())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+			})
+		})
+
+		Context("when an ID is forgotten", func() {
+			It("is considered to be unseen", func() {
+				s := dedup.NewMemoryStore(10, time.Minute)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				s.Forget("Ev0001")
+				Expect(s.Len()).To(Equal(0))
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+			})
+		})
+
+		Context("when the store is full", func() {
+			It("forgets the least recently seen ID", func() {
+				s := dedup.NewMemoryStore(2, time.Minute)
+				Expect(s.Seen("Ev0001")).To(BeFalse())
+				Expect(s.Seen("Ev0002")).To(BeFalse())
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+				Expect(s.Seen("Ev0003")).To(BeFalse())
+				Expect(s.Len()).To(Equal(2))
+				Expect(s.Seen("Ev0001")).To(BeTrue())
+				Expect(s.Seen("Ev0002")).To(BeFalse())
+			})
+		})
+
+		Context("when used concurrently", func() {
+			It("reports each ID as unseen exactly once", func() {
+				s := dedup.NewMemoryStore(100, time.Minute)
+				var (
+					wg     sync.WaitGroup
+					mu     sync.Mutex
+					unseen = make(map[string]int)
+				)
+				for i := 0; i < 8; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for j := 0; j < 50; j++ {
+							id := fmt.Sprintf("Ev%04d", j)
+							if !s.Seen(id) {
+								mu.Lock()
+								unseen[id]++
+								mu.Unlock()
+							}
+						}
+					}()
+				}
+				wg.Wait()
+				Expect(unseen).To(HaveLen(50))
+				for _, n := range unseen {
+					Expect(n).To(Equal(1))
+				}
+			})
+		})
+
+		Context("when the size is not positive", func() {
+			It("panics", func() {
+				Expect(func() { dedup.NewMemoryStore(0, time.Minute) }).To(Panic())
+			})
+		})
+	})
+})
@@ -19,3 +19,31 @@ func (e HttpError) Error() string {
 }
 
 var _ error = HttpError(0)
+
+// Errors that can be returned from handlers to respond with the corresponding status codes.
+const (
+	ErrBadRequest          = HttpError(http.StatusBadRequest)
+	ErrUnauthorized        = HttpError(http.StatusUnauthorized)
+	ErrForbidden           = HttpError(http.StatusForbidden)
+	ErrNotFound            = HttpError(http.StatusNotFound)
+	ErrConflict            = HttpError(http.StatusConflict)
+	ErrPayloadTooLarge     = HttpError(http.StatusRequestEntityTooLarge)
+	ErrTooManyRequests     = HttpError(http.StatusTooManyRequests)
+	ErrInternalServerError = HttpError(http.StatusInternalServerError)
+	ErrServiceUnavailable  = HttpError(http.StatusServiceUnavailable)
+)
+
+// HTTPStatus returns an HTTP status code that corresponds to the given error.
+//
+// If err is (or wraps) an HttpError, it returns the corresponding status code.
+// If err is nil, it returns http.StatusOK. Otherwise it returns http.StatusInternalServerError.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var httpErr HttpError
+	if errors.As(err, &httpErr) {
+		return int(httpErr)
+	}
+	return http.StatusInternalServerError
+}
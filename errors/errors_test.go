@@ -0,0 +1,48 @@
+package errors_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/genkami/go-slack-event-router/errors"
+)
+
+var _ = Describe("Errors", func() {
+	Describe("HttpError", func() {
+		It("describes itself with the status text", func() {
+			Expect(errors.ErrNotFound.Error()).To(Equal("Not Found"))
+			Expect(errors.HttpError(http.StatusTeapot).Error()).To(Equal("I'm a teapot"))
+		})
+	})
+
+	Describe("HTTPStatus", func() {
+		Context("when the error is nil", func() {
+			It("returns 200", func() {
+				Expect(errors.HTTPStatus(nil)).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the error is an HttpError", func() {
+			It("returns the corresponding status code", func() {
+				Expect(errors.HTTPStatus(errors.ErrTooManyRequests)).To(Equal(http.StatusTooManyRequests))
+			})
+		})
+
+		Context("when the error wraps an HttpError", func() {
+			It("returns the corresponding status code", func() {
+				err := fmt.Errorf("failed to process: %w", errors.ErrServiceUnavailable)
+				Expect(errors.HTTPStatus(err)).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("when the error is not an HttpError", func() {
+			It("returns 500", func() {
+				Expect(errors.HTTPStatus(fmt.Errorf("something wrong happened"))).To(Equal(http.StatusInternalServerError))
+				Expect(errors.HTTPStatus(errors.NotInterested)).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
@@ -5,16 +5,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/apphome"
 	"github.com/genkami/go-slack-event-router/appmention"
 	"github.com/genkami/go-slack-event-router/appratelimited"
 	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/file"
 	"github.com/genkami/go-slack-event-router/internal/routerutils"
+	"github.com/genkami/go-slack-event-router/items"
+	"github.com/genkami/go-slack-event-router/membership"
 	"github.com/genkami/go-slack-event-router/message"
 	"github.com/genkami/go-slack-event-router/reaction"
 	"github.com/genkami/go-slack-event-router/signature"
@@ -60,10 +66,64 @@ func InsecureSkipVerification() Option {
 
 // WithSigningSecret sets a signing token to verify requests from Slack.
 //
+// New returns an error if token is empty, which typically means that the secret is read from an unset environment variable.
+//
 // For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
 func WithSigningSecret(token string) Option {
 	return optionFunc(func(r *Router) {
 		r.signingSecret = token
+		if token == "" {
+			r.hasEmptySigningSecret = true
+		}
+	})
+}
+
+// WithSigningSecrets sets signing tokens to verify requests from Slack.
+//
+// A request is considered to be valid if it is signed with any of the given tokens and the one given to WithSigningSecret.
+// This is useful to rotate signing secrets without downtime.
+// Note that the cost of verification increases in proportion to the number of the tokens.
+func WithSigningSecrets(tokens ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecrets = append(r.signingSecrets, tokens...)
+		for _, t := range tokens {
+			if t == "" {
+				r.hasEmptySigningSecret = true
+			}
+		}
+	})
+}
+
+// WithSigningSecretFunc sets a function that returns the current signing secret, so that secrets can be rotated without restarting the process.
+//
+// The secret returned from f is cached for ttl to avoid calling f (e.g. fetching the secret from a vault) for every request.
+// Note that it may take up to ttl for a rotated secret to take effect. If ttl is zero, f is called for every request.
+// If f returns an error, the Router responds with Internal Server Error. Errors are not cached.
+//
+// This can be used together with WithSigningSecret and WithSigningSecrets.
+func WithSigningSecretFunc(f func() ([]byte, error), ttl time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecretFunc = f
+		r.signingSecretTTL = ttl
+	})
+}
+
+// WithSignatureTolerance sets the maximum difference between request timestamps and the current time.
+//
+// If this is not set, signature.DefaultTolerance is used. New returns an error if d is negative.
+func WithSignatureTolerance(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signatureTolerance = d
+	})
+}
+
+// WithMaxBodyBytes limits the size of request bodies to n bytes.
+//
+// The Router responds with Request Entity Too Large to requests whose bodies exceed the limit.
+// If this is not set, the limit is 1 MiB, which is large enough for any requests from Slack.
+func WithMaxBodyBytes(n int64) Option {
+	return optionFunc(func(r *Router) {
+		r.maxBodyBytes = n
 	})
 }
 
@@ -74,18 +134,95 @@ func VerboseResponse() Option {
 	})
 }
 
+// WithoutPanicRecovery disables the Router's own recovery from panics in handlers.
+//
+// By default, the Router converts panics into errors and responds with Internal Server Error.
+// This is useful if you have your own recovery middleware.
+func WithoutPanicRecovery() Option {
+	return optionFunc(func(r *Router) {
+		r.skipPanicRecovery = true
+	})
+}
+
+// WithMaxRetries makes the Router drop retried deliveries of events whose retry count exceeds n.
+//
+// Such requests are acknowledged with 200 and the `X-Slack-No-Retry` header without calling any handlers,
+// which prevents Slack from retrying deliveries to a broken handler over and over again.
+// If this is not set, all retries are processed as usual.
+//
+// For more details, see https://api.slack.com/apis/connections/events-api#retries.
+func WithMaxRetries(n int) Option {
+	return optionFunc(func(r *Router) {
+		r.maxRetries = n
+		r.limitRetries = true
+	})
+}
+
+// DedupStore remembers IDs of events to detect events that Slack delivers more than once.
+//
+// See the package `dedup` for implementations.
+type DedupStore interface {
+	// Seen reports whether the given event ID has been seen before, and records it.
+	Seen(id string) bool
+
+	// Forget removes the given event ID, so that it is considered to be unseen next time.
+	Forget(id string)
+}
+
+// WithDedupStore makes the Router drop events whose IDs (`event_id`) are already seen by the given DedupStore.
+//
+// Such events are acknowledged with 200 without calling any handlers.
+// An event is recorded as soon as it arrives, so that concurrent deliveries of the same event are processed only once.
+// If handlers fail (i.e. the Router responds with a status code other than 2xx), the event is forgotten, so that retries from Slack are processed again.
+func WithDedupStore(s DedupStore) Option {
+	return optionFunc(func(r *Router) {
+		r.dedupStore = s
+	})
+}
+
+// WithClient sets a Slack API client that handlers can use via helpers such as `message.PostEphemeral`, `apphome.PublishView`, and `membership.SendDirectMessage`,
+// and predicates such as `reaction.CountAtLeast` use to call Slack APIs.
+//
+// This is optional. If this is not set (or nil is given), such helpers return errors (e.g. `message.ErrNoClient`).
+func WithClient(c *slack.Client) Option {
+	return optionFunc(func(r *Router) {
+		r.client = c
+	})
+}
+
 // Router is an http.Handler that processes events from Slack via Events API.
 //
+// The Router responds to `url_verification` requests automatically after verifying their signatures,
+// so you don't need to register any handlers to set up the Request URL. See also SetURLVerificationHandler.
+//
+// The Router does not depend on the URL path of requests, so it can be mounted at any path,
+// e.g. `mux.Handle("/slack/events", r)` or under `http.StripPrefix` behind a reverse proxy.
+//
+// All handlers must be registered before the Router starts serving, i.e. before ServeHTTP is called for the first time.
+// Registering handlers after that panics.
+//
 // For more details, see https://api.slack.com/apis/connections/events-api.
 type Router struct {
 	signingSecret          string
+	signingSecrets         []string
+	signingSecretFunc      func() ([]byte, error)
+	signingSecretTTL       time.Duration
+	hasEmptySigningSecret  bool
 	skipVerification       bool
+	signatureTolerance     time.Duration
 	verboseResponse        bool
 	callbackHandlers       map[string][]Handler
 	urlVerificationHandler urlverification.Handler
 	appRateLimitedHandler  appratelimited.Handler
 	fallbackHandler        Handler
+	skipPanicRecovery      bool
+	maxRetries             int
+	limitRetries           bool
+	dedupStore             DedupStore
+	client                 *slack.Client
+	maxBodyBytes           int64
 	httpHandler            http.Handler
+	registry               routerutils.Registry
 }
 
 // New creates a new Router.
@@ -96,23 +233,43 @@ func New(options ...Option) (*Router, error) {
 		callbackHandlers:       make(map[string][]Handler),
 		urlVerificationHandler: urlverification.DefaultHandler,
 		appRateLimitedHandler:  appratelimited.DefaultHandler,
+		maxBodyBytes:           routerutils.DefaultMaxBodyBytes,
 	}
 	for _, o := range options {
 		o.apply(r)
 	}
-	if r.signingSecret == "" && !r.skipVerification {
+	if r.maxBodyBytes <= 0 {
+		return nil, errors.New("WithMaxBodyBytes must be positive")
+	}
+	if r.hasEmptySigningSecret {
+		return nil, errors.New("WithSigningSecret is given an empty signing secret; make sure that the secret is set correctly")
+	}
+	if r.signingSecretTTL < 0 {
+		return nil, errors.New("WithSigningSecretFunc must not be given a negative TTL")
+	}
+	if r.signatureTolerance < 0 {
+		return nil, errors.New("WithSignatureTolerance must not be negative")
+	}
+	hasSigningSecret := r.signingSecret != "" || len(r.signingSecrets) > 0 || r.signingSecretFunc != nil
+	if !hasSigningSecret && !r.skipVerification {
 		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
 	}
-	if r.signingSecret != "" && r.skipVerification {
+	if hasSigningSecret && r.skipVerification {
 		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
 	}
+	if r.limitRetries && r.maxRetries < 0 {
+		return nil, errors.New("WithMaxRetries must not be negative")
+	}
 
 	r.httpHandler = http.HandlerFunc(r.serveHTTP)
 	if !r.skipVerification {
 		r.httpHandler = &signature.Middleware{
-			SigningSecret:   r.signingSecret,
-			VerboseResponse: r.verboseResponse,
-			Handler:         r.httpHandler,
+			SigningSecret:     r.signingSecret,
+			SigningSecrets:    r.signingSecrets,
+			SigningSecretFunc: signature.CacheSecret(r.signingSecretFunc, r.signingSecretTTL),
+			Tolerance:         r.signatureTolerance,
+			VerboseResponse:   r.verboseResponse,
+			Handler:           r.httpHandler,
 		}
 	}
 	return r, nil
@@ -131,12 +288,14 @@ func New(options ...Option) (*Router, error) {
 // This can be useful if you have a general-purpose event handlers that can process arbitrary types of events,
 // but, in the most cases it would be better option to use event-specfic `OnEVENT_NAME` methods instead.
 func (r *Router) On(eventType string, h Handler) {
-	handlers, ok := r.callbackHandlers[eventType]
-	if !ok {
-		handlers = make([]Handler, 0)
-	}
-	handlers = append(handlers, h)
-	r.callbackHandlers[eventType] = handlers
+	r.registry.Register("eventrouter.Router.On", func() {
+		handlers, ok := r.callbackHandlers[eventType]
+		if !ok {
+			handlers = make([]Handler, 0)
+		}
+		handlers = append(handlers, h)
+		r.callbackHandlers[eventType] = handlers
+	})
 }
 
 // OnMessage registers a handler that processes `message` events.
@@ -152,6 +311,10 @@ func (r *Router) OnMessage(h message.Handler, preds ...message.Predicate) {
 		if !ok {
 			return routererrors.HttpError(http.StatusBadRequest)
 		}
+		ctx = message.WithTeamID(ctx, e.TeamID)
+		if r.client != nil {
+			ctx = message.WithClient(ctx, r.client, inner)
+		}
 		return h.HandleMessageEvent(ctx, inner)
 	}))
 }
@@ -186,6 +349,9 @@ func (r *Router) OnReactionAdded(h reaction.AddedHandler, preds ...reaction.Pred
 		if !ok {
 			return routererrors.HttpError(http.StatusBadRequest)
 		}
+		if r.client != nil {
+			ctx = reaction.WithClient(ctx, r.client)
+		}
 		return h.HandleReactionAddedEvent(ctx, inner)
 	}))
 }
@@ -203,10 +369,166 @@ func (r *Router) OnReactionRemoved(h reaction.RemovedHandler, preds ...reaction.
 		if !ok {
 			return routererrors.HttpError(http.StatusBadRequest)
 		}
+		if r.client != nil {
+			ctx = reaction.WithClient(ctx, r.client)
+		}
 		return h.HandleReactionRemovedEvent(ctx, inner)
 	}))
 }
 
+// OnAppHomeOpened registers a handler that processes `app_home_opened` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnAppHomeOpened(h apphome.Handler, preds ...apphome.Predicate) {
+	h = apphome.Build(h, preds...)
+	r.On(slackevents.AppHomeOpened, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		inner, ok := e.InnerEvent.Data.(*slackevents.AppHomeOpenedEvent)
+		if !ok {
+			return routererrors.HttpError(http.StatusBadRequest)
+		}
+		if r.client != nil {
+			ctx = apphome.WithClient(ctx, r.client, inner)
+		}
+		return h.HandleAppHomeOpenedEvent(ctx, inner)
+	}))
+}
+
+// OnTeamJoin registers a handler that processes `team_join` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnTeamJoin(h membership.TeamJoinHandler, preds ...membership.Predicate) {
+	h = membership.BuildTeamJoin(h, preds...)
+	r.On("team_join", HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		inner, ok := e.InnerEvent.Data.(*slack.TeamJoinEvent)
+		if !ok {
+			return routererrors.HttpError(http.StatusBadRequest)
+		}
+		if r.client != nil {
+			ctx = membership.WithClient(ctx, r.client)
+		}
+		return h.HandleTeamJoinEvent(ctx, inner)
+	}))
+}
+
+// OnMemberJoinedChannel registers a handler that processes `member_joined_channel` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnMemberJoinedChannel(h membership.ChannelJoinHandler, preds ...membership.Predicate) {
+	h = membership.BuildChannelJoin(h, preds...)
+	r.On(slackevents.MemberJoinedChannel, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		inner, ok := e.InnerEvent.Data.(*slackevents.MemberJoinedChannelEvent)
+		if !ok {
+			return routererrors.HttpError(http.StatusBadRequest)
+		}
+		if r.client != nil {
+			ctx = membership.WithClient(ctx, r.client)
+		}
+		return h.HandleChannelJoinEvent(ctx, inner)
+	}))
+}
+
+// OnFileShared registers a handler that processes `file_shared` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnFileShared(h file.Handler, preds ...file.Predicate) {
+	r.onFileEvent("file_shared", h, preds...)
+}
+
+// OnFileCreated registers a handler that processes `file_created` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnFileCreated(h file.Handler, preds ...file.Predicate) {
+	r.onFileEvent("file_created", h, preds...)
+}
+
+func (r *Router) onFileEvent(eventType string, h file.Handler, preds ...file.Predicate) {
+	h = file.Build(h, preds...)
+	r.On(eventType, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		// slackevents parses file events into types for the RTM API, which lack some fields, so we need to parse them by ourselves.
+		inner := &file.Event{}
+		if err := decodeInnerEvent(e, inner); err != nil {
+			return errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), err.Error())
+		}
+		return h.HandleFileEvent(ctx, inner)
+	}))
+}
+
+// OnPinAdded registers a handler that processes `pin_added` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnPinAdded(h items.Handler, preds ...items.Predicate) {
+	r.onItemEvent(items.PinAdded, h, preds...)
+}
+
+// OnPinRemoved registers a handler that processes `pin_removed` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnPinRemoved(h items.Handler, preds ...items.Predicate) {
+	r.onItemEvent(items.PinRemoved, h, preds...)
+}
+
+// OnStarAdded registers a handler that processes `star_added` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnStarAdded(h items.Handler, preds ...items.Predicate) {
+	r.onItemEvent(items.StarAdded, h, preds...)
+}
+
+// OnStarRemoved registers a handler that processes `star_removed` events.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Predicates are used to distinguish whether a coming event should be processed by the given handler or not.
+// The handler `h` will be called only when all of given Predicates are true.
+func (r *Router) OnStarRemoved(h items.Handler, preds ...items.Predicate) {
+	r.onItemEvent(items.StarRemoved, h, preds...)
+}
+
+func (r *Router) onItemEvent(eventType string, h items.Handler, preds ...items.Predicate) {
+	h = items.Build(h, preds...)
+	r.On(eventType, HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+		// slackevents has different types for pin events and none for star events, so we need to parse them by ourselves.
+		inner := &items.Event{}
+		if err := decodeInnerEvent(e, inner); err != nil {
+			return errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), err.Error())
+		}
+		return h.HandleItemEvent(ctx, inner)
+	}))
+}
+
+// decodeInnerEvent decodes the raw JSON of the inner event of e into v.
+func decodeInnerEvent(e *slackevents.EventsAPIEvent, v interface{}) error {
+	outer, ok := e.Data.(*slackevents.EventsAPICallbackEvent)
+	if !ok || outer.InnerEvent == nil {
+		return fmt.Errorf("expected EventsAPICallbackEvent but got %T", e.Data)
+	}
+	return json.Unmarshal(*outer.InnerEvent, v)
+}
+
 // SetURLVerificationHandler sets a handler to process `url_verification` events.
 //
 // If more than one handlers are registered, the last one will be used.
@@ -215,7 +537,9 @@ func (r *Router) OnReactionRemoved(h reaction.RemovedHandler, preds ...reaction.
 //
 // For more details see https://api.slack.com/events/url_verification.
 func (r *Router) SetURLVerificationHandler(h urlverification.Handler) {
-	r.urlVerificationHandler = h
+	r.registry.Register("eventrouter.Router.SetURLVerificationHandler", func() {
+		r.urlVerificationHandler = h
+	})
 }
 
 // SetAppRateLimitedHandler sets a handler to process `app_rate_limited` events.
@@ -226,22 +550,48 @@ func (r *Router) SetURLVerificationHandler(h urlverification.Handler) {
 //
 // For more details see https://api.slack.com/docs/rate-limits#rate-limits__events-api.
 func (r *Router) SetAppRateLimitedHandler(h appratelimited.Handler) {
-	r.appRateLimitedHandler = h
+	r.registry.Register("eventrouter.Router.SetAppRateLimitedHandler", func() {
+		r.appRateLimitedHandler = h
+	})
 }
 
 // SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming event.
 //
+// That is, the fallback handler is called only when no handler is registered for the event or all of them returned `routererrors.NotInterested`
+// (e.g. because their Predicates are "false"). It is never called after a handler processed the event, whether it succeeded or not.
+//
 // If more than one handlers are registered, the last one will be used.
 func (r *Router) SetFallback(h Handler) {
-	r.fallbackHandler = h
+	r.registry.Register("eventrouter.Router.SetFallback", func() {
+		r.fallbackHandler = h
+	})
 }
 
+// ServeHTTP processes an event.
+//
+// It attaches a request ID to each request, which is taken from the X-Request-ID header if present or generated otherwise.
+// The ID is set to the X-Request-ID header of the response, and available to handlers via RequestID.
+//
+// Handlers can no longer be registered once ServeHTTP is called.
 func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	router.registry.Freeze()
+	requestID := routerutils.RequestID(req)
+	w.Header().Set(routerutils.HeaderRequestID, requestID)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey, requestID))
+	routerutils.LimitBody(req, router.maxBodyBytes)
+	defer routerutils.ReleaseBody(req)
 	router.httpHandler.ServeHTTP(w, req)
 }
 
 func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
+	retryNum, _ := strconv.Atoi(req.Header.Get(HeaderRetryNum))
+	if router.limitRetries && retryNum > router.maxRetries {
+		w.Header().Set(HeaderNoRetry, "1")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, err := routerutils.ReadBody(req)
 	if err != nil {
 		router.respondWithError(w, err)
 		return
@@ -255,7 +605,10 @@ func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	ctx := req.Context()
+	ctx := context.WithValue(req.Context(), rawBodyKey, body)
+	if retryNum > 0 {
+		ctx = context.WithValue(ctx, retryKey, &retry{num: retryNum, reason: req.Header.Get(HeaderRetryReason)})
+	}
 	switch eventsAPIEvent.Type {
 	case slackevents.URLVerification:
 		router.handleURLVerification(ctx, w, &eventsAPIEvent)
@@ -286,7 +639,11 @@ func (r *Router) handleURLVerification(ctx context.Context, w http.ResponseWrite
 		r.respondWithError(w, fmt.Errorf("expected EventsAPIURLVerificationEvent but got %T", e.Data))
 		return
 	}
-	resp, err := r.urlVerificationHandler.HandleURLVerification(ctx, ev)
+	var resp *slackevents.ChallengeResponse
+	err := r.recoverPanic(func() (err error) {
+		resp, err = r.urlVerificationHandler.HandleURLVerification(ctx, ev)
+		return err
+	})
 	if err != nil {
 		r.respondWithError(w, err)
 		return
@@ -297,6 +654,42 @@ func (r *Router) handleURLVerification(ctx context.Context, w http.ResponseWrite
 }
 
 func (r *Router) handleCallbackEvent(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIEvent) {
+	eventID := r.dedupEventID(e)
+	if eventID != "" && r.dedupStore.Seen(eventID) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	ctx = context.WithValue(ctx, envelopeKey, e)
+	if cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent); ok && cb.InnerEvent != nil {
+		ctx = routerutils.WithInnerEvent(ctx, *cb.InnerEvent)
+	}
+	err := r.recoverPanic(func() error {
+		return r.callHandlers(ctx, e)
+	})
+	if err != nil && !errors.Is(err, routererrors.NotInterested) {
+		if eventID != "" {
+			// Let Slack's retries be processed again.
+			r.dedupStore.Forget(eventID)
+		}
+		r.respondWithError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// dedupEventID returns the event ID of e if it should be deduplicated, or an empty string otherwise.
+func (r *Router) dedupEventID(e *slackevents.EventsAPIEvent) string {
+	if r.dedupStore == nil {
+		return ""
+	}
+	cb, ok := e.Data.(*slackevents.EventsAPICallbackEvent)
+	if !ok {
+		return ""
+	}
+	return cb.EventID
+}
+
+func (r *Router) callHandlers(ctx context.Context, e *slackevents.EventsAPIEvent) error {
 	var err error = routererrors.NotInterested
 	handlers, ok := r.callbackHandlers[e.InnerEvent.Type]
 	if ok {
@@ -311,16 +704,13 @@ func (r *Router) handleCallbackEvent(ctx context.Context, w http.ResponseWriter,
 	if errors.Is(err, routererrors.NotInterested) {
 		err = r.handleFallback(ctx, e)
 	}
-
-	if err != nil && !errors.Is(err, routererrors.NotInterested) {
-		r.respondWithError(w, err)
-		return
-	}
-	w.WriteHeader(http.StatusOK)
+	return err
 }
 
 func (r *Router) handleAppRateLimited(ctx context.Context, w http.ResponseWriter, e *slackevents.EventsAPIAppRateLimited) {
-	err := r.appRateLimitedHandler.HandleAppRateLimited(ctx, e)
+	err := r.recoverPanic(func() error {
+		return r.appRateLimitedHandler.HandleAppRateLimited(ctx, e)
+	})
 	if err != nil {
 		r.respondWithError(w, err)
 		return
@@ -335,6 +725,90 @@ func (r *Router) handleFallback(ctx context.Context, e *slackevents.EventsAPIEve
 	return r.fallbackHandler.HandleEventsAPIEvent(ctx, e)
 }
 
+const (
+	// HeaderRetryNum is a header that holds the number of times Slack has retried to deliver the event.
+	HeaderRetryNum = "X-Slack-Retry-Num"
+	// HeaderRetryReason is a header that holds the reason why Slack retried to deliver the event.
+	HeaderRetryReason = "X-Slack-Retry-Reason"
+	// HeaderNoRetry is a header that asks Slack not to retry deliveries of the event.
+	HeaderNoRetry = "X-Slack-No-Retry"
+)
+
+type contextKey int
+
+const (
+	retryKey contextKey = iota
+	rawBodyKey
+	requestIDKey
+	envelopeKey
+)
+
+// Envelope returns the outer event (i.e. the envelope) of the event being processed.
+//
+// This is useful for handlers that receive only inner events, such as the ones registered with OnMessage,
+// to access metadata in the envelope (e.g. `team_id` and `api_app_id`).
+// Metadata specific to `event_callback` (e.g. `event_id`, `event_time`, and `authed_teams`) is in `*slackevents.EventsAPICallbackEvent`, e.g.
+//
+//	if cb, ok := eventrouter.Envelope(ctx).Data.(*slackevents.EventsAPICallbackEvent); ok {
+//		eventTime := time.Unix(int64(cb.EventTime), 0)
+//	}
+//
+// It returns nil if the context is not passed from the Router.
+func Envelope(ctx context.Context) *slackevents.EventsAPIEvent {
+	e, _ := ctx.Value(envelopeKey).(*slackevents.EventsAPIEvent)
+	return e
+}
+
+// RequestID returns the ID of the request being processed, which is useful to correlate logs.
+//
+// It returns an empty string if the context is not passed from the Router.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RawBody returns the raw body of the request being processed, which is useful if you want to parse it by yourself.
+//
+// The returned bytes are shared with the Router and reused after the request is processed,
+// so they must not be modified nor retained after the handler returns. Copy them if you need to do so.
+func RawBody(ctx context.Context) []byte {
+	body, _ := ctx.Value(rawBodyKey).([]byte)
+	return body
+}
+
+type retry struct {
+	num    int
+	reason string
+}
+
+// RetryNum returns the number of times Slack has retried to deliver the event being processed.
+//
+// It returns 0 if the event is delivered for the first time.
+func RetryNum(ctx context.Context) int {
+	if r, ok := ctx.Value(retryKey).(*retry); ok {
+		return r.num
+	}
+	return 0
+}
+
+// RetryReason returns the reason why Slack retried to deliver the event being processed (e.g. `http_timeout`).
+//
+// It returns an empty string if the event is delivered for the first time.
+func RetryReason(ctx context.Context) string {
+	if r, ok := ctx.Value(retryKey).(*retry); ok {
+		return r.reason
+	}
+	return ""
+}
+
+// recoverPanic calls f and converts panics in it into errors unless WithoutPanicRecovery is given.
+func (r *Router) recoverPanic(f func() error) (err error) {
+	if !r.skipPanicRecovery {
+		defer routerutils.RecoverPanic(&err)
+	}
+	return f()
+}
+
 func (r *Router) respondWithError(w http.ResponseWriter, err error) {
 	routerutils.RespondWithError(w, err, r.verboseResponse)
 }
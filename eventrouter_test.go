@@ -6,18 +6,31 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
 	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/apphome"
+	"github.com/genkami/go-slack-event-router/dedup"
 	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/file"
 	"github.com/genkami/go-slack-event-router/internal/testutils"
+	"github.com/genkami/go-slack-event-router/items"
+	"github.com/genkami/go-slack-event-router/membership"
+	"github.com/genkami/go-slack-event-router/message"
 )
 
 var _ = Describe("EventRouter", func() {
@@ -51,110 +64,1139 @@ var _ = Describe("EventRouter", func() {
 				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
 			})
 		})
+
+		Context("when WithSigningSecret is given an empty secret", func() {
+			It("returns an error that mentions the empty secret", func() {
+				_, err := eventrouter.New(eventrouter.WithSigningSecret(""))
+				Expect(err).To(MatchError(MatchRegexp("empty signing secret")))
+			})
+		})
+
+		Context("when WithSigningSecrets is given an empty secret", func() {
+			It("returns an error that mentions the empty secret", func() {
+				_, err := eventrouter.New(eventrouter.WithSigningSecret("THE_TOKEN"), eventrouter.WithSigningSecrets("OLD_TOKEN", ""))
+				Expect(err).To(MatchError(MatchRegexp("empty signing secret")))
+			})
+		})
+	})
+
+	Describe("WithSigningSecret", func() {
+		var (
+			r       *eventrouter.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is valid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with Unauthorized", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("when the timestamp is too old", func() {
+			It("responds with BadRequest", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("WithSigningSecrets", func() {
+		var (
+			r       *eventrouter.Router
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecrets("THE_OLD_TOKEN", "THE_NEW_TOKEN"), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the request is signed with the second token", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest("THE_NEW_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the request is signed with an unknown token", func() {
+			It("responds with Unauthorized", func() {
+				req, err := NewSignedRequest("OOPS_I_MISTOOK_THE_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("WithSignatureTolerance", func() {
+		var (
+			r       *eventrouter.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithSignatureTolerance(2*time.Hour), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the timestamp is within the tolerance", func() {
+			It("responds with 200", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is out of the tolerance", func() {
+			It("responds with BadRequest", func() {
+				ts := time.Now().Add(-3 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when a negative tolerance is given", func() {
+			It("returns an error", func() {
+				_, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithSignatureTolerance(-time.Minute))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("InsecureSkipVerification", func() {
+		var (
+			r       *eventrouter.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is valid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is too old", func() {
+			It("responds with 200", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("Retries", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+			numHandlerCalled int
+			retryNum         int
+			retryReason      string
+			handler          = eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+				numHandlerCalled++
+				retryNum = eventrouter.RetryNum(ctx)
+				retryReason = eventrouter.RetryReason(ctx)
+				return nil
+			})
+			newRetriedRequest = func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(eventrouter.HeaderRetryNum, "2")
+				req.Header.Set(eventrouter.HeaderRetryReason, "http_timeout")
+				return req
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			retryNum = -1
+			retryReason = "NOT_SET"
+		})
+
+		Context("when the event is delivered for the first time", func() {
+			It("passes zero values to handlers", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(retryNum).To(Equal(0))
+				Expect(retryReason).To(Equal(""))
+			})
+		})
+
+		Context("when the event is retried", func() {
+			It("passes the retry headers to handlers", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRetriedRequest())
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(retryNum).To(Equal(2))
+				Expect(retryReason).To(Equal("http_timeout"))
+			})
+		})
+
+		Context("when the retry count does not exceed WithMaxRetries", func() {
+			It("calls handlers", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxRetries(2))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRetriedRequest())
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the retry count exceeds WithMaxRetries", func() {
+			It("acknowledges the event without calling handlers", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxRetries(1))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, handler)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, newRetriedRequest())
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(resp.Header.Get(eventrouter.HeaderNoRetry)).To(Equal("1"))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when WithMaxRetries is negative", func() {
+			It("returns an error", func() {
+				_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxRetries(-1))
+				Expect(err).To(MatchError(MatchRegexp("WithMaxRetries")))
+			})
+		})
+	})
+
+	Describe("WithDedupStore", func() {
+		var (
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("drops events that have already been seen", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithDedupStore(dedup.NewMemoryStore(10, time.Minute)))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled := 0
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				numHandlerCalled++
+				return nil
+			}))
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			}
+			Expect(numHandlerCalled).To(Equal(1))
+		})
+
+		It("processes retries of events whose handlers failed", func() {
+			r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithDedupStore(dedup.NewMemoryStore(10, time.Minute)))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled := 0
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				numHandlerCalled++
+				if numHandlerCalled == 1 {
+					return routererrors.ErrServiceUnavailable
+				}
+				return nil
+			}))
+			statuses := make([]int, 0, 3)
+			for i := 0; i < 3; i++ {
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				if i > 0 {
+					req.Header.Set(eventrouter.HeaderRetryNum, fmt.Sprint(i))
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				statuses = append(statuses, w.Result().StatusCode)
+			}
+			Expect(statuses).To(Equal([]int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}))
+			Expect(numHandlerCalled).To(Equal(2))
+		})
+	})
+
+	Describe("WithMaxBodyBytes", func() {
+		var (
+			content = `
+			{
+				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
+				"type": "url_verification"
+			}`
+		)
+
+		Context("when the body does not exceed the limit", func() {
+			It("responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxBodyBytes(int64(len(content))))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the body exceeds the limit", func() {
+			It("responds with RequestEntityTooLarge", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxBodyBytes(int64(len(content)-1)))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("when the limit is math.MaxInt64", func() {
+			It("responds with 200", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxBodyBytes(math.MaxInt64))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the body exceeds the default limit", func() {
+			It("responds with RequestEntityTooLarge before verifying the signature", func() {
+				token := "THE_TOKEN"
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+				Expect(err).NotTo(HaveOccurred())
+				huge := `{"type": "url_verification", "challenge": "` + strings.Repeat("x", 2<<20) + `"}`
+				req, err := NewSignedRequest(token, huge, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("when the limit is not positive", func() {
+			It("returns an error", func() {
+				_, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithMaxBodyBytes(0))
+				Expect(err).To(MatchError(MatchRegexp("WithMaxBodyBytes")))
+			})
+		})
+	})
+
+	Describe("RawBody", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the raw body to handlers, reading the body only once", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var rawBody []byte
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+				rawBody = eventrouter.RawBody(ctx)
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			body := &countingReader{r: req.Body}
+			req.Body = body
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(string(rawBody)).To(Equal(content))
+			Expect(body.n).To(Equal(len(content)))
+		})
+
+		It("does not mix up bodies of concurrent requests", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var (
+				mu         sync.Mutex
+				mismatches []string
+			)
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, e *slackevents.EventsAPIEvent) error {
+				text := e.InnerEvent.Data.(*slackevents.MessageEvent).Text
+				if !strings.Contains(string(eventrouter.RawBody(ctx)), text) {
+					mu.Lock()
+					mismatches = append(mismatches, text)
+					mu.Unlock()
+				}
+				return nil
+			}))
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					body := strings.Replace(content, "Hello world", fmt.Sprintf("message #%d %s", i, strings.Repeat("x", i*10)), 1)
+					req, err := NewSignedRequest(token, body, nil)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				}(i)
+			}
+			wg.Wait()
+			Expect(mismatches).To(BeEmpty())
+		})
+	})
+
+	Describe("RequestID", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the same request ID to both the response and the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var requestID string
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+				requestID = eventrouter.RequestID(ctx)
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Result().Header.Get("X-Request-ID")).NotTo(BeEmpty())
+			Expect(requestID).To(Equal(w.Result().Header.Get("X-Request-ID")))
+		})
+
+		It("uses the ID given by the X-Request-ID header", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var requestID string
+			r.On(slackevents.Message, eventrouter.HandlerFunc(func(ctx context.Context, _ *slackevents.EventsAPIEvent) error {
+				requestID = eventrouter.RequestID(ctx)
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("X-Request-ID", "req-12345")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().Header.Get("X-Request-ID")).To(Equal("req-12345"))
+			Expect(requestID).To(Equal("req-12345"))
+		})
+	})
+
+	Describe("OnPinAdded", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "pin_added",
+					"user": "U12345",
+					"channel_id": "C12345",
+					"item": {
+						"type": "message",
+						"channel": "C12345",
+						"message": {"type": "message", "user": "U67890", "text": "Hello world", "ts": "1355517523.000005"},
+						"created": 1360782804,
+						"created_by": "U12345"
+					},
+					"event_ts": "1360782804.083113"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the pin_added event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *items.Event
+			r.OnPinAdded(items.HandlerFunc(func(_ context.Context, e *items.Event) error {
+				received = e
+				return nil
+			}), items.InChannel("C12345"), items.ItemType("message"))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.Type).To(Equal(items.PinAdded))
+			Expect(received.User).To(Equal("U12345"))
+			Expect(received.ChannelID).To(Equal("C12345"))
+			Expect(received.Item.Message).NotTo(BeNil())
+			Expect(received.Item.Message.Text).To(Equal("Hello world"))
+		})
+
+		It("does not pass pin_added events to handlers for pin_removed", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled := 0
+			r.OnPinRemoved(items.HandlerFunc(func(_ context.Context, _ *items.Event) error {
+				numHandlerCalled++
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("OnStarAdded", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "star_added",
+					"user": "U12345",
+					"item": {
+						"type": "message",
+						"channel": "C12345",
+						"message": {"type": "message", "user": "U67890", "text": "Hello world", "ts": "1355517523.000005"}
+					},
+					"event_ts": "1360782804.083113"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the star_added event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *items.Event
+			r.OnStarAdded(items.HandlerFunc(func(_ context.Context, e *items.Event) error {
+				received = e
+				return nil
+			}), items.InChannel("C12345"))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.Type).To(Equal(items.StarAdded))
+			Expect(received.Channel()).To(Equal("C12345"))
+		})
+	})
+
+	Describe("OnFileShared", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "file_shared",
+					"channel_id": "C12345",
+					"file_id": "F12345",
+					"user_id": "U12345",
+					"file": {"id": "F12345"},
+					"event_ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the file_shared event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *file.Event
+			r.OnFileShared(file.HandlerFunc(func(_ context.Context, e *file.Event) error {
+				received = e
+				return nil
+			}), file.InChannel("C12345"))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.Type).To(Equal("file_shared"))
+			Expect(received.ChannelID).To(Equal("C12345"))
+			Expect(received.FileID).To(Equal("F12345"))
+			Expect(received.UserID).To(Equal("U12345"))
+			Expect(received.File.ID).To(Equal("F12345"))
+		})
+
+		It("does not pass file_shared events to handlers for file_created", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled := 0
+			r.OnFileCreated(file.HandlerFunc(func(_ context.Context, _ *file.Event) error {
+				numHandlerCalled++
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(numHandlerCalled).To(Equal(0))
+		})
+	})
+
+	Describe("OnAppHomeOpened", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "app_home_opened",
+					"user": "U12345",
+					"channel": "D12345",
+					"event_ts": "1515449522000016",
+					"tab": "home"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the app_home_opened event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *slackevents.AppHomeOpenedEvent
+			r.OnAppHomeOpened(apphome.HandlerFunc(func(_ context.Context, e *slackevents.AppHomeOpenedEvent) error {
+				received = e
+				return nil
+			}), apphome.Tab(apphome.TabHome))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.User).To(Equal("U12345"))
+			Expect(received.Tab).To(Equal("home"))
+		})
+	})
+
+	Describe("OnMemberJoinedChannel", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "member_joined_channel",
+					"user": "U12345",
+					"channel": "C12345",
+					"channel_type": "C",
+					"team": "TXXXXXXXX"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the member_joined_channel event to the handler", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *slackevents.MemberJoinedChannelEvent
+			r.OnMemberJoinedChannel(membership.ChannelJoinHandlerFunc(func(_ context.Context, e *slackevents.MemberJoinedChannelEvent) error {
+				received = e
+				return nil
+			}), membership.InChannel("C12345"))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.User).To(Equal("U12345"))
+			Expect(received.Channel).To(Equal("C12345"))
+		})
+	})
+
+	Describe("Envelope", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"authed_teams": ["TXXXXXXXX", "TYYYYYYYY"],
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("passes the envelope to handlers of inner events", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var envelope *slackevents.EventsAPIEvent
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				envelope = eventrouter.Envelope(ctx)
+				return nil
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(envelope).NotTo(BeNil())
+			Expect(envelope.TeamID).To(Equal("TXXXXXXXX"))
+			Expect(envelope.APIAppID).To(Equal("AXXXXXXXXX"))
+			cb, ok := envelope.Data.(*slackevents.EventsAPICallbackEvent)
+			Expect(ok).To(BeTrue())
+			Expect(cb.EventID).To(Equal("Ev08MFMKH6"))
+			Expect(cb.EventTime).To(Equal(1234567890))
+			Expect(cb.AuthedTeams).To(Equal([]string{"TXXXXXXXX", "TYYYYYYYY"}))
+		})
+
+		It("returns nil if the context is not passed from the Router", func() {
+			Expect(eventrouter.Envelope(context.Background())).To(BeNil())
+		})
+	})
+
+	Describe("OnMessage with message.FromApp", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"subtype": "bot_message",
+					"channel": "C2147483705",
+					"text": "PR opened",
+					"bot_id": "B12345",
+					"username": "GitHub",
+					"app_id": "A12345",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("routes messages by the App ID in the raw event", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var matched, other int
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				other++
+				return nil
+			}), message.FromApp("A67890"))
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+				matched++
+				return nil
+			}), message.FromApp("A12345"))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(matched).To(Equal(1))
+			Expect(other).To(Equal(0))
+		})
 	})
 
-	Describe("WithSigningSecret", func() {
+	Describe("OnMessage in shared channels", func() {
 		var (
-			r       *eventrouter.Router
 			token   = "THE_TOKEN"
 			content = `
 			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
-				"type": "url_verification"
+				"token": "XXYYZZ",
+				"team_id": "THOME",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"user_team": "TEXTERNAL",
+					"source_team": "TEXTERNAL",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
 			}`
 		)
-		BeforeEach(func() {
-			var err error
-			r, err = eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.VerboseResponse())
+
+		It("passes the team ID of the receiving workspace to message predicates", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled := 0
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				numHandlerCalled++
+				Expect(message.TeamID(ctx)).To(Equal("THOME"))
+				return nil
+			}), message.IsExternalShared())
+			req, err := NewSignedRequest(token, content, nil)
 			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(numHandlerCalled).To(Equal(1))
 		})
+	})
 
-		Context("when the signature is valid", func() {
-			It("responds with 200", func() {
-				req, err := NewSignedRequest(token, content, nil)
-				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
-			})
+	Describe("WithClient", func() {
+		var (
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
+			}`
+		)
+
+		It("lets message handlers post ephemeral messages", func() {
+			var posted url.Values
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.ParseForm()).To(Succeed())
+				posted = req.PostForm
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok": true, "message_ts": "1355517523.000006"}`))
+			}))
+			defer api.Close()
+			client := slack.New("xoxb-token", slack.OptionAPIURL(api.URL+"/"))
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithClient(client))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				_, err := message.PostEphemeral(ctx, "hi there")
+				return err
+			}))
+			req, err := NewSignedRequest(token, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(posted.Get("channel")).To(Equal("C2147483705"))
+			Expect(posted.Get("user")).To(Equal("U2147483697"))
+			Expect(posted.Get("text")).To(Equal("hi there"))
 		})
 
-		Context("when the signature is invalid", func() {
-			It("responds with Unauthorized", func() {
-				req, err := NewSignedRequest(token, content, nil)
-				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
-			})
+		It("lets membership handlers send direct messages", func() {
+			var posted url.Values
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.ParseForm()).To(Succeed())
+				posted = req.PostForm
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok": true, "channel": "D12345", "ts": "1355517523.000006"}`))
+			}))
+			defer api.Close()
+			client := slack.New("xoxb-token", slack.OptionAPIURL(api.URL+"/"))
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token), eventrouter.WithClient(client))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnMemberJoinedChannel(membership.ChannelJoinHandlerFunc(func(ctx context.Context, e *slackevents.MemberJoinedChannelEvent) error {
+				return membership.SendDirectMessage(ctx, e.User, slack.MsgOptionText("Welcome!", false))
+			}))
+			req, err := NewSignedRequest(token, `
+			{
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "member_joined_channel",
+					"user": "U2147483697",
+					"channel": "C2147483705",
+					"channel_type": "C",
+					"team": "TXXXXXXXX"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH7",
+				"event_time": 1234567890
+			}`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(posted.Get("channel")).To(Equal("U2147483697"))
+			Expect(posted.Get("text")).To(Equal("Welcome!"))
 		})
 
-		Context("when the timestamp is too old", func() {
-			It("responds with BadRequest", func() {
-				ts := time.Now().Add(-1 * time.Hour)
-				req, err := NewSignedRequest(token, content, &ts)
+		Context("when no client is given", func() {
+			It("makes PostEphemeral fail", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
 				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				var postErr error
+				r.OnMessage(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					_, postErr = message.PostEphemeral(ctx, "hi there")
+					return nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(postErr).To(MatchError(message.ErrNoClient))
 			})
 		})
 	})
 
-	Describe("InsecureSkipVerification", func() {
+	Describe("Registration", func() {
 		var (
-			r       *eventrouter.Router
 			token   = "THE_TOKEN"
 			content = `
 			{
-				"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
-				"challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P",
-				"type": "url_verification"
+				"token": "XXYYZZ",
+				"team_id": "TXXXXXXXX",
+				"api_app_id": "AXXXXXXXXX",
+				"event": {
+					"type": "message",
+					"channel": "C2147483705",
+					"user": "U2147483697",
+					"text": "Hello world",
+					"ts": "1355517523.000005"
+				},
+				"type": "event_callback",
+				"event_id": "Ev08MFMKH6",
+				"event_time": 1234567890
 			}`
+			handler = eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+				return nil
+			})
 		)
-		BeforeEach(func() {
-			var err error
-			r, err = eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.VerboseResponse())
-			Expect(err).NotTo(HaveOccurred())
-		})
 
-		Context("when the signature is valid", func() {
-			It("responds with 200", func() {
-				req, err := NewSignedRequest(token, content, nil)
+		Context("when handlers are registered after ServeHTTP is called", func() {
+			It("panics", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
 				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
-			})
-		})
-
-		Context("when the signature is invalid", func() {
-			It("responds with 200", func() {
+				r.On(slackevents.Message, handler)
 				req, err := NewSignedRequest(token, content, nil)
 				Expect(err).NotTo(HaveOccurred())
-				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(func() { r.On(slackevents.Message, handler) }).To(Panic())
+				Expect(func() {
+					r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error { return nil }))
+				}).To(Panic())
+				Expect(func() { r.SetFallback(handler) }).To(Panic())
 			})
 		})
 
-		Context("when the timestamp is too old", func() {
-			It("responds with 200", func() {
-				ts := time.Now().Add(-1 * time.Hour)
-				req, err := NewSignedRequest(token, content, &ts)
+		Context("when handlers are registered concurrently with ServeHTTP", func() {
+			// This is meant to be run with `go test -race`.
+			It("either registers them or panics without data races", func() {
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
 				Expect(err).NotTo(HaveOccurred())
-				w := httptest.NewRecorder()
-				r.ServeHTTP(w, req)
-				resp := w.Result()
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(2)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						defer func() { _ = recover() }()
+						r.On(slackevents.Message, handler)
+					}()
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						req, err := NewSignedRequest(token, content, nil)
+						Expect(err).NotTo(HaveOccurred())
+						w := httptest.NewRecorder()
+						r.ServeHTTP(w, req)
+						Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+					}()
+				}
+				wg.Wait()
+				Expect(func() { r.On(slackevents.Message, handler) }).To(Panic())
 			})
 		})
 	})
@@ -188,6 +1230,36 @@ var _ = Describe("EventRouter", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
 		})
+
+		Context("when the request is signed", func() {
+			It("verifies the signature and returns the given challenge without calling other handlers", func() {
+				token := "THE_TOKEN"
+				r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+				Expect(err).NotTo(HaveOccurred())
+				numFallbackCalled := 0
+				r.SetFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numFallbackCalled++
+					return nil
+				}))
+				req, err := NewSignedRequest(token, `
+				{
+					"token": "Jhj5dZrVaK7ZwHHjRyZWjbDl",
+					"challenge": "THE_SECRET_CHALLENGE_VALUE",
+					"type": "url_verification"
+				}`, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+				body := slackevents.ChallengeResponse{}
+				err = json.NewDecoder(resp.Body).Decode(&body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body.Challenge).To(Equal("THE_SECRET_CHALLENGE_VALUE"))
+				Expect(numFallbackCalled).To(Equal(0))
+			})
+		})
 	})
 
 	Describe("App Rate Limited", func() {
@@ -346,6 +1418,20 @@ var _ = Describe("EventRouter", func() {
 			})
 		})
 
+		Context("when a handler returned one of the predefined HttpErrors", func() {
+			It("responds with a corresponding status code", func() {
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					return errors.WithMessage(routererrors.ErrForbidden, "you ain't allowed")
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
 		Context("when a handler returned an error that equals to HttpError using errors.As", func() {
 			It("responds with a corresponding status code", func() {
 				code := http.StatusUnauthorized
@@ -361,6 +1447,35 @@ var _ = Describe("EventRouter", func() {
 			})
 		})
 
+		Context("when a handler panics", func() {
+			It("responds with InternalServerError", func() {
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					panic("oops")
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(w.Body.String()).To(ContainSubstring("oops"))
+			})
+		})
+
+		Context("when a handler panics and WithoutPanicRecovery is given", func() {
+			It("does not recover from the panic", func() {
+				r, err := eventrouter.New(eventrouter.InsecureSkipVerification(), eventrouter.WithoutPanicRecovery())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					panic("oops")
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				Expect(func() { r.ServeHTTP(w, req) }).To(PanicWith("oops"))
+			})
+		})
+
 		Describe("Fallback", func() {
 			var (
 				numFirstHandlerCalled  int
@@ -491,6 +1606,48 @@ var _ = Describe("EventRouter", func() {
 			})
 		})
 
+		Context("when predicates of the registered handler are not satisfied", func() {
+			It("calls fallback handler", func() {
+				numCalled := 0
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					numHandlerCalled++
+					return nil
+				}), message.Channel("CANOTHERCHANNEL"))
+				r.SetFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numCalled++
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when predicates of the registered handler are satisfied", func() {
+			It("does not call fallback handler", func() {
+				numCalled := 0
+				r.OnMessage(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					numHandlerCalled++
+					return nil
+				}), message.Channel("C2147483705"))
+				r.SetFallback(eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+					numCalled++
+					return nil
+				}))
+				req, err := http.NewRequest(http.MethodPost, "http:/example.com/path", bytes.NewReader([]byte(content)))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(numCalled).To(Equal(0))
+			})
+		})
+
 		Context("when no handler except for fallback is registered", func() {
 			It("calls fallback handler", func() {
 				numCalled := 0
@@ -549,3 +1706,61 @@ func NewSignedRequest(signingSecret string, body string, ts *time.Time) (*http.R
 	}
 	return req, nil
 }
+
+// countingReader counts the number of bytes read from it.
+type countingReader struct {
+	r io.ReadCloser
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	token := "THE_TOKEN"
+	content := `
+	{
+		"token": "XXYYZZ",
+		"team_id": "TXXXXXXXX",
+		"api_app_id": "AXXXXXXXXX",
+		"event": {
+			"type": "message",
+			"channel": "C2147483705",
+			"user": "U2147483697",
+			"text": "Hello world",
+			"ts": "1355517523.000005"
+		},
+		"type": "event_callback",
+		"event_id": "Ev08MFMKH6",
+		"event_time": 1234567890
+	}`
+	r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.On(slackevents.Message, eventrouter.HandlerFunc(func(_ context.Context, _ *slackevents.EventsAPIEvent) error {
+		return nil
+	}))
+	header := http.Header{}
+	if err := testutils.AddSignature(header, []byte(token), []byte(content), time.Now()); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/path/to/callback", strings.NewReader(content))
+		req.Header = header
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status: %d", w.Code)
+		}
+	}
+}
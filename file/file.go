@@ -0,0 +1,156 @@
+// Package file provides handlers to process `file_shared` and `file_created` events.
+//
+// These events carry only the ID of a file. Use FetchInfo to get the details of the file before handlers are called.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/file_shared
+//   - https://api.slack.com/events/file_created
+package file
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// Event is a `file_shared` or `file_created` event.
+//
+// `slack-go/slack` parses these events into types for the RTM API, which lack some fields sent via the Events API,
+// so this package has its own type.
+type Event struct {
+	// Type is either `file_shared` or `file_created`.
+	Type string `json:"type"`
+
+	// ChannelID is the channel that the file is shared in. It is empty for `file_created` events.
+	ChannelID string `json:"channel_id"`
+
+	FileID string `json:"file_id"`
+	UserID string `json:"user_id"`
+
+	// File has only its ID unless FetchInfo is used.
+	File slack.File `json:"file"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// Handler processes `file_shared` and `file_created` events.
+type Handler interface {
+	HandleFileEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleFileEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type inChannelPredicate struct {
+	ids []string
+}
+
+// InChannel is a predicate that is considered to be "true" if and only if a file is shared in one of the given channels.
+//
+// `file_created` events have no channels, so they are never considered to be "true".
+//
+// It panics if no channel ID is given.
+func InChannel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("file.InChannel: at least one channel ID must be given")
+	}
+	return &inChannelPredicate{ids: ids}
+}
+
+func (p *inChannelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.ChannelID == "" || !contains(p.ids, e.ChannelID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleFileEvent(ctx, e)
+	})
+}
+
+type fromUserPredicate struct {
+	ids []string
+}
+
+// FromUser is a predicate that is considered to be "true" if and only if a file is shared or created by one of the given users.
+//
+// It panics if no user ID is given.
+func FromUser(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("file.FromUser: at least one user ID must be given")
+	}
+	return &fromUserPredicate{ids: ids}
+}
+
+func (p *fromUserPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if e.UserID == "" || !contains(p.ids, e.UserID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleFileEvent(ctx, e)
+	})
+}
+
+// InfoClient is a client that gets the details of files. `*slack.Client` implements this.
+type InfoClient interface {
+	GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error)
+}
+
+type fetchInfoPredicate struct {
+	client InfoClient
+}
+
+// FetchInfo is a predicate that calls `files.info` to fill `Event.File` before the inner handler is called.
+// It is always considered to be "true" unless the API call fails, in which case the error is returned.
+//
+// This makes an API call for each event, so give it first to Build (or `eventrouter.Router.OnFileShared`),
+// which makes it run after the other predicates, so that the API is called only for events that the handler is interested in.
+//
+// It panics if client is nil.
+func FetchInfo(client InfoClient) Predicate {
+	if client == nil {
+		panic("file.FetchInfo: client must not be nil")
+	}
+	return &fetchInfoPredicate{client: client}
+}
+
+func (p *fetchInfoPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		id := e.FileID
+		if id == "" {
+			id = e.File.ID
+		}
+		f, _, _, err := p.client.GetFileInfoContext(ctx, id, 0, 0)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get file info of %s", id)
+		}
+		e.File = *f
+		return h.HandleFileEvent(ctx, e)
+	})
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	for _, p := range preds {
+		h = p.Wrap(h)
+	}
+	return h
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package file_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/file"
+)
+
+var _ = Describe("File", func() {
+	var (
+		numHandlerCalled int
+		received         *file.Event
+		innerHandler     = file.HandlerFunc(func(_ context.Context, e *file.Event) error {
+			numHandlerCalled++
+			received = e
+			return nil
+		})
+		ctx context.Context
+	)
+	BeforeEach(func() {
+		numHandlerCalled = 0
+		received = nil
+		ctx = context.Background()
+	})
+
+	Describe("InChannel", func() {
+		Context("when the file is shared in one of the given channels", func() {
+			It("calls the inner handler", func() {
+				h := file.InChannel("C12345", "C67890").Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", ChannelID: "C67890", FileID: "F12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the file is shared in another channel", func() {
+			It("does not call the inner handler", func() {
+				h := file.InChannel("C12345").Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", ChannelID: "C67890", FileID: "F12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the event has no channel", func() {
+			It("does not call the inner handler", func() {
+				h := file.InChannel("C12345").Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_created", FileID: "F12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel ID is given", func() {
+			It("panics", func() {
+				Expect(func() { file.InChannel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromUser", func() {
+		Context("when the file is shared by one of the given users", func() {
+			It("calls the inner handler", func() {
+				h := file.FromUser("U12345", "U67890").Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", UserID: "U67890", FileID: "F12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the file is shared by another user", func() {
+			It("does not call the inner handler", func() {
+				h := file.FromUser("U12345").Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", UserID: "U67890", FileID: "F12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no user ID is given", func() {
+			It("panics", func() {
+				Expect(func() { file.FromUser() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FetchInfo", func() {
+		var (
+			client *stubInfoClient
+		)
+		BeforeEach(func() {
+			client = &stubInfoClient{
+				files: map[string]*slack.File{
+					"F12345": {ID: "F12345", Name: "report.pdf", Filetype: "pdf"},
+				},
+			}
+		})
+
+		Context("when the API call succeeds", func() {
+			It("calls the inner handler with the details of the file", func() {
+				h := file.FetchInfo(client).Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", FileID: "F12345", File: slack.File{ID: "F12345"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(received.File.Name).To(Equal("report.pdf"))
+				Expect(received.File.Filetype).To(Equal("pdf"))
+				Expect(client.requested).To(Equal([]string{"F12345"}))
+			})
+		})
+
+		Context("when the API call fails", func() {
+			It("returns the error without calling the inner handler", func() {
+				h := file.FetchInfo(client).Wrap(innerHandler)
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", FileID: "F67890"})
+				Expect(err).To(MatchError(ContainSubstring("file_not_found")))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when it is given first to Build", func() {
+			It("does not call the API if the other predicates are not satisfied", func() {
+				h := file.Build(innerHandler, file.FetchInfo(client), file.InChannel("C12345"))
+				err := h.HandleFileEvent(ctx, &file.Event{Type: "file_shared", ChannelID: "C67890", FileID: "F12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(client.requested).To(BeEmpty())
+			})
+		})
+
+		Context("when the client is nil", func() {
+			It("panics", func() {
+				Expect(func() { file.FetchInfo(nil) }).To(Panic())
+			})
+		})
+	})
+})
+
+type stubInfoClient struct {
+	files     map[string]*slack.File
+	requested []string
+}
+
+func (c *stubInfoClient) GetFileInfoContext(_ context.Context, fileID string, _, _ int) (*slack.File, []slack.Comment, *slack.Paging, error) {
+	c.requested = append(c.requested, fileID)
+	f, ok := c.files[fileID]
+	if !ok {
+		return nil, nil, nil, errors.New("file_not_found")
+	}
+	return f, nil, nil, nil
+}
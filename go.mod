@@ -3,8 +3,13 @@ module github.com/genkami/go-slack-event-router
 go 1.16
 
 require (
+	github.com/aws/aws-lambda-go v1.28.0
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.14.0
 	github.com/pkg/errors v0.9.1
 	github.com/slack-go/slack v0.9.5
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/time v0.3.0
 )
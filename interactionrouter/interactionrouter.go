@@ -4,19 +4,32 @@
 package interactionrouter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"golang.org/x/time/rate"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/predicates"
+	"github.com/genkami/go-slack-event-router/internal/ratelimit"
 	"github.com/genkami/go-slack-event-router/internal/routerutils"
 	"github.com/genkami/go-slack-event-router/signature"
 )
 
 // Handler processes interaction callbacks sent from Slack.
+//
+// The context passed to handlers is derived from the context of the HTTP request, so it is canceled when the request is canceled.
+// In the Async mode, it is detached from the request instead.
 type Handler interface {
 	HandleInteraction(context.Context, *slack.InteractionCallback) error
 }
@@ -27,6 +40,165 @@ func (f HandlerFunc) HandleInteraction(ctx context.Context, callback *slack.Inte
 	return f(ctx, callback)
 }
 
+// ViewSubmissionHandler processes `view_submission` callbacks sent from Slack.
+//
+// Unlike Handler, it can return a response to the callback.
+// If it returns a non-nil response, the Router writes the response to the body in the following form:
+//
+//	{"response_action": "errors", "errors": {"BLOCK_ID": "error message"}}
+//
+// If it returns nil, the Router responds with an empty body, which closes the view.
+//
+// For more details, see https://api.slack.com/surfaces/modals/using#handling_submissions.
+type ViewSubmissionHandler interface {
+	HandleViewSubmission(context.Context, *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error)
+}
+
+type ViewSubmissionHandlerFunc func(context.Context, *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error)
+
+func (f ViewSubmissionHandlerFunc) HandleViewSubmission(ctx context.Context, callback *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+	return f(ctx, callback)
+}
+
+// BlockSuggestionHandler processes `block_suggestion` callbacks sent from Slack, which are sent when users type in external select menus.
+//
+// The options returned from the handler are shown to users.
+// Note that Slack waits for the response only for 3 seconds, so the handler must return as fast as possible.
+//
+// For more details, see https://api.slack.com/reference/block-kit/block-elements#external_select.
+type BlockSuggestionHandler interface {
+	HandleBlockSuggestion(context.Context, *slack.InteractionCallback) (*BlockSuggestionResponse, error)
+}
+
+type BlockSuggestionHandlerFunc func(context.Context, *slack.InteractionCallback) (*BlockSuggestionResponse, error)
+
+func (f BlockSuggestionHandlerFunc) HandleBlockSuggestion(ctx context.Context, callback *slack.InteractionCallback) (*BlockSuggestionResponse, error) {
+	return f(ctx, callback)
+}
+
+// DialogSubmissionHandler processes `dialog_submission` callbacks sent from legacy dialogs.
+//
+// If it returns validation errors, the Router writes them to the body in the following form and the dialog stays open:
+//
+//	{"errors": [{"name": "FIELD_NAME", "error": "error message"}]}
+//
+// If it returns nil or no errors, the Router responds with an empty body, which closes the dialog.
+//
+// For more details, see https://api.slack.com/dialogs#validation.
+type DialogSubmissionHandler interface {
+	HandleDialogSubmission(context.Context, *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error)
+}
+
+type DialogSubmissionHandlerFunc func(context.Context, *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error)
+
+func (f DialogSubmissionHandlerFunc) HandleDialogSubmission(ctx context.Context, callback *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error) {
+	return f(ctx, callback)
+}
+
+// BlockSuggestionResponse is a response to `block_suggestion` callbacks.
+//
+// Only one of Options and OptionGroups should be set. If OptionGroups is set, Options is ignored.
+type BlockSuggestionResponse struct {
+	Options      []*slack.OptionBlockObject
+	OptionGroups []*slack.OptionGroupBlockObject
+}
+
+func (resp *BlockSuggestionResponse) body() interface{} {
+	if resp == nil {
+		resp = &BlockSuggestionResponse{}
+	}
+	if resp.OptionGroups != nil {
+		return &slack.OptionGroupsResponse{OptionGroups: resp.OptionGroups}
+	}
+	// Slack treats a response without `options` as an error, so we always respond with an array.
+	body := struct {
+		Options []*slack.OptionBlockObject `json:"options"`
+	}{Options: resp.Options}
+	if body.Options == nil {
+		body.Options = []*slack.OptionBlockObject{}
+	}
+	return &body
+}
+
+// Middleware decorates a Handler to add cross-cutting behavior such as metrics and panic recovery.
+type Middleware func(Handler) Handler
+
+// Recover returns a Middleware that recovers from panics in handlers and converts them into errors.
+//
+// Note that the Router recovers from panics by default unless WithoutPanicRecovery is given,
+// so this is only useful when you want to handle panics in a certain position of the middleware chain.
+//
+// The errors are processed in the same way as other errors returned from handlers, e.g. passed to the function given to WithErrorHandler.
+func Recover() Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) (err error) {
+			defer routerutils.RecoverPanic(&err)
+			return h.HandleInteraction(ctx, callback)
+		})
+	}
+}
+
+// Retry returns a Middleware that calls handlers again when they fail with retryable errors, up to attempts times in total.
+//
+// backoff returns the delay before the n-th retry (starting from 1). If it is nil, handlers are retried immediately.
+// retryable reports whether an error is transient. If it is nil, all errors are retried.
+// `routererrors.NotInterested` is never retried.
+//
+// It never waits beyond the deadline of the context (e.g. set by WithHandlerTimeout); it returns the last error instead.
+// Since Slack expects responses within 3 seconds, this is most useful in the Async mode, where the response has already been sent.
+//
+// It is intended to wrap a single handler, e.g. `r.On(t, Retry(...)(h))`.
+// When it is installed with Use, it retries all handlers and the fallback together,
+// so with AllMatches handlers that have already succeeded are called again.
+// The response set by a failed attempt is discarded before retrying.
+//
+// It panics if attempts is less than 1.
+func Retry(attempts int, backoff func(n int) time.Duration, retryable func(error) bool) Middleware {
+	if attempts < 1 {
+		panic("interactionrouter.Retry: attempts must be at least 1")
+	}
+	return func(h Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+			var err error
+			for n := 0; n < attempts; n++ {
+				if n > 0 {
+					if !waitForRetry(ctx, backoff, n) {
+						return err
+					}
+					setResponse(ctx, nil)
+				}
+				err = h.HandleInteraction(ctx, callback)
+				if err == nil || errors.Is(err, routererrors.NotInterested) || (retryable != nil && !retryable(err)) {
+					return err
+				}
+			}
+			return err
+		})
+	}
+}
+
+// waitForRetry waits before the n-th retry. It returns false if the context is done or its deadline is too close to retry.
+func waitForRetry(ctx context.Context, backoff func(n int) time.Duration, n int) bool {
+	var d time.Duration
+	if backoff != nil {
+		d = backoff(n)
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Add(d).Before(deadline) {
+		return false
+	}
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // Predicate disthinguishes whether or not a certain handler should process coming events.
 type Predicate interface {
 	Wrap(Handler) Handler
@@ -41,6 +213,10 @@ func Type(typeName slack.InteractionType) Predicate {
 	return &typePredicate{typeName: typeName}
 }
 
+func (p *typePredicate) Describe() string {
+	return fmt.Sprintf("Type(%s)", p.typeName)
+}
+
 func (p *typePredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
 		if callback.Type != p.typeName {
@@ -56,28 +232,147 @@ type blockActionPredicate struct {
 }
 
 // BlockAction is a predicate that is considered to be "true" if and only if the InteractionCallback has a BlockAction identified by blockID and actionID.
+//
+// The matched BlockAction is passed to the inner handler through the context. It can be retrieved with MatchedAction.
 func BlockAction(blockID, actionID string) Predicate {
 	return &blockActionPredicate{blockID: blockID, actionID: actionID}
 }
 
+func (p *blockActionPredicate) Describe() string {
+	return fmt.Sprintf("BlockAction(%s,%s)", p.blockID, p.actionID)
+}
+
 func (p *blockActionPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
-		if FindBlockAction(callback, p.blockID, p.actionID) == nil {
+		ba := FindBlockAction(callback, p.blockID, p.actionID)
+		if ba == nil {
 			return routererrors.NotInterested
 		}
+		ctx = context.WithValue(ctx, matchedActionKey, ba)
 		return h.HandleInteraction(ctx, callback)
 	})
 }
 
+type actionIDPredicate struct {
+	ids []string
+}
+
+// ActionID is a predicate that is considered to be "true" if and only if the InteractionCallback has a BlockAction whose action ID is one of the given ones,
+// regardless of its block ID.
+//
+// This is useful when action IDs are unique across blocks. It is "true" if any of the BlockActions in the callback qualifies,
+// and the first one is passed to the inner handler through the context, which can be retrieved with MatchedAction.
+//
+// It panics if no action ID is given.
+func ActionID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.ActionID: at least one action ID must be given")
+	}
+	return &actionIDPredicate{ids: ids}
+}
+
+func (p *actionIDPredicate) Describe() string {
+	return fmt.Sprintf("ActionID(%s)", strings.Join(p.ids, ","))
+}
+
+func (p *actionIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		for _, ba := range callback.ActionCallback.BlockActions {
+			if contains(p.ids, ba.ActionID) {
+				ctx = context.WithValue(ctx, matchedActionKey, ba)
+				return h.HandleInteraction(ctx, callback)
+			}
+		}
+		return routererrors.NotInterested
+	})
+}
+
+type blockIDPredicate struct {
+	ids []string
+}
+
+// BlockID is a predicate that is considered to be "true" if and only if the InteractionCallback has a BlockAction whose block ID is one of the given ones,
+// regardless of its action ID.
+//
+// This is useful when all elements in a block (e.g. a block of related buttons) should be processed by the same handler.
+// It is "true" if any of the BlockActions in the callback qualifies, and the first one is passed to the inner handler through the context,
+// which can be retrieved with MatchedAction.
+//
+// It panics if no block ID is given.
+func BlockID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.BlockID: at least one block ID must be given")
+	}
+	return &blockIDPredicate{ids: ids}
+}
+
+func (p *blockIDPredicate) Describe() string {
+	return fmt.Sprintf("BlockID(%s)", strings.Join(p.ids, ","))
+}
+
+func (p *blockIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		for _, ba := range callback.ActionCallback.BlockActions {
+			if contains(p.ids, ba.BlockID) {
+				ctx = context.WithValue(ctx, matchedActionKey, ba)
+				return h.HandleInteraction(ctx, callback)
+			}
+		}
+		return routererrors.NotInterested
+	})
+}
+
+type actionValuePredicate struct {
+	blockID  string
+	actionID string
+	value    string
+}
+
+// ActionValue is a predicate that is considered to be "true" if and only if the InteractionCallback has a BlockAction identified by blockID and actionID, and its value equals to the given one.
+//
+// The value of a BlockAction is taken from `value` for buttons, and from `selected_option.value` for elements that have options
+// (e.g. static selects, external selects, overflow menus, and radio buttons). The predicate is considered to be "true" if either of them equals to the given one.
+//
+// The matched BlockAction is passed to the inner handler through the context. It can be retrieved with MatchedAction.
+func ActionValue(blockID, actionID, value string) Predicate {
+	return &actionValuePredicate{blockID: blockID, actionID: actionID, value: value}
+}
+
+func (p *actionValuePredicate) Describe() string {
+	return fmt.Sprintf("ActionValue(%s,%s,%s)", p.blockID, p.actionID, p.value)
+}
+
+func (p *actionValuePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		for _, ba := range callback.ActionCallback.BlockActions {
+			if ba.BlockID != p.blockID || ba.ActionID != p.actionID {
+				continue
+			}
+			if ba.Value == p.value || ba.SelectedOption.Value == p.value {
+				ctx = context.WithValue(ctx, matchedActionKey, ba)
+				return h.HandleInteraction(ctx, callback)
+			}
+		}
+		return routererrors.NotInterested
+	})
+}
+
 type callbackIDPredicate struct {
 	id string
 }
 
 // CallbackID is a predicate that is considered to be "true" if and only if the callback ID of the InteractionCallback equals to the given one.
+//
+// Note that `view_submission` and `view_closed` callbacks have their callback IDs in `View.CallbackID`, not in the top-level `CallbackID`.
+// Use ViewCallbackID for them.
 func CallbackID(id string) Predicate {
 	return &callbackIDPredicate{id: id}
 }
 
+func (p *callbackIDPredicate) Describe() string {
+	return fmt.Sprintf("CallbackID(%s)", p.id)
+}
+
 func (p *callbackIDPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
 		if callback.CallbackID != p.id {
@@ -87,195 +382,1886 @@ func (p *callbackIDPredicate) Wrap(h Handler) Handler {
 	})
 }
 
-type channelPredicate struct {
+type viewCallbackIDPredicate struct {
 	id string
 }
 
-// Channel is a predicate that is considered to be "true" if and only if the InteractionCallback is triggered in the given channel.
-func Channel(id string) Predicate {
-	return &channelPredicate{id: id}
+// ViewCallbackID is a predicate that is considered to be "true" if and only if the callback ID of the view (i.e. `View.CallbackID`) equals to the given one.
+//
+// This is useful for `view_submission` and `view_closed` callbacks, whose top-level `CallbackID` is empty.
+func ViewCallbackID(id string) Predicate {
+	return &viewCallbackIDPredicate{id: id}
 }
 
-func (p *channelPredicate) Wrap(h Handler) Handler {
+func (p *viewCallbackIDPredicate) Describe() string {
+	return fmt.Sprintf("ViewCallbackID(%s)", p.id)
+}
+
+func (p *viewCallbackIDPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
-		if callback.Channel.ID != p.id {
+		if callback.View.CallbackID != p.id {
 			return routererrors.NotInterested
 		}
 		return h.HandleInteraction(ctx, callback)
 	})
 }
 
-// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
-func Build(h Handler, preds ...Predicate) Handler {
-	for _, p := range preds {
-		h = p.Wrap(h)
-	}
-	return h
+type viewExternalIDPredicate struct {
+	id string
 }
 
-// Option configures the Router.
-type Option interface {
-	apply(*Router)
+// ViewExternalID is a predicate that is considered to be "true" if and only if the external ID of the view (i.e. `View.ExternalID`) equals to the given one.
+//
+// External IDs are set by apps when they open views, and are unique among all views of the team.
+func ViewExternalID(id string) Predicate {
+	return &viewExternalIDPredicate{id: id}
 }
 
-type optionFunc func(*Router)
-
-func (f optionFunc) apply(r *Router) {
-	f(r)
+func (p *viewExternalIDPredicate) Describe() string {
+	return fmt.Sprintf("ViewExternalID(%s)", p.id)
 }
 
-// InsecureSkipVerification skips verifying request signatures.
-// This is useful to test your handlers, but do not use this in production environments.
-func InsecureSkipVerification() Option {
-	return optionFunc(func(r *Router) {
-		r.skipVerification = true
+func (p *viewExternalIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.View.ExternalID != p.id {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
 	})
 }
 
-// WithSigningSecret sets a signing token to verify requests from Slack.
-//
-// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
-func WithSigningSecret(token string) Option {
-	return optionFunc(func(r *Router) {
-		r.signingSecret = token
-	})
+type typedCallbackIDPredicate struct {
+	typeName slack.InteractionType
+	id       string
 }
 
-// If VerboseResponse is set, the Router shows error details when it fails to process requests.
-func VerboseResponse() Option {
-	return optionFunc(func(r *Router) {
-		r.verboseResponse = true
-	})
+// Shortcut is a predicate that is considered to be "true" if and only if the InteractionCallback is a global shortcut with the given callback ID.
+//
+// This is equivalent to the combination of `Type(slack.InteractionTypeShortcut)` and `CallbackID(callbackID)`.
+func Shortcut(callbackID string) Predicate {
+	return &typedCallbackIDPredicate{typeName: slack.InteractionTypeShortcut, id: callbackID}
 }
 
-// Router is an http.Handler that processes interaction callbacks from Slack.
+// MessageAction is a predicate that is considered to be "true" if and only if the InteractionCallback is a message shortcut with the given callback ID.
 //
-// For more details, see https://api.slack.com/interactivity/handling.
-type Router struct {
-	signingSecret    string
-	skipVerification bool
-	handlers         map[slack.InteractionType][]Handler
-	fallbackHandler  Handler
-	verboseResponse  bool
-	httpHandler      http.Handler
+// This is equivalent to the combination of `Type(slack.InteractionTypeMessageAction)` and `CallbackID(callbackID)`.
+func MessageAction(callbackID string) Predicate {
+	return &typedCallbackIDPredicate{typeName: slack.InteractionTypeMessageAction, id: callbackID}
 }
 
-// New creates a new Router.
-//
-// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
-func New(opts ...Option) (*Router, error) {
-	r := &Router{
-		handlers: make(map[slack.InteractionType][]Handler),
-	}
-	for _, o := range opts {
-		o.apply(r)
-	}
-	if r.signingSecret == "" && !r.skipVerification {
-		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
-	}
-	if r.signingSecret != "" && r.skipVerification {
-		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
+func (p *typedCallbackIDPredicate) Describe() string {
+	if p.typeName == slack.InteractionTypeMessageAction {
+		return fmt.Sprintf("MessageAction(%s)", p.id)
 	}
+	return fmt.Sprintf("Shortcut(%s)", p.id)
+}
 
-	r.httpHandler = http.HandlerFunc(r.serveHTTP)
-	if !r.skipVerification {
-		r.httpHandler = &signature.Middleware{
-			SigningSecret:   r.signingSecret,
-			VerboseResponse: r.verboseResponse,
-			Handler:         r.httpHandler,
+func (p *typedCallbackIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Type != p.typeName || callback.CallbackID != p.id {
+			return routererrors.NotInterested
 		}
-	}
-	return r, nil
+		return h.HandleInteraction(ctx, callback)
+	})
 }
 
-// On registers a handler for a specific event type.
-//
-// Unlike `eventrouter.Router`, the Router does not have type-specific `OnXXX` methods because all types of
-// interactions share the same struct in `slack-go/slack`.
+type viewClosedPredicate struct {
+	callbackID string
+}
+
+// ViewClosed is a predicate that is considered to be "true" if and only if the InteractionCallback is a `view_closed` callback from the view with the given callback ID.
 //
-// If more than one handlers are registered, the first ones take precedence.
+// Slack sends `view_closed` callbacks when users dismiss modals, only if the modals are opened with `notify_on_close` set.
+// This is useful to clean up temporary state; the closed view (including its `private_metadata` and state) is available in `callback.View`.
+// No response body is expected for `view_closed` callbacks, so the Router just responds with 200.
 //
-// Handlers may return `routererrors.NotInterested` (or its equivalents in the sense of `errors.Is`). In such case the Router falls back to other handlers.
+// This is equivalent to the combination of `Type(slack.InteractionTypeViewClosed)` and `ViewCallbackID(callbackID)`.
+func ViewClosed(callbackID string) Predicate {
+	return &viewClosedPredicate{callbackID: callbackID}
+}
+
+func (p *viewClosedPredicate) Describe() string {
+	return fmt.Sprintf("ViewClosed(%s)", p.callbackID)
+}
+
+func (p *viewClosedPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Type != slack.InteractionTypeViewClosed || callback.View.CallbackID != p.callbackID {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type channelPredicate struct {
+	ids []string
+}
+
+// Channel is a predicate that is considered to be "true" if and only if the InteractionCallback is triggered in one of the given channels.
 //
-// Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
+// Note that not all types of interactions have channels, e.g. `view_submission` callbacks and global shortcuts usually do not.
+// Such callbacks are never considered to be "true".
 //
-// If any other errors are returned, the Router responds with Internal Server Error.
-func (r *Router) On(typeName slack.InteractionType, h Handler, preds ...Predicate) {
-	h = Build(h, preds...)
-	handlers, ok := r.handlers[typeName]
-	if !ok {
-		handlers = make([]Handler, 0)
+// It panics if no channel ID is given.
+func Channel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.Channel: at least one channel ID must be given")
 	}
-	handlers = append(handlers, h)
-	r.handlers[typeName] = handlers
+	return &channelPredicate{ids: ids}
 }
 
-// SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming event.
-//
-// If more than one handlers are registered, the last one will be used.
-func (r *Router) SetFallback(h Handler) {
-	r.fallbackHandler = h
+func (p *channelPredicate) Describe() string {
+	return fmt.Sprintf("Channel(%s)", strings.Join(p.ids, ","))
 }
 
-func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	router.httpHandler.ServeHTTP(w, req)
+func (p *channelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Channel.ID == "" || !contains(p.ids, callback.Channel.ID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
 }
 
-func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
-	callback := slack.InteractionCallback{}
-	if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
-		router.respondWithError(w,
-			errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "unexpected Content-Type"))
-		return
-	}
-	payload := req.FormValue("payload")
-	if payload == "" {
-		router.respondWithError(w,
-			errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "missing payload"))
-		return
-	}
-	if err := json.Unmarshal([]byte(payload), &callback); err != nil {
-		router.respondWithError(w, err)
-		return
+type appIDPredicate struct {
+	ids []string
+}
+
+// AppID is a predicate that is considered to be "true" if and only if the InteractionCallback is sent to one of the given apps.
+//
+// This is useful when a single endpoint serves more than one apps. Give all of their signing secrets to WithSigningSecrets,
+// and distinguish the apps with this predicate.
+//
+// It panics if no app ID is given.
+func AppID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.AppID: at least one app ID must be given")
 	}
+	return &appIDPredicate{ids: ids}
+}
 
-	router.handleInteractionCallback(req.Context(), w, &callback)
+func (p *appIDPredicate) Describe() string {
+	return fmt.Sprintf("AppID(%s)", strings.Join(p.ids, ","))
 }
 
-func (r *Router) handleInteractionCallback(ctx context.Context, w http.ResponseWriter, callback *slack.InteractionCallback) {
-	var err error = routererrors.NotInterested
-	handlers, ok := r.handlers[callback.Type]
-	if ok {
-		for _, h := range handlers {
-			err = h.HandleInteraction(ctx, callback)
-			if !errors.Is(err, routererrors.NotInterested) {
-				break
-			}
+func (p *appIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.APIAppID == "" || !contains(p.ids, callback.APIAppID) {
+			return routererrors.NotInterested
 		}
-	}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
 
-	if errors.Is(err, routererrors.NotInterested) {
-		err = r.handleFallback(ctx, callback)
-	}
+type actionFreshnessPredicate struct {
+	max time.Duration
+}
 
-	if err != nil && !errors.Is(err, routererrors.NotInterested) {
-		r.respondWithError(w, err)
-		return
+// ActionFreshness is a predicate that is considered to be "true" if and only if the action of the InteractionCallback happened within max before now.
+//
+// The time of the action is taken from the `action_ts` of the callback or of its first block action.
+// Callbacks without valid `action_ts` (e.g. `view_submission`) are never considered to be "true".
+//
+// The current time is taken from Now, so it follows the clock set by WithClock.
+// This is useful to reject stale actions for security-sensitive operations.
+// Note that this does not replace signature verification, which rejects replayed requests by their own timestamps.
+//
+// It panics if max is not positive.
+func ActionFreshness(max time.Duration) Predicate {
+	if max <= 0 {
+		panic("interactionrouter.ActionFreshness: max must be positive")
 	}
-	w.WriteHeader(http.StatusOK)
+	return &actionFreshnessPredicate{max: max}
 }
 
-func (r *Router) handleFallback(ctx context.Context, callback *slack.InteractionCallback) error {
-	if r.fallbackHandler == nil {
-		return routererrors.NotInterested
-	}
-	return r.fallbackHandler.HandleInteraction(ctx, callback)
+func (p *actionFreshnessPredicate) Describe() string {
+	return fmt.Sprintf("ActionFreshness(%s)", p.max)
 }
 
-func (r *Router) respondWithError(w http.ResponseWriter, err error) {
+func (p *actionFreshnessPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		t, err := routerutils.ParseTimestamp(actionTs(callback))
+		if err != nil || Now(ctx).Sub(t) > p.max {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type actionContainerPredicate struct {
+	types []string
+}
+
+// ActionContainer is a predicate that is considered to be "true" if and only if the InteractionCallback comes from one of the given types of containers.
+//
+// The container of a callback (`callback.Container`) tells where the interactive element is:
+//   - `Type` is `message` (with `ChannelID` and `MessageTs`), `message_attachment`, or `view` (with `ViewID`).
+//   - For the `view` type, `callback.View.Type` tells whether the view is a `modal` or the `home` tab.
+//
+// Both are compared to the given types, so `ActionContainer("message")` matches actions in messages,
+// `ActionContainer("view")` matches those in any views, and `ActionContainer("home")` matches only those in the home tab.
+//
+// It panics if no type is given.
+func ActionContainer(types ...string) Predicate {
+	if len(types) == 0 {
+		panic("interactionrouter.ActionContainer: at least one container type must be given")
+	}
+	return &actionContainerPredicate{types: types}
+}
+
+func (p *actionContainerPredicate) Describe() string {
+	return fmt.Sprintf("ActionContainer(%s)", strings.Join(p.types, ","))
+}
+
+func (p *actionContainerPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		t := callback.Container.Type
+		if t == "" {
+			return routererrors.NotInterested
+		}
+		if !contains(p.types, t) && !(t == "view" && callback.View.Type != "" && contains(p.types, string(callback.View.Type))) {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type teamIDPredicate struct {
+	ids []string
+}
+
+// TeamID is a predicate that is considered to be "true" if and only if the InteractionCallback is sent from one of the given teams (workspaces).
+//
+// Note that the team ID may be empty for callbacks from organization-wide apps in Enterprise Grid. Such callbacks are never considered to be "true".
+// Use EnterpriseID to handle them.
+//
+// It panics if no team ID is given.
+func TeamID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.TeamID: at least one team ID must be given")
+	}
+	return &teamIDPredicate{ids: ids}
+}
+
+func (p *teamIDPredicate) Describe() string {
+	return fmt.Sprintf("TeamID(%s)", strings.Join(p.ids, ","))
+}
+
+func (p *teamIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Team.ID == "" || !contains(p.ids, callback.Team.ID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type enterpriseIDPredicate struct {
+	ids []string
+}
+
+// EnterpriseID is a predicate that is considered to be "true" if and only if the InteractionCallback is sent from one of the given Enterprise Grid organizations.
+//
+// It panics if no enterprise ID is given.
+func EnterpriseID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.EnterpriseID: at least one enterprise ID must be given")
+	}
+	return &enterpriseIDPredicate{ids: ids}
+}
+
+func (p *enterpriseIDPredicate) Describe() string {
+	return fmt.Sprintf("EnterpriseID(%s)", strings.Join(p.ids, ","))
+}
+
+func (p *enterpriseIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.Enterprise.ID == "" || !contains(p.ids, callback.Enterprise.ID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type userIDPredicate struct {
+	ids []string
+}
+
+// UserID is a predicate that is considered to be "true" if and only if the InteractionCallback is triggered by one of the given users.
+//
+// Like other predicates, this makes the Router fall back to other handlers when an unexpected user triggers the interaction.
+// If you want to reject such interactions explicitly instead, check `callback.User.ID` in your handler and return an error like `routererrors.HttpError(http.StatusForbidden)`.
+//
+// It panics if no user ID is given.
+func UserID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("interactionrouter.UserID: at least one user ID must be given")
+	}
+	return &userIDPredicate{ids: ids}
+}
+
+func (p *userIDPredicate) Describe() string {
+	return fmt.Sprintf("UserID(%s)", strings.Join(p.ids, ","))
+}
+
+func (p *userIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.User.ID == "" || !contains(p.ids, callback.User.ID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+type notPredicate struct {
+	pred Predicate
+}
+
+// Not is a predicate that is considered to be "true" if and only if the given Predicate is considered to be "false".
+//
+// If the given Predicate returns an error other than `routererrors.NotInterested`, the error is returned as is.
+func Not(pred Predicate) Predicate {
+	return &notPredicate{pred: pred}
+}
+
+func (p *notPredicate) Describe() string {
+	return fmt.Sprintf("Not(%s)", describe(p.pred))
+}
+
+func (p *notPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		return predicates.Not(ctx, probe(p.pred, callback), handle(h, callback))
+	})
+}
+
+type anyPredicate struct {
+	preds []Predicate
+}
+
+// Any is a predicate that is considered to be "true" if and only if at least one of the given Predicates is considered to be "true".
+//
+// The given Predicates are evaluated in order without calling the inner handler, and the evaluation stops at the first one that is considered to be "true".
+// Then the inner handler is called exactly once.
+// If one of the given Predicates returns an error other than `routererrors.NotInterested`, the evaluation stops and the error is returned as is.
+//
+// It panics if no Predicate is given.
+func Any(preds ...Predicate) Predicate {
+	if len(preds) == 0 {
+		panic("interactionrouter.Any: at least one predicate must be given")
+	}
+	return &anyPredicate{preds: preds}
+}
+
+func (p *anyPredicate) Describe() string {
+	descs := make([]string, 0, len(p.preds))
+	for _, pred := range p.preds {
+		descs = append(descs, describe(pred))
+	}
+	return fmt.Sprintf("Any(%s)", strings.Join(descs, ","))
+}
+
+func (p *anyPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		probes := make([]predicates.Probe, 0, len(p.preds))
+		for _, pred := range p.preds {
+			probes = append(probes, probe(pred, callback))
+		}
+		return predicates.Any(ctx, probes, handle(h, callback))
+	})
+}
+
+// probe converts the given Predicate into a Probe for the callback.
+func probe(pred Predicate, callback *slack.InteractionCallback) predicates.Probe {
+	return func(ctx context.Context, next func(context.Context) error) error {
+		return pred.Wrap(HandlerFunc(func(innerCtx context.Context, _ *slack.InteractionCallback) error {
+			return next(innerCtx)
+		})).HandleInteraction(ctx, callback)
+	}
+}
+
+// handle returns a function that calls h with the callback.
+func handle(h Handler, callback *slack.InteractionCallback) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return h.HandleInteraction(ctx, callback)
+	}
+}
+
+// Describer is implemented by Predicates that can describe themselves, e.g. `Type(block_actions)`.
+// The descriptions are used in route traces (see WithRouteTrace).
+//
+// Predicates that do not implement Describer are described by their type names. Use Named to give them names.
+type Describer interface {
+	Describe() string
+}
+
+type namedPredicate struct {
+	name string
+	pred Predicate
+}
+
+// Named returns a Predicate that works in the same way as pred, except that it is described as name in route traces.
+func Named(name string, pred Predicate) Predicate {
+	return &namedPredicate{name: name, pred: pred}
+}
+
+func (p *namedPredicate) Describe() string {
+	return p.name
+}
+
+func (p *namedPredicate) Wrap(h Handler) Handler {
+	return p.pred.Wrap(h)
+}
+
+func describe(pred Predicate) string {
+	if d, ok := pred.(Describer); ok {
+		return d.Describe()
+	}
+	return fmt.Sprintf("%T", pred)
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	for _, p := range preds {
+		h = p.Wrap(h)
+	}
+	return h
+}
+
+// Option configures the Router.
+type Option interface {
+	apply(*Router)
+}
+
+type optionFunc func(*Router)
+
+func (f optionFunc) apply(r *Router) {
+	f(r)
+}
+
+// InsecureSkipVerification skips verifying request signatures.
+// This is useful to test your handlers, but do not use this in production environments.
+func InsecureSkipVerification() Option {
+	return optionFunc(func(r *Router) {
+		r.skipVerification = true
+	})
+}
+
+// WithSigningSecret sets a signing token to verify requests from Slack.
+//
+// New returns an error if token is empty, which typically means that the secret is read from an unset environment variable.
+//
+// For more details, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func WithSigningSecret(token string) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecret = token
+		if token == "" {
+			r.hasEmptySigningSecret = true
+		}
+	})
+}
+
+// WithSigningSecrets sets signing tokens to verify requests from Slack.
+//
+// A request is considered to be valid if it is signed with any of the given tokens and the one given to WithSigningSecret.
+// This is useful to rotate signing secrets without downtime.
+// Note that the cost of verification increases in proportion to the number of the tokens.
+func WithSigningSecrets(tokens ...string) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecrets = append(r.signingSecrets, tokens...)
+		for _, t := range tokens {
+			if t == "" {
+				r.hasEmptySigningSecret = true
+			}
+		}
+	})
+}
+
+// WithSigningSecretFunc sets a function that returns the current signing secret, so that secrets can be rotated without restarting the process.
+//
+// The secret returned from f is cached for ttl to avoid calling f (e.g. fetching the secret from a vault) for every request.
+// Note that it may take up to ttl for a rotated secret to take effect. If ttl is zero, f is called for every request.
+// If f returns an error, the Router responds with Internal Server Error. Errors are not cached.
+//
+// This can be used together with WithSigningSecret and WithSigningSecrets.
+func WithSigningSecretFunc(f func() ([]byte, error), ttl time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signingSecretFunc = f
+		r.signingSecretTTL = ttl
+	})
+}
+
+// WithSignatureTolerance sets the maximum difference between request timestamps and the current time.
+//
+// If this is not set, signature.DefaultTolerance is used. New returns an error if d is negative.
+func WithSignatureTolerance(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.signatureTolerance = d
+	})
+}
+
+// WithRouteTrace makes the Router log how the Predicates of each handler are evaluated for each callback, e.g.
+//
+//	Type(block_actions)=true, BlockAction(B1,A1)=false -> NotInterested
+//
+// Predicates are listed in the order of evaluation, and the ones that are not evaluated are omitted.
+// See Describer and Named for how Predicates are described.
+//
+// This is meant for debugging why handlers are not called. Do not set this in production, since it adds overhead and writes a log for every handler.
+func WithRouteTrace() Option {
+	return optionFunc(func(r *Router) {
+		r.routeTrace = true
+	})
+}
+
+// WithClock sets a function that returns the current time.
+//
+// The Router uses it to verify timestamps of requests, and time-based predicates such as ActionFreshness use it via Now.
+// If this is not set, time.Now is used. This is mainly useful to make tests deterministic.
+func WithClock(clock func() time.Time) Option {
+	return optionFunc(func(r *Router) {
+		r.clock = clock
+	})
+}
+
+// WithMaxBodyBytes limits the size of request bodies to n bytes.
+//
+// The Router responds with Request Entity Too Large to requests whose bodies exceed the limit.
+// If this is not set, the limit is 1 MiB, which is large enough for any requests from Slack.
+func WithMaxBodyBytes(n int64) Option {
+	return optionFunc(func(r *Router) {
+		r.maxBodyBytes = n
+	})
+}
+
+// WithPreVerifyHook sets a function that is called for each request before the Router verifies its signature.
+//
+// The function is called before the request body is read, so it can cheaply reject requests, e.g. by an IP allowlist or during maintenance.
+// If it returns an error, the Router responds with Forbidden (or the status code of the error if it is an HttpError) without calling any handlers.
+// As with signature verification failures, the function set by WithErrorHandler is not called in this case.
+func WithPreVerifyHook(f func(*http.Request) error) Option {
+	return optionFunc(func(r *Router) {
+		r.preVerifyHook = f
+	})
+}
+
+// If VerboseResponse is set, the Router shows error details when it fails to process requests.
+func VerboseResponse() Option {
+	return optionFunc(func(r *Router) {
+		r.verboseResponse = true
+	})
+}
+
+// WithErrorHandler sets a function that is called when the Router fails to process requests.
+//
+// The function is responsible for writing the response. It can be used to log errors, to emit metrics, or to customize the response.
+// If this is not set, the Router responds with the status code corresponding to the error (or Internal Server Error), with error details only if VerboseResponse is set.
+//
+// Note that the function is not called when the Router fails to verify request signatures.
+func WithErrorHandler(h func(http.ResponseWriter, *http.Request, error)) Option {
+	return optionFunc(func(r *Router) {
+		r.errorHandler = h
+	})
+}
+
+// Logger is a logger that the Router writes logs to.
+//
+// `*slog.Logger` satisfies this interface.
+type Logger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(_ string, _ ...interface{})  {}
+func (nopLogger) Error(_ string, _ ...interface{}) {}
+
+// WithLogger sets a logger.
+//
+// The Router logs failures of signature verification and malformed payloads at the info level, and errors returned from handlers at the error level.
+// If this is not set, the Router does not write any logs.
+func WithLogger(l Logger) Option {
+	return optionFunc(func(r *Router) {
+		r.logger = l
+	})
+}
+
+// maxRateLimitKeys is the maximum number of keys whose rate limits are tracked by WithRateLimit.
+const maxRateLimitKeys = 10000
+
+// WithRateLimit limits the rate of callbacks for each key returned from keyFunc, e.g. ByTeamID or ByUserID.
+//
+// Callbacks of each key are allowed up to limit per second with bursts of at most burst callbacks.
+// The Router responds to callbacks that exceed the limit with Too Many Requests without calling any handlers
+// (HandleSocketMode returns an error equivalent to `routererrors.HttpError(http.StatusTooManyRequests)` instead).
+// Callbacks whose keys are empty are never limited.
+//
+// The Router tracks the limits of the 10000 most recently seen keys. A key evicted from them is not limited until it comes again.
+func WithRateLimit(limit rate.Limit, burst int, keyFunc func(*slack.InteractionCallback) string) Option {
+	return optionFunc(func(r *Router) {
+		r.rateLimit = limit
+		r.rateLimitBurst = burst
+		r.rateLimitKeyFunc = keyFunc
+		r.hasRateLimit = true
+	})
+}
+
+// ByTeamID is a key function for WithRateLimit that limits callbacks per team.
+func ByTeamID(callback *slack.InteractionCallback) string {
+	return callback.Team.ID
+}
+
+// ByUserID is a key function for WithRateLimit that limits callbacks per user.
+//
+// User IDs are unique only within a workspace (or an organization), so the key also has the team ID.
+func ByUserID(callback *slack.InteractionCallback) string {
+	if callback.User.ID == "" {
+		return ""
+	}
+	return callback.Team.ID + ":" + callback.User.ID
+}
+
+// WithoutPanicRecovery disables the Router's own recovery from panics in handlers.
+//
+// By default, the Router converts panics into errors, logs them and responds with Internal Server Error.
+// This is useful if you have your own recovery middleware.
+func WithoutPanicRecovery() Option {
+	return optionFunc(func(r *Router) {
+		r.skipPanicRecovery = true
+	})
+}
+
+// WithClient sets a Slack API client that handlers can use via Client.
+//
+// This is optional. If this is not set (or nil is given), Client returns nil, whose methods always return ErrNoClient.
+func WithClient(c *slack.Client) Option {
+	return optionFunc(func(r *Router) {
+		r.client = c
+	})
+}
+
+// DefaultHTTPClientTimeout is the timeout of the HTTP client that RespondWith uses if WithHTTPClient is not given.
+const DefaultHTTPClientTimeout = 10 * time.Second
+
+var defaultHTTPClient = &http.Client{Timeout: DefaultHTTPClientTimeout}
+
+// WithHTTPClient sets an HTTP client that RespondWith uses to send messages to response URLs.
+//
+// If this is not set (or nil is given), a client whose timeout is DefaultHTTPClientTimeout is used.
+func WithHTTPClient(c *http.Client) Option {
+	return optionFunc(func(r *Router) {
+		r.httpClient = c
+	})
+}
+
+// Observer receives notifications at key points of processing requests.
+//
+// It is intended to be used to collect metrics (e.g. with Prometheus or OpenTelemetry) without making this package depend on them.
+// Methods are called synchronously, so they should return quickly.
+// If you only need some of them, embed NopObserver into your own struct.
+//
+// Be careful about the cardinality of labels. Interaction types and callback IDs are usually fine,
+// but IDs that differ from request to request (e.g. trigger IDs, user IDs) should not be used as labels.
+type Observer interface {
+	// RequestReceived is called when the Router receives an HTTP request.
+	RequestReceived(req *http.Request)
+	// SignatureFailed is called when the Router fails to verify the signature of a request.
+	SignatureFailed(req *http.Request, err error)
+	// PayloadParsed is called when the Router successfully parses the payload of a request.
+	PayloadParsed(ctx context.Context, callback *slack.InteractionCallback)
+	// HandlerMatched is called when all Predicates of a handler are considered to be "true" (or the fallback handler is chosen),
+	// right before the handler is called. Under AllMatches, it is called for each of such handlers.
+	HandlerMatched(ctx context.Context, callback *slack.InteractionCallback)
+	// HandlerFinished is called when the Router finishes calling handlers, with the time it took and the error returned from them.
+	// The error is NotInterested if no handlers are interested in the callback.
+	HandlerFinished(ctx context.Context, callback *slack.InteractionCallback, d time.Duration, err error)
+}
+
+// NopObserver is an Observer that does nothing.
+type NopObserver struct{}
+
+func (NopObserver) RequestReceived(_ *http.Request)                                {}
+func (NopObserver) SignatureFailed(_ *http.Request, _ error)                       {}
+func (NopObserver) PayloadParsed(_ context.Context, _ *slack.InteractionCallback)  {}
+func (NopObserver) HandlerMatched(_ context.Context, _ *slack.InteractionCallback) {}
+func (NopObserver) HandlerFinished(_ context.Context, _ *slack.InteractionCallback, _ time.Duration, _ error) {
+}
+
+// WithObserver sets an Observer.
+//
+// If this is not set, the Router does not notify anything.
+func WithObserver(o Observer) Option {
+	return optionFunc(func(r *Router) {
+		r.observer = o
+	})
+}
+
+// WithSpanStarter sets a function that is called at the start of each stage of processing requests, e.g. to start a tracing span.
+//
+// The stages are the signature verification (named `signature.SpanName`) and the decoding of payloads (named DecodeSpanName).
+// The function returned from f is called with the error of the stage when the stage ends.
+// `tracing.SpanStarter` returns such a function for OpenTelemetry.
+func WithSpanStarter(f func(ctx context.Context, name string) func(error)) Option {
+	return optionFunc(func(r *Router) {
+		r.startSpan = f
+	})
+}
+
+// DecodeSpanName is the name given to the function set by WithSpanStarter when the Router decodes payloads.
+const DecodeSpanName = "slack.decode_payload"
+
+// DispatchPolicy determines how the Router calls handlers when more than one handlers are interested in a callback.
+type DispatchPolicy int
+
+const (
+	// FirstMatch makes the Router stop at the first handler that returns something other than NotInterested.
+	FirstMatch DispatchPolicy = iota
+	// AllMatches makes the Router call all handlers registered for the type of a callback.
+	//
+	// Handlers that return NotInterested are ignored. If more than one handlers return errors,
+	// they are aggregated into `*DispatchError`, so that `errors.Is` and `errors.As` can find any of them.
+	// If more than one handlers write responses (e.g. with OnViewSubmission), the last one is used.
+	// The fallback handler is called only if none of the handlers are interested in the callback.
+	AllMatches
+)
+
+// WithDispatchPolicy sets the DispatchPolicy.
+//
+// If this is not set, FirstMatch is used.
+func WithDispatchPolicy(p DispatchPolicy) Option {
+	return optionFunc(func(r *Router) {
+		r.dispatchPolicy = p
+	})
+}
+
+// WithHandlerTimeout sets a deadline to the context passed to handlers, so that slow downstream calls are canceled before Slack gives up.
+//
+// Slack expects responses within 3 seconds, so a value with a margin (e.g. 2.5 seconds) is recommended.
+// If handlers do not return by the deadline, the Router responds with an error wrapping `context.DeadlineExceeded`
+// without waiting for them, and they keep running in the background until they return.
+//
+// In the Async mode, the deadline is set to the context detached from the request, and the Router does not wait for handlers anyway.
+// If this is not set, handlers have no deadline other than the one of the request.
+func WithHandlerTimeout(d time.Duration) Option {
+	return optionFunc(func(r *Router) {
+		r.handlerTimeout = d
+	})
+}
+
+// WarnOnUnhandled makes the Router write a log at the info level when no handler (including the fallback handler) is interested in a callback,
+// with its type and callback ID.
+//
+// This is useful to find misconfigurations, e.g. mistyped predicates or new types of interactions, which are otherwise silently ignored.
+// It does not change the response. To count such callbacks, use an Observer, whose HandlerFinished receives `routererrors.NotInterested` in such case.
+func WarnOnUnhandled() Option {
+	return optionFunc(func(r *Router) {
+		r.warnOnUnhandled = true
+	})
+}
+
+// WithStrictDecoding makes the Router reject payloads that have fields unknown to `slack.InteractionCallback` with Bad Request.
+//
+// This is useful to catch schema drift early during development. Do not use this in production environments,
+// since Slack adds new fields to payloads from time to time.
+// Note that fields decoded by custom decoders of `slack-go/slack` (e.g. `actions` and `blocks`) are not checked.
+func WithStrictDecoding() Option {
+	return optionFunc(func(r *Router) {
+		r.strictDecoding = true
+	})
+}
+
+// WithSuccessResponse sets the status code and the body that the Router responds with when handlers succeed without returning any response.
+//
+// This is useful when Slack renders the response body directly. The body is written as is, so set Content-Type by a middleware if necessary.
+// Responses returned from handlers (e.g. responses to `view_submission` callbacks) take precedence over this.
+// If this is not set, the Router responds with 200 and an empty body.
+func WithSuccessResponse(status int, body []byte) Option {
+	return optionFunc(func(r *Router) {
+		r.successStatus = status
+		r.successBody = body
+	})
+}
+
+// Async makes the Router process interaction callbacks asynchronously.
+//
+// In this mode, the Router responds with 200 immediately after verifying the signature and parsing the payload,
+// and then calls handlers in a background goroutine with a context detached from the request.
+// This is useful when handlers take longer than 3 seconds, which is the limit of Slack.
+//
+// Note that the responses returned from handlers (e.g. responses to `view_submission` callbacks) are ignored in this mode.
+// Errors returned from handlers are passed to the function given to WithErrorHandler, with an http.ResponseWriter that discards everything.
+func Async() Option {
+	return optionFunc(func(r *Router) {
+		r.async = true
+	})
+}
+
+// WithMaxConcurrency limits the number of handlers running concurrently in the Async mode.
+//
+// When the limit is reached, the Router responds to new requests with Service Unavailable.
+// If this is not set, the number is unlimited.
+func WithMaxConcurrency(n int) Option {
+	return optionFunc(func(r *Router) {
+		r.maxConcurrency = n
+	})
+}
+
+// Router is an http.Handler that processes interaction callbacks from Slack.
+//
+// The Router does not depend on the URL path of requests, so it can be mounted at any path,
+// e.g. `mux.Handle("/slack/interactions", r)` or under `http.StripPrefix` behind a reverse proxy.
+//
+// All handlers and Middlewares must be registered before the Router starts serving,
+// i.e. before ServeHTTP or HandleSocketMode is called for the first time. Registering them after that panics.
+//
+// For more details, see https://api.slack.com/interactivity/handling.
+type Router struct {
+	signingSecret         string
+	signingSecrets        []string
+	signingSecretFunc     func() ([]byte, error)
+	signingSecretTTL      time.Duration
+	hasEmptySigningSecret bool
+	skipVerification      bool
+	signatureTolerance    time.Duration
+	clock                 func() time.Time
+	routeTrace            bool
+	handlers              map[slack.InteractionType][]Handler
+	fallbackHandler       Handler
+	verboseResponse       bool
+	errorHandler          func(http.ResponseWriter, *http.Request, error)
+	preVerifyHook         func(*http.Request) error
+	strictDecoding        bool
+	warnOnUnhandled       bool
+	successStatus         int
+	successBody           []byte
+	logger                Logger
+	observer              Observer
+	startSpan             func(ctx context.Context, name string) func(error)
+	dispatchPolicy        DispatchPolicy
+	handlerTimeout        time.Duration
+	async                 bool
+	maxConcurrency        int
+	semaphore             chan struct{}
+	shutdownMu            sync.Mutex
+	shuttingDown          bool
+	inFlight              sync.WaitGroup
+	middlewares           []Middleware
+	skipPanicRecovery     bool
+	client                *slack.Client
+	httpClient            *http.Client
+	maxBodyBytes          int64
+	hasRateLimit          bool
+	rateLimit             rate.Limit
+	rateLimitBurst        int
+	rateLimitKeyFunc      func(*slack.InteractionCallback) string
+	rateLimiter           *ratelimit.Limiter
+	httpHandler           http.Handler
+	registry              routerutils.Registry
+}
+
+// New creates a new Router.
+//
+// At least one of WithSigningSecret() or InsecureSkipVerification() must be specified.
+func New(opts ...Option) (*Router, error) {
+	r := &Router{
+		handlers:      make(map[slack.InteractionType][]Handler),
+		logger:        nopLogger{},
+		observer:      NopObserver{},
+		successStatus: http.StatusOK,
+		maxBodyBytes:  routerutils.DefaultMaxBodyBytes,
+		clock:         time.Now,
+	}
+	r.errorHandler = r.defaultErrorHandler
+	for _, o := range opts {
+		o.apply(r)
+	}
+	if r.httpClient == nil {
+		r.httpClient = defaultHTTPClient
+	}
+	if r.errorHandler == nil {
+		return nil, errors.New("WithErrorHandler must not be nil")
+	}
+	if r.logger == nil {
+		return nil, errors.New("WithLogger must not be nil")
+	}
+	if r.observer == nil {
+		return nil, errors.New("WithObserver must not be nil")
+	}
+	if r.dispatchPolicy != FirstMatch && r.dispatchPolicy != AllMatches {
+		return nil, errors.New("WithDispatchPolicy must be either FirstMatch or AllMatches")
+	}
+	if r.successStatus < 200 || 300 <= r.successStatus {
+		return nil, errors.New("WithSuccessResponse must be given a 2xx status code")
+	}
+	if r.handlerTimeout < 0 {
+		return nil, errors.New("WithHandlerTimeout must not be negative")
+	}
+	if r.maxConcurrency < 0 {
+		return nil, errors.New("WithMaxConcurrency must not be negative")
+	}
+	if r.maxBodyBytes <= 0 {
+		return nil, errors.New("WithMaxBodyBytes must be positive")
+	}
+	if r.clock == nil {
+		return nil, errors.New("WithClock must not be nil")
+	}
+	if r.hasRateLimit {
+		if r.rateLimitKeyFunc == nil {
+			return nil, errors.New("WithRateLimit must be given a key function")
+		}
+		if r.rateLimit <= 0 || r.rateLimitBurst <= 0 {
+			return nil, errors.New("WithRateLimit must be given a positive limit and burst")
+		}
+		r.rateLimiter = ratelimit.New(r.rateLimit, r.rateLimitBurst, maxRateLimitKeys)
+	}
+	if r.maxConcurrency > 0 {
+		r.semaphore = make(chan struct{}, r.maxConcurrency)
+	}
+	if r.hasEmptySigningSecret {
+		return nil, errors.New("WithSigningSecret is given an empty signing secret; make sure that the secret is set correctly")
+	}
+	if r.signingSecretTTL < 0 {
+		return nil, errors.New("WithSigningSecretFunc must not be given a negative TTL")
+	}
+	if r.signatureTolerance < 0 {
+		return nil, errors.New("WithSignatureTolerance must not be negative")
+	}
+	hasSigningSecret := r.signingSecret != "" || len(r.signingSecrets) > 0 || r.signingSecretFunc != nil
+	if !hasSigningSecret && !r.skipVerification {
+		return nil, errors.New("WithSigningSecret must be set, or you can ignore this by setting InsecureSkipVerification")
+	}
+	if hasSigningSecret && r.skipVerification {
+		return nil, errors.New("both WithSigningSecret and InsecureSkipVerification are given")
+	}
+
+	r.httpHandler = http.HandlerFunc(r.serveHTTP)
+	if !r.skipVerification {
+		r.httpHandler = &signature.Middleware{
+			SigningSecret:     r.signingSecret,
+			SigningSecrets:    r.signingSecrets,
+			SigningSecretFunc: signature.CacheSecret(r.signingSecretFunc, r.signingSecretTTL),
+			Tolerance:         r.signatureTolerance,
+			Clock:             r.clock,
+			VerboseResponse:   r.verboseResponse,
+			Handler:           r.httpHandler,
+			OnError: func(req *http.Request, err error) {
+				r.logger.Info("failed to verify request signature", "error", err, "requestID", RequestID(req.Context()))
+				r.observer.SignatureFailed(req, err)
+			},
+			StartSpan: r.startSpan,
+		}
+	}
+	return r, nil
+}
+
+// On registers a handler for a specific event type.
+//
+// All types of interactions share the same struct in `slack-go/slack`, so handlers for any types can be registered with this method.
+// For common cases, there are shorthands such as OnBlockAction and OnShortcut.
+//
+// If more than one handlers are registered, the first ones take precedence.
+//
+// Handlers may return `routererrors.NotInterested` (or its equivalents in the sense of `errors.Is`). In such case the Router falls back to other handlers.
+//
+// Handlers also may return `routererrors.HttpError` (or its equivalents in the sense of `errors.Is`). In such case the Router responds with corresponding HTTP status codes.
+//
+// If any other errors are returned, the Router responds with Internal Server Error.
+func (r *Router) On(typeName slack.InteractionType, h Handler, preds ...Predicate) {
+	h = r.notifyMatched(h)
+	if r.routeTrace {
+		h = r.buildWithTrace(h, preds)
+	} else {
+		h = Build(h, preds...)
+	}
+	r.registry.Register("interactionrouter.Router.On", func() {
+		handlers, ok := r.handlers[typeName]
+		if !ok {
+			handlers = make([]Handler, 0)
+		}
+		handlers = append(handlers, h)
+		r.handlers[typeName] = handlers
+	})
+}
+
+// routeTrace records how many Predicates are considered to be "true".
+type routeTrace struct {
+	passed int
+}
+
+// buildWithTrace works in the same way as Build, except that the returned Handler logs the route trace (see WithRouteTrace).
+func (r *Router) buildWithTrace(h Handler, preds []Predicate) Handler {
+	for _, p := range preds {
+		next := h
+		h = p.Wrap(HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+			if t, ok := ctx.Value(routeTraceKey).(*routeTrace); ok {
+				t.passed++
+			}
+			return next.HandleInteraction(ctx, callback)
+		}))
+	}
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		t := &routeTrace{}
+		err := h.HandleInteraction(context.WithValue(ctx, routeTraceKey, t), callback)
+		r.logger.Info("route trace", "trace", formatRouteTrace(preds, t.passed, err),
+			"type", callback.Type, "callbackID", callback.CallbackID, "requestID", RequestID(ctx))
+		return err
+	})
+}
+
+// formatRouteTrace formats the result of the evaluation of preds.
+// The last Predicate is the outermost one, so Predicates are evaluated in the reverse order.
+func formatRouteTrace(preds []Predicate, passed int, err error) string {
+	result := "OK"
+	if errors.Is(err, routererrors.NotInterested) {
+		result = "NotInterested"
+	} else if err != nil {
+		result = err.Error()
+	}
+	steps := make([]string, 0, len(preds))
+	for i := len(preds) - 1; i >= 0; i-- {
+		if len(steps) < passed {
+			steps = append(steps, describe(preds[i])+"=true")
+			continue
+		}
+		if errors.Is(err, routererrors.NotInterested) {
+			steps = append(steps, describe(preds[i])+"=false")
+		} else {
+			steps = append(steps, describe(preds[i])+"=error")
+		}
+		break
+	}
+	return strings.Join(steps, ", ") + " -> " + result
+}
+
+// OnBlockAction registers a handler that processes `block_actions` callbacks from the block element identified by blockID and actionID.
+//
+// This is equivalent to `On(slack.InteractionTypeBlockActions, h, BlockAction(blockID, actionID), preds...)`.
+func (r *Router) OnBlockAction(blockID, actionID string, h Handler, preds ...Predicate) {
+	preds = append([]Predicate{BlockAction(blockID, actionID)}, preds...)
+	r.On(slack.InteractionTypeBlockActions, h, preds...)
+}
+
+// OnShortcut registers a handler that processes global shortcuts identified by callbackID.
+//
+// This is equivalent to `On(slack.InteractionTypeShortcut, h, CallbackID(callbackID), preds...)`.
+func (r *Router) OnShortcut(callbackID string, h Handler, preds ...Predicate) {
+	preds = append([]Predicate{CallbackID(callbackID)}, preds...)
+	r.On(slack.InteractionTypeShortcut, h, preds...)
+}
+
+// OnMessageAction registers a handler that processes message shortcuts identified by callbackID.
+//
+// This is equivalent to `On(slack.InteractionTypeMessageAction, h, CallbackID(callbackID), preds...)`.
+func (r *Router) OnMessageAction(callbackID string, h Handler, preds ...Predicate) {
+	preds = append([]Predicate{CallbackID(callbackID)}, preds...)
+	r.On(slack.InteractionTypeMessageAction, h, preds...)
+}
+
+// OnViewSubmission registers a handler that processes `view_submission` callbacks from the view identified by callbackID and responds to them.
+//
+// This works in the same way as `On(slack.InteractionTypeViewSubmission, ..., ViewCallbackID(callbackID), preds...)` except that the response returned from `h` is written to the response body.
+func (r *Router) OnViewSubmission(callbackID string, h ViewSubmissionHandler, preds ...Predicate) {
+	preds = append([]Predicate{ViewCallbackID(callbackID)}, preds...)
+	r.On(slack.InteractionTypeViewSubmission, HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		resp, err := h.HandleViewSubmission(ctx, callback)
+		if err != nil {
+			return err
+		}
+		if resp != nil {
+			setResponse(ctx, resp)
+		}
+		return nil
+	}), preds...)
+}
+
+// OnBlockSuggestion registers a handler that processes `block_suggestion` callbacks from the external select menu identified by blockID and actionID.
+//
+// The options returned from `h` are written to the response body.
+func (r *Router) OnBlockSuggestion(blockID, actionID string, h BlockSuggestionHandler, preds ...Predicate) {
+	preds = append([]Predicate{&blockSuggestionPredicate{blockID: blockID, actionID: actionID}}, preds...)
+	r.On(slack.InteractionTypeBlockSuggestion, HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		resp, err := h.HandleBlockSuggestion(ctx, callback)
+		if err != nil {
+			return err
+		}
+		setResponse(ctx, resp.body())
+		return nil
+	}), preds...)
+}
+
+// OnDialogSubmission registers a handler that processes `dialog_submission` callbacks from the dialog identified by callbackID.
+//
+// The validation errors returned from `h` are written to the response body.
+func (r *Router) OnDialogSubmission(callbackID string, h DialogSubmissionHandler, preds ...Predicate) {
+	preds = append([]Predicate{CallbackID(callbackID)}, preds...)
+	r.On(slack.InteractionTypeDialogSubmission, HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		resp, err := h.HandleDialogSubmission(ctx, callback)
+		if err != nil {
+			return err
+		}
+		if resp != nil && len(resp.Errors) > 0 {
+			setResponse(ctx, resp)
+		}
+		return nil
+	}), preds...)
+}
+
+type blockSuggestionPredicate struct {
+	blockID  string
+	actionID string
+}
+
+func (p *blockSuggestionPredicate) Describe() string {
+	return fmt.Sprintf("BlockSuggestion(%s,%s)", p.blockID, p.actionID)
+}
+
+func (p *blockSuggestionPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		if callback.BlockID != p.blockID || callback.ActionID != p.actionID {
+			return routererrors.NotInterested
+		}
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+// Use adds a Middleware to the Router.
+//
+// Middlewares are applied to every coming callback after the signature is verified and the payload is parsed.
+// Each Middleware wraps the entire dispatch process, that is, evaluation of predicates and calls to handlers (including the fallback handler).
+// So they are called exactly once per callback, even if no handler is interested in it, in which case they receive `routererrors.NotInterested`.
+//
+// Middlewares are executed in the order they are added, i.e. the first one is the outermost.
+func (r *Router) Use(mw Middleware) {
+	r.registry.Register("interactionrouter.Router.Use", func() {
+		r.middlewares = append(r.middlewares, mw)
+	})
+}
+
+// SetFallback sets a fallback handler that is called when none of the registered handlers matches to a coming event.
+//
+// That is, the fallback handler is called only when no handler is registered for the event or all of them returned `routererrors.NotInterested`
+// (e.g. because their Predicates are "false"). It is never called after a handler processed the event, whether it succeeded or not.
+//
+// If more than one handlers are registered, the last one will be used.
+func (r *Router) SetFallback(h Handler) {
+	r.registry.Register("interactionrouter.Router.SetFallback", func() {
+		r.fallbackHandler = h
+	})
+}
+
+// ServeHTTP processes an interaction callback.
+//
+// It attaches a request ID to each request, which is taken from the X-Request-ID header if present or generated otherwise.
+// The ID is set to the X-Request-ID header of the response, included in all log lines, and available to handlers via RequestID.
+//
+// Handlers can no longer be registered once ServeHTTP is called.
+func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	router.registry.Freeze()
+	requestID := routerutils.RequestID(req)
+	w.Header().Set(routerutils.HeaderRequestID, requestID)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey, requestID))
+	router.observer.RequestReceived(req)
+	if router.preVerifyHook != nil {
+		if err := router.preVerifyHook(req); err != nil {
+			router.logger.Info("request is rejected by the pre-verify hook", "error", err, "requestID", requestID)
+			var httpErr routererrors.HttpError
+			if !errors.As(err, &httpErr) {
+				err = errors.WithMessage(routererrors.ErrForbidden, err.Error())
+			}
+			routerutils.RespondWithError(w, err, router.verboseResponse)
+			return
+		}
+	}
+	routerutils.LimitBody(req, router.maxBodyBytes)
+	defer routerutils.ReleaseBody(req)
+	router.httpHandler.ServeHTTP(w, req)
+}
+
+func (router *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	var endSpan func(error)
+	if router.startSpan != nil {
+		endSpan = router.startSpan(req.Context(), DecodeSpanName)
+	}
+	callback, body, err := router.decode(req)
+	if endSpan != nil {
+		endSpan(err)
+	}
+	if err != nil {
+		router.respondWithError(w, req, err)
+		return
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), rawBodyKey, body))
+	router.observer.PayloadParsed(req.Context(), callback)
+	router.handleInteractionCallback(w, req, callback)
+}
+
+// decode reads the callback from the request. It also returns the raw body, which is needed by RawBody.
+func (router *Router) decode(req *http.Request) (*slack.InteractionCallback, []byte, error) {
+	if !routerutils.IsForm(req) {
+		router.logger.Info("unexpected Content-Type", "contentType", req.Header.Get("Content-Type"), "requestID", RequestID(req.Context()))
+		return nil, nil, errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), "unexpected Content-Type")
+	}
+	body, err := routerutils.ReadBody(req)
+	if err != nil {
+		router.logger.Info("failed to read body", "error", err, "requestID", RequestID(req.Context()))
+		return nil, nil, err
+	}
+	// Parse the body by ourselves rather than using req.FormValue, which reads the body again.
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		router.logger.Info("failed to parse form", "error", err, "requestID", RequestID(req.Context()))
+		return nil, nil, errors.WithMessage(routererrors.HttpError(http.StatusBadRequest), err.Error())
+	}
+	payload := form.Get("payload")
+	if payload == "" {
+		router.logger.Info("missing payload", "requestID", RequestID(req.Context()))
+		return nil, nil, &PayloadError{Reason: ErrMissingPayload}
+	}
+	if router.strictDecoding {
+		if err := checkUnknownFields([]byte(payload)); err != nil {
+			router.logger.Info("payload has unknown fields", "error", err, "requestID", RequestID(req.Context()))
+			return nil, nil, &PayloadError{Reason: ErrInvalidPayload, Err: err}
+		}
+	}
+	callback := &slack.InteractionCallback{}
+	if err := json.Unmarshal([]byte(payload), callback); err != nil {
+		router.logger.Info("failed to parse payload", "error", err, "requestID", RequestID(req.Context()))
+		return nil, nil, &PayloadError{Reason: ErrInvalidPayload, Err: err}
+	}
+	return callback, body, nil
+}
+
+// checkRateLimit returns an error if the callback exceeds the limit set by WithRateLimit.
+func (r *Router) checkRateLimit(ctx context.Context, callback *slack.InteractionCallback) error {
+	if r.rateLimiter == nil {
+		return nil
+	}
+	key := r.rateLimitKeyFunc(callback)
+	if key == "" || r.rateLimiter.Allow(key) {
+		return nil
+	}
+	r.logger.Info("rate limit exceeded", "key", key, "requestID", RequestID(ctx))
+	return errors.WithMessage(routererrors.HttpError(http.StatusTooManyRequests), "rate limit exceeded")
+}
+
+// Reasons of PayloadError.
+var (
+	ErrMissingPayload = errors.New("missing payload")
+	ErrInvalidPayload = errors.New("invalid payload JSON")
+)
+
+// PayloadError is the error that the Router fails with when a request does not have a valid `payload` field.
+// The Router responds to such requests with Bad Request, or passes the error to the function given to WithErrorHandler.
+//
+// It is equivalent to both its Reason and `routererrors.HttpError(http.StatusBadRequest)` in the sense of `errors.Is`,
+// so the reason can be checked with e.g. `errors.Is(err, ErrInvalidPayload)`.
+type PayloadError struct {
+	// Reason is either ErrMissingPayload or ErrInvalidPayload.
+	Reason error
+
+	// Err is the error returned from the JSON decoder, if any.
+	Err error
+}
+
+func (e *PayloadError) Error() string {
+	if e.Err == nil {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.Err.Error()
+}
+
+func (e *PayloadError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PayloadError) Is(target error) bool {
+	return target == e.Reason || target == routererrors.HttpError(http.StatusBadRequest)
+}
+
+func (e *PayloadError) As(target interface{}) bool {
+	if httpErr, ok := target.(*routererrors.HttpError); ok {
+		*httpErr = routererrors.HttpError(http.StatusBadRequest)
+		return true
+	}
+	return false
+}
+
+// callbackFields has the same fields as slack.InteractionCallback but not its UnmarshalJSON,
+// which would otherwise decode the payload with its own lenient decoder.
+type callbackFields slack.InteractionCallback
+
+// checkUnknownFields returns an error if the payload has fields that slack.InteractionCallback does not know.
+func checkUnknownFields(payload []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	var fields callbackFields
+	return dec.Decode(&fields)
+}
+
+func (r *Router) handleInteractionCallback(w http.ResponseWriter, req *http.Request, callback *slack.InteractionCallback) {
+	if err := r.checkRateLimit(req.Context(), callback); err != nil {
+		r.respondWithError(w, req, err)
+		return
+	}
+	if r.async {
+		r.handleInteractionCallbackAsync(w, req, callback)
+		return
+	}
+
+	res, err := r.dispatchWithTimeout(req.Context(), callback)
+	if err != nil {
+		r.respondWithError(w, req, err)
+		return
+	}
+	if res.body == nil {
+		r.respondWithSuccess(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(res.body)
+}
+
+func (r *Router) respondWithSuccess(w http.ResponseWriter) {
+	w.WriteHeader(r.successStatus)
+	if len(r.successBody) > 0 {
+		_, _ = w.Write(r.successBody)
+	}
+}
+
+func (r *Router) handleInteractionCallbackAsync(w http.ResponseWriter, req *http.Request, callback *slack.InteractionCallback) {
+	r.shutdownMu.Lock()
+	if r.shuttingDown {
+		r.shutdownMu.Unlock()
+		r.respondWithError(w, req, errors.WithMessage(routererrors.ErrServiceUnavailable, "the router is shutting down"))
+		return
+	}
+	if r.semaphore != nil {
+		select {
+		case r.semaphore <- struct{}{}:
+		default:
+			r.shutdownMu.Unlock()
+			r.respondWithError(w, req, errors.WithMessage(routererrors.ErrServiceUnavailable, "too many running handlers"))
+			return
+		}
+	}
+	r.inFlight.Add(1)
+	r.shutdownMu.Unlock()
+
+	// The request and its context must not be used after ServeHTTP returns, so we need a detached copy of them.
+	ctx := context.WithValue(context.Background(), rawBodyKey, append([]byte(nil), RawBody(req.Context())...))
+	ctx = context.WithValue(ctx, requestIDKey, RequestID(req.Context()))
+	detachedReq := req.Clone(ctx)
+	detachedReq.Body = http.NoBody
+	r.respondWithSuccess(w)
+	go func() {
+		defer r.inFlight.Done()
+		defer func() {
+			if r.semaphore != nil {
+				<-r.semaphore
+			}
+		}()
+		ctx := ctx
+		if r.handlerTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.handlerTimeout)
+			defer cancel()
+		}
+		_, err := r.dispatch(ctx, callback)
+		if err != nil {
+			r.errorHandler(discardResponseWriter{}, detachedReq, err)
+		}
+	}()
+}
+
+// Shutdown makes the Router stop accepting new callbacks in the Async mode, and waits for running handlers to finish.
+//
+// After Shutdown is called, the Router responds to new callbacks with Service Unavailable in the Async mode.
+// If ctx is done before all handlers finish, Shutdown returns the error of ctx (e.g. `context.DeadlineExceeded`).
+// In the synchronous mode, `http.Server.Shutdown` waits for handlers instead, so Shutdown only waits for handlers
+// that are still running after WithHandlerTimeout is exceeded. If WithHandlerTimeout is set, new callbacks are
+// rejected after Shutdown is called in the synchronous mode as well, and HandleSocketMode returns an error for them.
+//
+// This is intended to be called after `http.Server.Shutdown`.
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.shutdownMu.Lock()
+	r.shuttingDown = true
+	r.shutdownMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SocketModeAcker acknowledges requests received via Socket Mode. `*socketmode.Client` implements this.
+type SocketModeAcker interface {
+	Ack(req socketmode.Request, payload ...interface{})
+}
+
+// HandleSocketMode processes an interactive event received via Socket Mode in the same way as ServeHTTP,
+// and acknowledges it with the response returned from handlers (e.g. ViewSubmissionHandler), if any.
+//
+// Signature verification is skipped because the Socket Mode connection is already authenticated.
+// The envelope ID of the request is used as the request ID (see RequestID).
+// Handlers are always called synchronously, even in the Async mode.
+//
+// It returns `routererrors.NotInterested` if the event is not an interactive one, so that you can process it by yourself.
+// If handlers return errors, the event is not acknowledged and the error is returned.
+//
+//	for evt := range client.Events {
+//		if err := r.HandleSocketMode(ctx, client, evt); err != nil {
+//			// handle other events or errors
+//		}
+//	}
+//
+// For more details, see https://api.slack.com/apis/connections/socket.
+func (r *Router) HandleSocketMode(ctx context.Context, acker SocketModeAcker, evt socketmode.Event) error {
+	r.registry.Freeze()
+	if evt.Type != socketmode.EventTypeInteractive {
+		return routererrors.NotInterested
+	}
+	callback, ok := evt.Data.(slack.InteractionCallback)
+	if !ok {
+		return errors.Errorf("expected slack.InteractionCallback but got %T", evt.Data)
+	}
+	if evt.Request == nil {
+		return errors.New("the event has no request to acknowledge")
+	}
+	ctx = context.WithValue(ctx, requestIDKey, evt.Request.EnvelopeID)
+	r.observer.PayloadParsed(ctx, &callback)
+	if err := r.checkRateLimit(ctx, &callback); err != nil {
+		return err
+	}
+	res, err := r.dispatchWithTimeout(ctx, &callback)
+	if err != nil {
+		return err
+	}
+	if res.body == nil {
+		acker.Ack(*evt.Request)
+	} else {
+		acker.Ack(*evt.Request, res.body)
+	}
+	return nil
+}
+
+// dispatchWithTimeout calls dispatch with the deadline set by WithHandlerTimeout, if any.
+// It returns an error as soon as the deadline is exceeded, even if handlers are still running.
+func (r *Router) dispatchWithTimeout(ctx context.Context, callback *slack.InteractionCallback) (*response, error) {
+	if r.handlerTimeout <= 0 {
+		return r.dispatch(ctx, callback)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.handlerTimeout)
+	defer cancel()
+	// The body is released as soon as ServeHTTP returns, which may be earlier than handlers return.
+	ctx = context.WithValue(ctx, rawBodyKey, append([]byte(nil), RawBody(ctx)...))
+
+	type result struct {
+		res *response
+		err error
+	}
+	done := make(chan result, 1)
+	// Handlers keep running after they time out, so Shutdown has to wait for them.
+	r.shutdownMu.Lock()
+	if r.shuttingDown {
+		r.shutdownMu.Unlock()
+		return nil, errors.WithMessage(routererrors.ErrServiceUnavailable, "the router is shutting down")
+	}
+	r.inFlight.Add(1)
+	r.shutdownMu.Unlock()
+	go func() {
+		defer r.inFlight.Done()
+		res, err := r.dispatch(ctx, callback)
+		done <- result{res: res, err: err}
+	}()
+	select {
+	case rs := <-done:
+		return rs.res, rs.err
+	case <-ctx.Done():
+		r.logger.Error("handler timed out",
+			"type", callback.Type, "callbackID", callback.CallbackID, "triggerID", callback.TriggerID, "requestID", RequestID(ctx))
+		return nil, errors.WithMessage(ctx.Err(), "handler timed out")
+	}
+}
+
+// dispatch calls handlers that are interested in the callback.
+// It returns a response that handlers want to write, or an error other than NotInterested.
+func (r *Router) dispatch(ctx context.Context, callback *slack.InteractionCallback) (*response, error) {
+	res := &response{}
+	ctx = context.WithValue(ctx, responseKey, res)
+	ctx = context.WithValue(ctx, callbackKey, callback)
+	ctx = context.WithValue(ctx, clockKey, r.clock)
+	ctx = context.WithValue(ctx, httpClientKey, r.httpClient)
+	if r.client != nil {
+		ctx = context.WithValue(ctx, clientKey, &CallbackClient{client: r.client, callback: callback})
+	}
+	var h Handler = HandlerFunc(r.callHandlers)
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	if !r.skipPanicRecovery {
+		h = Recover()(h)
+	}
+	start := time.Now()
+	err := h.HandleInteraction(ctx, callback)
+	r.observer.HandlerFinished(ctx, callback, time.Since(start), err)
+	if r.warnOnUnhandled && errors.Is(err, routererrors.NotInterested) {
+		r.logger.Info("no handler is interested in the callback",
+			"type", callback.Type, "callbackID", callback.CallbackID, "requestID", RequestID(ctx))
+	}
+	if err != nil && !errors.Is(err, routererrors.NotInterested) {
+		r.logger.Error("handler returned an error",
+			"error", err, "type", callback.Type, "callbackID", callback.CallbackID, "triggerID", callback.TriggerID, "requestID", RequestID(ctx))
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Router) callHandlers(ctx context.Context, callback *slack.InteractionCallback) error {
+	var err error = routererrors.NotInterested
+	handlers, ok := r.handlers[callback.Type]
+	if ok {
+		if r.dispatchPolicy == AllMatches {
+			err = callAllHandlers(ctx, handlers, callback)
+		} else {
+			err = callFirstHandler(ctx, handlers, callback)
+		}
+	}
+
+	if errors.Is(err, routererrors.NotInterested) {
+		err = r.handleFallback(ctx, callback)
+	}
+	return err
+}
+
+// notifyMatched returns a Handler that notifies the Observer before calling h.
+func (r *Router) notifyMatched(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+		r.observer.HandlerMatched(ctx, callback)
+		return h.HandleInteraction(ctx, callback)
+	})
+}
+
+func callFirstHandler(ctx context.Context, handlers []Handler, callback *slack.InteractionCallback) error {
+	var err error = routererrors.NotInterested
+	for _, h := range handlers {
+		err = h.HandleInteraction(ctx, callback)
+		if !errors.Is(err, routererrors.NotInterested) {
+			break
+		}
+	}
+	return err
+}
+
+func callAllHandlers(ctx context.Context, handlers []Handler, callback *slack.InteractionCallback) error {
+	interested := false
+	var errs []HandlerError
+	for i, h := range handlers {
+		err := h.HandleInteraction(ctx, callback)
+		if errors.Is(err, routererrors.NotInterested) {
+			continue
+		}
+		interested = true
+		if err != nil {
+			errs = append(errs, HandlerError{Index: i, Err: err})
+		}
+	}
+	switch {
+	case !interested:
+		return routererrors.NotInterested
+	case len(errs) == 0:
+		return nil
+	case len(errs) == 1:
+		return errs[0].Err
+	default:
+		return &DispatchError{Type: callback.Type, Errors: errs}
+	}
+}
+
+// HandlerError is an error returned from one of the handlers called under AllMatches.
+type HandlerError struct {
+	// Index is the index of the handler among the ones registered for Type, in the order of registration.
+	Index int
+
+	// Err is the error returned from the handler.
+	Err error
+}
+
+// DispatchError is the error that the Router fails with when more than one handlers return errors under AllMatches.
+//
+// `errors.Is` and `errors.As` on DispatchError check the error of each handler in order,
+// so `routererrors.HTTPStatus` returns the status code corresponding to the first HttpError in it.
+// Use `errors.As` with `**DispatchError` to find out which handler returned which error.
+type DispatchError struct {
+	// Type is the type of the callback that the handlers are registered for.
+	Type slack.InteractionType
+
+	// Errors are the errors returned from the handlers, in the order of registration.
+	Errors []HandlerError
+}
+
+func (e *DispatchError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, he := range e.Errors {
+		msgs = append(msgs, he.Err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *DispatchError) Is(target error) bool {
+	for _, he := range e.Errors {
+		if errors.Is(he.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *DispatchError) As(target interface{}) bool {
+	for _, he := range e.Errors {
+		if errors.As(he.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoResponseURL is returned from RespondWith when the InteractionCallback being processed has no response URL.
+var ErrNoResponseURL = errors.New("the callback has no response_url")
+
+// ResponseURL returns the response URL of the InteractionCallback being processed.
+//
+// It returns an empty string if the context is not passed from the Router or the callback has no response URL.
+func ResponseURL(ctx context.Context) string {
+	callback, ok := ctx.Value(callbackKey).(*slack.InteractionCallback)
+	if !ok {
+		return ""
+	}
+	return callback.ResponseURL
+}
+
+// RespondWith sends a message to the response URL of the InteractionCallback being processed.
+//
+// If replaceOriginal is true, the message replaces the one the callback came from. Otherwise it is posted as a new message.
+// Other fields like `response_type` and `delete_original` are sent as they are set in msg.
+//
+// The message is sent with the client given to WithHTTPClient, and the request is canceled when ctx is done.
+//
+// Note that Slack accepts requests to a response URL only up to 5 times within 30 minutes after the callback is sent.
+//
+// For more details, see https://api.slack.com/interactivity/handling#message_responses.
+func RespondWith(ctx context.Context, msg slack.Message, replaceOriginal bool) error {
+	responseURL := ResponseURL(ctx)
+	if responseURL == "" {
+		return ErrNoResponseURL
+	}
+	msg.ReplaceOriginal = replaceOriginal
+	body, err := json.Marshal(&msg)
+	if err != nil {
+		return errors.WithMessage(err, "failed to encode the message")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client, ok := ctx.Value(httpClientKey).(*http.Client)
+	if !ok {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("response_url responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// ErrNoClient is returned from methods of CallbackClient when WithClient is not given.
+var ErrNoClient = errors.New("no slack.Client is given to the Router")
+
+// CallbackClient is a wrapper of slack.Client that fills in parameters taken from the InteractionCallback being processed.
+//
+// Methods of CallbackClient are nil-safe. They return ErrNoClient if the receiver is nil.
+type CallbackClient struct {
+	client   *slack.Client
+	callback *slack.InteractionCallback
+}
+
+// Client returns a CallbackClient for the InteractionCallback being processed.
+//
+// It returns nil if WithClient is not given or the context is not passed from the Router.
+func Client(ctx context.Context) *CallbackClient {
+	c, _ := ctx.Value(clientKey).(*CallbackClient)
+	return c
+}
+
+// OpenView opens a modal view with the trigger ID of the callback.
+func (c *CallbackClient) OpenView(ctx context.Context, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	if c == nil {
+		return nil, ErrNoClient
+	}
+	return c.client.OpenViewContext(ctx, c.callback.TriggerID, view)
+}
+
+// PushView pushes a modal view onto the stack of the current modal with the trigger ID of the callback.
+func (c *CallbackClient) PushView(ctx context.Context, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	if c == nil {
+		return nil, ErrNoClient
+	}
+	return c.client.PushViewContext(ctx, c.callback.TriggerID, view)
+}
+
+// UpdateView updates the view the callback came from.
+//
+// The hash of the view is also sent, so the update fails if the view has been updated since the callback was sent.
+func (c *CallbackClient) UpdateView(ctx context.Context, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	if c == nil {
+		return nil, ErrNoClient
+	}
+	return c.client.UpdateViewContext(ctx, view, "", c.callback.View.Hash, c.callback.View.ID)
+}
+
+// PostEphemeral posts an ephemeral message to the user who triggered the callback in the channel the callback came from.
+func (c *CallbackClient) PostEphemeral(ctx context.Context, options ...slack.MsgOption) (string, error) {
+	if c == nil {
+		return "", ErrNoClient
+	}
+	return c.client.PostEphemeralContext(ctx, c.callback.Channel.ID, c.callback.User.ID, options...)
+}
+
+// discardResponseWriter is an http.ResponseWriter that discards everything written to it.
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header         { return http.Header{} }
+func (discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (discardResponseWriter) WriteHeader(_ int)           {}
+
+type contextKey int
+
+const (
+	responseKey contextKey = iota
+	callbackKey
+	clientKey
+	rawBodyKey
+	matchedActionKey
+	requestIDKey
+	clockKey
+	routeTraceKey
+	httpClientKey
+)
+
+// RawBody returns the raw body of the request being processed, which is useful if you want to parse it by yourself.
+//
+// The returned bytes are shared with the Router and reused after the request is processed,
+// so they must not be modified nor retained after the handler returns. Copy them if you need to do so.
+func RawBody(ctx context.Context) []byte {
+	body, _ := ctx.Value(rawBodyKey).([]byte)
+	return body
+}
+
+// Now returns the current time according to the clock set by WithClock.
+//
+// It returns time.Now() if the context is not passed from the Router.
+func Now(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockKey).(func() time.Time); ok {
+		return clock()
+	}
+	return time.Now()
+}
+
+// RequestID returns the ID of the request being processed, which is useful to correlate logs.
+//
+// It returns an empty string if the context is not passed from the Router.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// MatchedAction returns the BlockAction that matched to the predicate (BlockAction, ActionID, BlockID, or ActionValue) wrapping the handler being processed.
+//
+// This is useful when a callback has more than one block actions, since each handler receives the one that its own predicate matched to.
+// If more than one such predicates wrap the handler, the one given first (e.g. to On) wins.
+// It returns nil if the handler is not wrapped with such predicates.
+func MatchedAction(ctx context.Context) *slack.BlockAction {
+	ba, _ := ctx.Value(matchedActionKey).(*slack.BlockAction)
+	return ba
+}
+
+// TriggerID returns the trigger ID of the InteractionCallback being processed, which can be used to open modals.
+//
+// It returns an empty string if the context is not passed from the Router or the callback has no trigger ID.
+func TriggerID(ctx context.Context) string {
+	callback, ok := ctx.Value(callbackKey).(*slack.InteractionCallback)
+	if !ok {
+		return ""
+	}
+	return callback.TriggerID
+}
+
+// IdempotencyKey returns a key that identifies the user action that caused the callback, which is useful to make handlers idempotent
+// by storing the keys of processed callbacks and skipping the ones already seen.
+//
+// The key consists of the team, the user, the trigger ID, and the time of the action (the `action_ts` of the callback or of its first block action),
+// so callbacks caused by the same action always have the same key, even if they are delivered more than once.
+func IdempotencyKey(callback *slack.InteractionCallback) string {
+	return strings.Join([]string{"interaction", callback.Team.ID, callback.User.ID, callback.TriggerID, actionTs(callback)}, ":")
+}
+
+// actionTs returns the `action_ts` of the callback or of its first block action.
+func actionTs(callback *slack.InteractionCallback) string {
+	if callback.ActionTs == "" && len(callback.ActionCallback.BlockActions) > 0 {
+		return callback.ActionCallback.BlockActions[0].ActionTs
+	}
+	return callback.ActionTs
+}
+
+// response holds a response body that handlers want to write.
+type response struct {
+	body interface{}
+}
+
+func setResponse(ctx context.Context, body interface{}) {
+	if res, ok := ctx.Value(responseKey).(*response); ok {
+		res.body = body
+	}
+}
+
+func (r *Router) handleFallback(ctx context.Context, callback *slack.InteractionCallback) error {
+	if r.fallbackHandler == nil {
+		return routererrors.NotInterested
+	}
+	return r.notifyMatched(r.fallbackHandler).HandleInteraction(ctx, callback)
+}
+
+func (r *Router) respondWithError(w http.ResponseWriter, req *http.Request, err error) {
+	r.errorHandler(w, req, err)
+}
+
+func (r *Router) defaultErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
 	routerutils.RespondWithError(w, err, r.verboseResponse)
 }
 
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
 // FindBlockAction finds a block action whose blockID and actionID equal to the given ones.
 // If no such block action is found, it returns nil.
 func FindBlockAction(callback *slack.InteractionCallback, blockID, actionID string) *slack.BlockAction {
@@ -286,3 +2272,88 @@ func FindBlockAction(callback *slack.InteractionCallback, blockID, actionID stri
 	}
 	return nil
 }
+
+// ViewStateValues provides typed access to the values of input blocks submitted with a view (i.e. `View.State.Values`).
+//
+// All methods return zero values (empty strings or nil) rather than panicking if no value is found for the given blockID and actionID.
+type ViewStateValues struct {
+	values map[string]map[string]slack.BlockAction
+}
+
+// ViewState returns the values of input blocks in the view of the given callback, typically a `view_submission` callback.
+//
+// It never returns nil. If the callback has no view state, all methods of the returned value return zero values.
+func ViewState(callback *slack.InteractionCallback) *ViewStateValues {
+	if callback == nil || callback.View.State == nil {
+		return &ViewStateValues{}
+	}
+	return &ViewStateValues{values: callback.View.State.Values}
+}
+
+// Action returns the raw value of the input element identified by blockID and actionID.
+// The second return value reports whether such an element is found.
+func (s *ViewStateValues) Action(blockID, actionID string) (slack.BlockAction, bool) {
+	action, ok := s.values[blockID][actionID]
+	return action, ok
+}
+
+// PlainText returns the value of the plain-text input element.
+func (s *ViewStateValues) PlainText(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.Value
+}
+
+// SelectedOption returns the value of the selected option of the static select menu, the external select menu, or the radio buttons.
+func (s *ViewStateValues) SelectedOption(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedOption.Value
+}
+
+// SelectedOptions returns the values of the selected options of the multi-select menu or the checkboxes.
+func (s *ViewStateValues) SelectedOptions(blockID, actionID string) []string {
+	action, _ := s.Action(blockID, actionID)
+	if len(action.SelectedOptions) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(action.SelectedOptions))
+	for _, o := range action.SelectedOptions {
+		values = append(values, o.Value)
+	}
+	return values
+}
+
+// SelectedDate returns the date selected with the date picker, in the form of `YYYY-MM-DD`.
+func (s *ViewStateValues) SelectedDate(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedDate
+}
+
+// SelectedTime returns the time selected with the time picker, in the form of `HH:mm`.
+func (s *ViewStateValues) SelectedTime(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedTime
+}
+
+// SelectedUser returns the ID of the user selected with the user select menu.
+func (s *ViewStateValues) SelectedUser(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedUser
+}
+
+// SelectedUsers returns the IDs of the users selected with the multi-user select menu.
+func (s *ViewStateValues) SelectedUsers(blockID, actionID string) []string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedUsers
+}
+
+// SelectedChannel returns the ID of the channel selected with the channel select menu.
+func (s *ViewStateValues) SelectedChannel(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedChannel
+}
+
+// SelectedConversation returns the ID of the conversation selected with the conversation select menu.
+func (s *ViewStateValues) SelectedConversation(blockID, actionID string) string {
+	action, _ := s.Action(blockID, actionID)
+	return action.SelectedConversation
+}
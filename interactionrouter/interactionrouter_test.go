@@ -4,16 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+	"golang.org/x/time/rate"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
 	ir "github.com/genkami/go-slack-event-router/interactionrouter"
@@ -176,6 +183,309 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
+	Describe("ActionID", func() {
+		var (
+			numHandlerCalled int
+			matched          *slack.BlockAction
+			innerHandler     = ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				matched = ir.MatchedAction(ctx)
+				return nil
+			})
+			ctx      context.Context
+			callback *slack.InteractionCallback
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			matched = nil
+			ctx = context.Background()
+			callback = &slack.InteractionCallback{
+				Type: slack.InteractionTypeBlockActions,
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{
+						{BlockID: "BLOCK_A", ActionID: "approve"},
+						{BlockID: "BLOCK_B", ActionID: "reject"},
+					},
+				},
+			}
+		})
+
+		Context("when one of the block actions has one of the given action IDs", func() {
+			It("calls the inner handler with the matched block action", func() {
+				h := ir.ActionID("reject", "cancel").Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(matched.BlockID).To(Equal("BLOCK_B"))
+				Expect(matched.ActionID).To(Equal("reject"))
+			})
+		})
+
+		Context("when none of the block actions has the given action IDs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionID("cancel").Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no action ID is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.ActionID() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("BlockID", func() {
+		var (
+			numHandlerCalled int
+			matched          *slack.BlockAction
+			innerHandler     = ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				matched = ir.MatchedAction(ctx)
+				return nil
+			})
+			ctx      context.Context
+			callback *slack.InteractionCallback
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			matched = nil
+			ctx = context.Background()
+			callback = &slack.InteractionCallback{
+				Type: slack.InteractionTypeBlockActions,
+				ActionCallback: slack.ActionCallbacks{
+					BlockActions: []*slack.BlockAction{
+						{BlockID: "BLOCK_A", ActionID: "approve"},
+						{BlockID: "BLOCK_B", ActionID: "reject"},
+					},
+				},
+			}
+		})
+
+		Context("when one of the block actions has one of the given block IDs", func() {
+			It("calls the inner handler with the matched block action", func() {
+				h := ir.BlockID("BLOCK_B", "BLOCK_C").Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(matched.BlockID).To(Equal("BLOCK_B"))
+				Expect(matched.ActionID).To(Equal("reject"))
+			})
+		})
+
+		Context("when none of the block actions has the given block IDs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.BlockID("BLOCK_C").Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no block ID is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.BlockID() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ActionValue", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the value of the button equals to the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := ir.ActionValue("BLOCK_ID", "ACTION_ID", "approve").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{
+							{BlockID: "BLOCK_ID", ActionID: "ACTION_ID", Type: "button", Value: "approve"},
+						},
+					},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the value of the button differs from the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionValue("BLOCK_ID", "ACTION_ID", "approve").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{
+							{BlockID: "BLOCK_ID", ActionID: "ACTION_ID", Type: "button", Value: "reject"},
+						},
+					},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the value of the selected option equals to the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := ir.ActionValue("BLOCK_ID", "ACTION_ID", "high").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{
+							{
+								BlockID:        "BLOCK_ID",
+								ActionID:       "ACTION_ID",
+								Type:           "static_select",
+								SelectedOption: slack.OptionBlockObject{Value: "high"},
+							},
+						},
+					},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the value of the selected option differs from the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionValue("BLOCK_ID", "ACTION_ID", "high").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{
+							{
+								BlockID:        "BLOCK_ID",
+								ActionID:       "ACTION_ID",
+								Type:           "static_select",
+								SelectedOption: slack.OptionBlockObject{Value: "low"},
+							},
+						},
+					},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the value matches but the action does not", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionValue("BLOCK_ID", "ACTION_ID", "approve").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{
+							{BlockID: "BLOCK_ID", ActionID: "ANOTHER_ACTION_ID", Type: "button", Value: "approve"},
+						},
+					},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("MatchedAction", func() {
+		var (
+			approve = &slack.BlockAction{BlockID: "BLOCK_ID", ActionID: "approve", Value: "yes"}
+			reject  = &slack.BlockAction{BlockID: "BLOCK_ID", ActionID: "reject", Value: "no"}
+			payload = `{
+				"type": "block_actions",
+				"actions": [
+					{"block_id": "BLOCK_ID", "action_id": "approve", "value": "yes"},
+					{"block_id": "BLOCK_ID", "action_id": "reject", "value": "no"}
+				]
+			}`
+		)
+
+		Context("when the handler is wrapped with BlockAction", func() {
+			It("returns the matched block action", func() {
+				var matched *slack.BlockAction
+				h := ir.BlockAction("BLOCK_ID", "reject").Wrap(ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					matched = ir.MatchedAction(ctx)
+					return nil
+				}))
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{approve, reject},
+					},
+				}
+				err := h.HandleInteraction(context.Background(), callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(matched).To(BeIdenticalTo(reject))
+			})
+		})
+
+		Context("when the handler is wrapped with ActionValue", func() {
+			It("returns the matched block action", func() {
+				var matched *slack.BlockAction
+				h := ir.ActionValue("BLOCK_ID", "approve", "yes").Wrap(ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					matched = ir.MatchedAction(ctx)
+					return nil
+				}))
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{
+						BlockActions: []*slack.BlockAction{approve, reject},
+					},
+				}
+				err := h.HandleInteraction(context.Background(), callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(matched).To(BeIdenticalTo(approve))
+			})
+		})
+
+		Context("when the handler is not wrapped with such predicates", func() {
+			It("returns nil", func() {
+				Expect(ir.MatchedAction(context.Background())).To(BeNil())
+			})
+		})
+
+		Context("when more than one handlers are registered for a callback with more than one block actions", func() {
+			It("passes each handler its own matched block action", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.AllMatches))
+				Expect(err).NotTo(HaveOccurred())
+				var approved, rejected *slack.BlockAction
+				r.OnBlockAction("BLOCK_ID", "approve", ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					approved = ir.MatchedAction(ctx)
+					return nil
+				}))
+				r.OnBlockAction("BLOCK_ID", "reject", ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					rejected = ir.MatchedAction(ctx)
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(approved).NotTo(BeNil())
+				Expect(approved.ActionID).To(Equal("approve"))
+				Expect(approved.Value).To(Equal("yes"))
+				Expect(rejected).NotTo(BeNil())
+				Expect(rejected.ActionID).To(Equal("reject"))
+				Expect(rejected.Value).To(Equal("no"))
+			})
+		})
+	})
+
 	Describe("CallbackID", func() {
 		var (
 			numHandlerCalled int
@@ -217,34 +527,3473 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
-	Describe("New", func() {
-		Context("when neither WithSigningSecret nor InsecureSkipVerification is given", func() {
-			It("returns an error", func() {
-				_, err := ir.New()
-				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+	Describe("ViewCallbackID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the callback_id of the view matches to the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := ir.ViewCallbackID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+					View: slack.View{CallbackID: "THE_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the callback_id of the view differs from the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewCallbackID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+					View: slack.View{CallbackID: "ANOTHER_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when only the top-level callback_id matches to the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewCallbackID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeViewSubmission,
+					CallbackID: "THE_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Not", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx = context.Background()
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		Context("when the given predicate does not match", func() {
+			It("calls the inner handler", func() {
+				h := ir.Not(ir.CallbackID("CALLBACK_ID")).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{CallbackID: "ANOTHER_CALLBACK_ID"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
 			})
 		})
 
-		Context("when InsecureSkipVerification is given", func() {
-			It("returns a new Router", func() {
-				r, err := ir.New(ir.InsecureSkipVerification())
+		Context("when the given predicate matches", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Not(ir.CallbackID("CALLBACK_ID")).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{CallbackID: "CALLBACK_ID"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Any", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx = context.Background()
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		Context("when one of the given predicates matches", func() {
+			It("calls the inner handler exactly once", func() {
+				h := ir.Any(ir.CallbackID("CALLBACK_ID"), ir.ViewCallbackID("CALLBACK_ID")).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{View: slack.View{CallbackID: "CALLBACK_ID"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when none of the given predicates match", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Any(ir.CallbackID("CALLBACK_ID"), ir.ViewCallbackID("CALLBACK_ID")).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{CallbackID: "ANOTHER_CALLBACK_ID"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no predicate is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.Any() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ViewExternalID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the external_id of the view matches to the predicate's", func() {
+			It("calls the inner handler", func() {
+				h := ir.ViewExternalID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+					View: slack.View{ExternalID: "THE_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the external_id of the view differs from the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewExternalID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+					View: slack.View{ExternalID: "ANOTHER_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when only the top-level callback_id matches to the predicate's", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewExternalID("THE_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeViewSubmission,
+					CallbackID: "THE_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Shortcut", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when both the type and the callback_id match", func() {
+			It("calls the inner handler", func() {
+				h := ir.Shortcut("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeShortcut,
+					CallbackID: "CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the type differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Shortcut("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeMessageAction,
+					CallbackID: "CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the callback_id differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Shortcut("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeShortcut,
+					CallbackID: "ANOTHER_CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("MessageAction", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when both the type and the callback_id match", func() {
+			It("calls the inner handler", func() {
+				h := ir.MessageAction("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeMessageAction,
+					CallbackID: "CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the type differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.MessageAction("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeShortcut,
+					CallbackID: "CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the callback_id differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.MessageAction("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:       slack.InteractionTypeMessageAction,
+					CallbackID: "ANOTHER_CALLBACK_ID",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("ViewClosed", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when both the type and the view's callback_id match", func() {
+			It("calls the inner handler", func() {
+				h := ir.ViewClosed("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewClosed,
+					View: slack.View{CallbackID: "CALLBACK_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the type differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewClosed("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+					View: slack.View{CallbackID: "CALLBACK_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the view's callback_id differs", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ViewClosed("CALLBACK_ID").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewClosed,
+					View: slack.View{CallbackID: "ANOTHER_CALLBACK_ID"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the Router receives a view_closed payload", func() {
+			It("calls the handler with the closed view and responds with an empty body", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var view slack.View
+				r.On(slack.InteractionTypeViewClosed, ir.HandlerFunc(func(_ context.Context, callback *slack.InteractionCallback) error {
+					view = callback.View
+					return nil
+				}), ir.ViewClosed("modal-identifier"))
+				req, err := NewRequest(`
+				{
+					"type": "view_closed",
+					"team": {"id": "TXXXXXX", "domain": "coverbands"},
+					"user": {"id": "UXXXXXX", "name": "dreamweaver"},
+					"view": {
+						"id": "VXXXXXX",
+						"type": "modal",
+						"callback_id": "modal-identifier",
+						"private_metadata": "shhh-its-secret"
+					},
+					"api_app_id": "AXXXXXX",
+					"is_cleared": false
+				}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body).To(BeEmpty())
+				Expect(view.ID).To(Equal("VXXXXXX"))
+				Expect(view.PrivateMetadata).To(Equal("shhh-its-secret"))
+			})
+		})
+	})
+
+	Describe("WithClient", func() {
+		var (
+			content = `
+			{
+				"type": "block_actions",
+				"team": {"id": "T12345", "domain": "example"},
+				"user": {"id": "U12345", "username": "alice", "team_id": "T12345"},
+				"channel": {"id": "C12345", "name": "general"},
+				"trigger_id": "12345.98765.abcd2358fdea",
+				"actions": [
+					{"type": "button", "block_id": "the_block", "action_id": "the_action", "value": "the_value"}
+				]
+			}`
+			server   *httptest.Server
+			requests map[string]url.Values
+		)
+		BeforeEach(func() {
+			requests = make(map[string]url.Values)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				body, _ := ioutil.ReadAll(req.Body)
+				if req.Header.Get("Content-Type") == "application/json" {
+					requests[req.URL.Path] = url.Values{"json": []string{string(body)}}
+				} else {
+					requests[req.URL.Path], _ = url.ParseQuery(string(body))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok": true, "message_ts": "1234567890.123456"}`))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when WithClient is given", func() {
+			It("provides a client filled with parameters of the callback", func() {
+				client := slack.New("xoxb-token", slack.OptionAPIURL(server.URL+"/"))
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithClient(client))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					c := ir.Client(ctx)
+					Expect(c).NotTo(BeNil())
+					_, err := c.OpenView(ctx, slack.ModalViewRequest{Type: slack.VTModal})
+					Expect(err).NotTo(HaveOccurred())
+					_, err = c.PostEphemeral(ctx, slack.MsgOptionText("hello", false))
+					Expect(err).NotTo(HaveOccurred())
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(requests).To(HaveKey("/views.open"))
+				Expect(requests["/views.open"].Get("json")).To(ContainSubstring(`"trigger_id":"12345.98765.abcd2358fdea"`))
+				Expect(requests).To(HaveKey("/chat.postEphemeral"))
+				Expect(requests["/chat.postEphemeral"].Get("channel")).To(Equal("C12345"))
+				Expect(requests["/chat.postEphemeral"].Get("user")).To(Equal("U12345"))
+			})
+		})
+
+		Context("when WithClient is not given", func() {
+			It("provides nil, which is still safe to use", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var openErr error
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					c := ir.Client(ctx)
+					Expect(c).To(BeNil())
+					_, openErr = c.OpenView(ctx, slack.ModalViewRequest{Type: slack.VTModal})
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(openErr).To(Equal(ir.ErrNoClient))
+			})
+		})
+	})
+
+	Describe("RespondWith", func() {
+		var (
+			server     *httptest.Server
+			statusCode int
+			received   []byte
+			payload    = func(responseURL string) string {
+				return fmt.Sprintf(`
+				{
+					"type": "block_actions",
+					"user": {"id": "U12345", "username": "alice", "team_id": "T12345"},
+					"response_url": %q,
+					"actions": [
+						{"type": "button", "block_id": "the_block", "action_id": "the_action", "value": "the_value"}
+					]
+				}`, responseURL)
+			}
+		)
+		BeforeEach(func() {
+			statusCode = http.StatusOK
+			received = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				received, _ = ioutil.ReadAll(req.Body)
+				w.WriteHeader(statusCode)
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when the callback has a response_url", func() {
+			It("posts the message to it", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var respondErr error
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					msg := slack.Message{}
+					msg.Text = "done!"
+					respondErr = ir.RespondWith(ctx, msg, true)
+					return nil
+				}))
+				req, err := NewRequest(payload(server.URL + "/response"))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(respondErr).NotTo(HaveOccurred())
+				Expect(received).To(MatchJSON(`{"text":"done!","replace_original":true,"delete_original":false,"blocks":null}`))
+			})
+		})
+
+		Context("when the response_url responds with an error", func() {
+			It("returns an error", func() {
+				statusCode = http.StatusNotFound
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var respondErr error
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					respondErr = ir.RespondWith(ctx, slack.Message{}, false)
+					return nil
+				}))
+				req, err := NewRequest(payload(server.URL + "/response"))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(respondErr).To(MatchError(MatchRegexp("404")))
+			})
+		})
+
+		Context("when WithHTTPClient is given", func() {
+			It("uses the client with its timeout", func() {
+				release := make(chan struct{})
+				slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					<-release
+				}))
+				defer slow.Close()
+				defer close(release)
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+				Expect(err).NotTo(HaveOccurred())
+				var respondErr error
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					respondErr = ir.RespondWith(ctx, slack.Message{}, false)
+					return nil
+				}))
+				req, err := NewRequest(payload(slow.URL + "/response"))
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(respondErr).To(HaveOccurred())
+			})
+		})
+
+		Context("when the context is canceled", func() {
+			It("returns an error", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var respondErr error
+				r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					ctx, cancel := context.WithCancel(ctx)
+					cancel()
+					respondErr = ir.RespondWith(ctx, slack.Message{}, false)
+					return nil
+				}))
+				req, err := NewRequest(payload(server.URL + "/response"))
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(errors.Is(respondErr, context.Canceled)).To(BeTrue())
+				Expect(received).To(BeNil())
+			})
+		})
+
+		Context("when the callback has no response_url", func() {
+			It("returns ErrNoResponseURL", func() {
+				err := ir.RespondWith(context.Background(), slack.Message{}, false)
+				Expect(err).To(Equal(ir.ErrNoResponseURL))
+			})
+		})
+	})
+
+	Describe("RawBody", func() {
+		It("passes the raw body to handlers", func() {
+			content := `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			secret := "THE_SECRET"
+			r, err := ir.New(ir.WithSigningSecret(secret))
+			Expect(err).NotTo(HaveOccurred())
+			var rawBody []byte
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				rawBody = ir.RawBody(ctx)
+				return nil
+			}))
+			req, err := NewSignedRequest(secret, content, nil)
+			Expect(err).NotTo(HaveOccurred())
+			body := &countingReader{r: req.Body}
+			req.Body = body
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(string(rawBody)).To(Equal(string(buildRequestBody(content))))
+			Expect(body.n).To(Equal(len(buildRequestBody(content))))
+		})
+	})
+
+	Describe("HandleSocketMode", func() {
+		var (
+			r     *ir.Router
+			acker *recordingAcker
+			ctx   = context.Background()
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			acker = &recordingAcker{}
+		})
+
+		Context("when an interactive event is given", func() {
+			It("calls the handler and acknowledges the event", func() {
+				numHandlerCalled := 0
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numHandlerCalled++
+					return nil
+				}), ir.Shortcut("shortcut_create_task"))
+				evt := socketmode.Event{
+					Type:    socketmode.EventTypeInteractive,
+					Data:    slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: "shortcut_create_task"},
+					Request: &socketmode.Request{Type: "interactive", EnvelopeID: "ENVELOPE_ID"},
+				}
+				err := r.HandleSocketMode(ctx, acker, evt)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(acker.requests).To(HaveLen(1))
+				Expect(acker.requests[0].EnvelopeID).To(Equal("ENVELOPE_ID"))
+				Expect(acker.payloads[0]).To(BeEmpty())
+			})
+		})
+
+		Context("when the handler returns a response", func() {
+			It("acknowledges the event with the response", func() {
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return slack.NewClearViewSubmissionResponse(), nil
+				}))
+				evt := socketmode.Event{
+					Type:    socketmode.EventTypeInteractive,
+					Data:    slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission, View: slack.View{CallbackID: "create_task"}},
+					Request: &socketmode.Request{Type: "interactive", EnvelopeID: "ENVELOPE_ID"},
+				}
+				err := r.HandleSocketMode(ctx, acker, evt)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(acker.payloads).To(HaveLen(1))
+				Expect(acker.payloads[0]).To(Equal([]interface{}{slack.NewClearViewSubmissionResponse()}))
+			})
+		})
+
+		Context("when the handler returns an error", func() {
+			It("returns the error without acknowledging the event", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return errors.New("something wrong happened")
+				}))
+				evt := socketmode.Event{
+					Type:    socketmode.EventTypeInteractive,
+					Data:    slack.InteractionCallback{Type: slack.InteractionTypeShortcut},
+					Request: &socketmode.Request{Type: "interactive", EnvelopeID: "ENVELOPE_ID"},
+				}
+				err := r.HandleSocketMode(ctx, acker, evt)
+				Expect(err).To(MatchError("something wrong happened"))
+				Expect(acker.requests).To(BeEmpty())
+			})
+		})
+
+		Context("when an event other than interactive ones is given", func() {
+			It("returns NotInterested", func() {
+				evt := socketmode.Event{Type: socketmode.EventTypeEventsAPI}
+				err := r.HandleSocketMode(ctx, acker, evt)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(acker.requests).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("TriggerID", func() {
+		It("returns the trigger ID of the callback being processed", func() {
+			r, err := ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			var triggerID string
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				triggerID = ir.TriggerID(ctx)
+				return nil
+			}), ir.Shortcut("shortcut_create_task"))
+			req, err := NewRequest(`
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(triggerID).To(Equal("944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"))
+		})
+
+		Context("when the context is not passed from the Router", func() {
+			It("returns an empty string", func() {
+				Expect(ir.TriggerID(context.Background())).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Channel", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the channel of the interaction callback is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := ir.Channel("C12345", "C67890").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:    slack.InteractionTypeBlockActions,
+					Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C67890"}}},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the channel of the interaction callback is not the given one", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Channel("C12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:    slack.InteractionTypeBlockActions,
+					Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C67890"}}},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the interaction callback has no channel", func() {
+			It("does not call the inner handler", func() {
+				h := ir.Channel("C12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type: slack.InteractionTypeViewSubmission,
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel ID is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.Channel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ActionFreshness", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx      context.Context
+			callback = func(actionTs string) *slack.InteractionCallback {
+				return &slack.InteractionCallback{
+					Type: slack.InteractionTypeBlockActions,
+					ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{
+						{BlockID: "block", ActionID: "action", ActionTs: actionTs},
+					}},
+				}
+			}
+			ago = func(d time.Duration) string {
+				t := time.Now().Add(-d)
+				return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the action happened just within max", func() {
+			It("calls the inner handler", func() {
+				h := ir.ActionFreshness(time.Minute).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback(ago(time.Minute-time.Second)))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the action happened just before max", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionFreshness(time.Minute).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback(ago(time.Minute+time.Second)))
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the callback has action_ts at the top level", func() {
+			It("uses it", func() {
+				h := ir.ActionFreshness(time.Minute).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{Type: slack.InteractionTypeShortcut, ActionTs: ago(time.Hour)})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when action_ts is malformed", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionFreshness(time.Minute).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, callback("not-a-timestamp"))
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when action_ts is missing", func() {
+			It("does not call the inner handler", func() {
+				h := ir.ActionFreshness(time.Minute).Wrap(innerHandler)
+				err := h.HandleInteraction(ctx, &slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when max is not positive", func() {
+			It("panics", func() {
+				Expect(func() { ir.ActionFreshness(0) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ActionContainer", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx             context.Context
+			messageCallback = func() *slack.InteractionCallback {
+				return &slack.InteractionCallback{
+					Type:      slack.InteractionTypeBlockActions,
+					Container: slack.Container{Type: "message", ChannelID: "C12345", MessageTs: "1355517523.000005"},
+				}
+			}
+			viewCallback = func(viewType slack.ViewType) *slack.InteractionCallback {
+				return &slack.InteractionCallback{
+					Type:      slack.InteractionTypeBlockActions,
+					Container: slack.Container{Type: "view", ViewID: "V12345"},
+					View:      slack.View{ID: "V12345", Type: viewType},
+				}
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the block action is in a message", func() {
+			It("matches the message container", func() {
+				err := ir.ActionContainer("message").Wrap(innerHandler).HandleInteraction(ctx, messageCallback())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("does not match view containers", func() {
+				err := ir.ActionContainer("view", "home").Wrap(innerHandler).HandleInteraction(ctx, messageCallback())
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the block action is in a modal", func() {
+			It("matches the view container and the modal type", func() {
+				err := ir.ActionContainer("view").Wrap(innerHandler).HandleInteraction(ctx, viewCallback(slack.VTModal))
+				Expect(err).NotTo(HaveOccurred())
+				err = ir.ActionContainer("modal").Wrap(innerHandler).HandleInteraction(ctx, viewCallback(slack.VTModal))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+
+			It("does not match the home tab or messages", func() {
+				err := ir.ActionContainer("home", "message").Wrap(innerHandler).HandleInteraction(ctx, viewCallback(slack.VTModal))
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the block action is in the home tab", func() {
+			It("matches the home type", func() {
+				err := ir.ActionContainer("home").Wrap(innerHandler).HandleInteraction(ctx, viewCallback(slack.VTHomeTab))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the callback has no container", func() {
+			It("does not call the inner handler", func() {
+				err := ir.ActionContainer("message", "view").Wrap(innerHandler).HandleInteraction(ctx, &slack.InteractionCallback{Type: slack.InteractionTypeShortcut})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no type is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.ActionContainer() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("AppID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the interaction callback is sent to one of the given apps", func() {
+			It("calls the inner handler", func() {
+				h := ir.AppID("A12345", "A67890").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:     slack.InteractionTypeShortcut,
+					APIAppID: "A67890",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the interaction callback is sent to another app", func() {
+			It("does not call the inner handler", func() {
+				h := ir.AppID("A12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Type:     slack.InteractionTypeShortcut,
+					APIAppID: "A67890",
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the payload has api_app_id", func() {
+			It("routes the callback to the handler for the app", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				called := ""
+				r.OnShortcut("shortcut_create_task", ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					called = "A12345"
+					return nil
+				}), ir.AppID("A12345"))
+				r.OnShortcut("shortcut_create_task", ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					called = "A67890"
+					return nil
+				}), ir.AppID("A67890"))
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task", "api_app_id": "A67890"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(called).To(Equal("A67890"))
+			})
+		})
+
+		Context("when no app ID is given", func() {
+			It("panics", func() {
+				Expect(func() { ir.AppID() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("TeamID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the team of the interaction callback is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := ir.TeamID("T12345", "T67890").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Team: slack.Team{ID: "T67890"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the team of the interaction callback is not the given one", func() {
+			It("does not call the inner handler", func() {
+				h := ir.TeamID("T12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Team: slack.Team{ID: "T67890"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the interaction callback has only an enterprise ID", func() {
+			It("does not call the inner handler", func() {
+				h := ir.TeamID("T12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Enterprise: slack.Enterprise{ID: "E12345"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("EnterpriseID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the interaction callback has only an enterprise ID that is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := ir.EnterpriseID("E12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Enterprise: slack.Enterprise{ID: "E12345"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the enterprise of the interaction callback is not the given one", func() {
+			It("does not call the inner handler", func() {
+				h := ir.EnterpriseID("E12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Enterprise: slack.Enterprise{ID: "E67890"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the interaction callback has no enterprise ID", func() {
+			It("does not call the inner handler", func() {
+				h := ir.EnterpriseID("E12345").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					Team: slack.Team{ID: "T12345"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("UserID", func() {
+		var (
+			numHandlerCalled int
+			innerHandler     = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			ctx = context.Background()
+		})
+
+		Context("when the interaction callback is triggered by one of the given users", func() {
+			It("calls the inner handler", func() {
+				h := ir.UserID("U12345", "U67890").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					User: slack.User{ID: "U12345"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the interaction callback is triggered by another user", func() {
+			It("does not call the inner handler", func() {
+				h := ir.UserID("U12345", "U67890").Wrap(innerHandler)
+				callback := &slack.InteractionCallback{
+					User: slack.User{ID: "U99999"},
+				}
+				err := h.HandleInteraction(ctx, callback)
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("New", func() {
+		Context("when neither WithSigningSecret nor InsecureSkipVerification is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New()
+				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+			})
+		})
+
+		Context("when InsecureSkipVerification is given", func() {
+			It("returns a new Router", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r).NotTo(BeNil())
+			})
+		})
+
+		Context("when WithSigningSecret is given", func() {
+			It("returns a new Router", func() {
+				r, err := ir.New(ir.WithSigningSecret("THE_TOKEN"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r).NotTo(BeNil())
+			})
+		})
+
+		Context("when both WithSigningSecret and InsecureSkipVerification are given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithSigningSecret("THE_TOKEN"))
+				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+			})
+		})
+
+		Context("when WithSigningSecret is given an empty secret", func() {
+			It("returns an error that mentions the empty secret", func() {
+				_, err := ir.New(ir.WithSigningSecret(""))
+				Expect(err).To(MatchError(MatchRegexp("empty signing secret")))
+			})
+		})
+
+		Context("when WithSigningSecrets is given an empty secret", func() {
+			It("returns an error that mentions the empty secret", func() {
+				_, err := ir.New(ir.WithSigningSecret("THE_TOKEN"), ir.WithSigningSecrets("OLD_TOKEN", ""))
+				Expect(err).To(MatchError(MatchRegexp("empty signing secret")))
+			})
+		})
+
+		Context("when WithErrorHandler is given nil", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithErrorHandler(nil))
+				Expect(err).To(MatchError(MatchRegexp("WithErrorHandler")))
+			})
+		})
+	})
+
+	Describe("OnBlockAction", func() {
+		var (
+			r                *ir.Router
+			numHandlerCalled int
+			handler          = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled = 0
+			r.OnBlockAction("BLOCK_ID", "ACTION_ID", handler)
+		})
+
+		Context("when the callback comes from the given block element", func() {
+			It("calls the handler", func() {
+				req, err := NewRequest(`{"type": "block_actions", "actions": [{"block_id": "BLOCK_ID", "action_id": "ACTION_ID"}]}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the callback comes from another block element", func() {
+			It("does not call the handler", func() {
+				req, err := NewRequest(`{"type": "block_actions", "actions": [{"block_id": "BLOCK_ID", "action_id": "OTHER_ACTION_ID"}]}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("OnShortcut", func() {
+		var (
+			r                *ir.Router
+			numHandlerCalled int
+			handler          = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled = 0
+			r.OnShortcut("shortcut_create_task", handler)
+		})
+
+		Context("when the callback is a global shortcut with the given callback ID", func() {
+			It("calls the handler", func() {
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the callback is a message shortcut with the same callback ID", func() {
+			It("does not call the handler", func() {
+				req, err := NewRequest(`{"type": "message_action", "callback_id": "shortcut_create_task"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("OnMessageAction", func() {
+		var (
+			r                *ir.Router
+			numHandlerCalled int
+			handler          = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+			numHandlerCalled = 0
+			r.OnMessageAction("message_action_quote", handler)
+		})
+
+		Context("when the callback is a message shortcut with the given callback ID", func() {
+			It("calls the handler", func() {
+				req, err := NewRequest(`{"type": "message_action", "callback_id": "message_action_quote"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the callback has another callback ID", func() {
+			It("does not call the handler", func() {
+				req, err := NewRequest(`{"type": "message_action", "callback_id": "message_action_other"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("OnDialogSubmission", func() {
+		var (
+			r       *ir.Router
+			secret  = "THE_SECRET"
+			content = `
+			{
+				"type": "dialog_submission",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1536786524.554297",
+				"team": {"id": "T12345", "domain": "example"},
+				"user": {"id": "U12345", "name": "alice"},
+				"channel": {"id": "C12345", "name": "general"},
+				"submission": {"email": "not an email"},
+				"callback_id": "employee_offsite",
+				"response_url": "https://hooks.slack.com/app/T12345/1234567890/XXXXXXXX",
+				"state": "Limo"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the handler returned validation errors", func() {
+			It("writes the errors to the body", func() {
+				r.OnDialogSubmission("employee_offsite", ir.DialogSubmissionHandlerFunc(func(_ context.Context, callback *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error) {
+					Expect(callback.Submission).To(HaveKeyWithValue("email", "not an email"))
+					return &slack.DialogInputValidationErrors{
+						Errors: []slack.DialogInputValidationError{{Name: "email", Error: "invalid email address"}},
+					}, nil
+				}))
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(w.Body.String()).To(MatchJSON(`{"errors":[{"name":"email","error":"invalid email address"}]}`))
+			})
+		})
+
+		Context("when the handler returned nil", func() {
+			It("responds with an empty body", func() {
+				r.OnDialogSubmission("employee_offsite", ir.DialogSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error) {
+					return nil, nil
+				}))
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when the callback ID is different", func() {
+			It("does not call the handler", func() {
+				numHandlerCalled := 0
+				r.OnDialogSubmission("another_dialog", ir.DialogSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.DialogInputValidationErrors, error) {
+					numHandlerCalled++
+					return nil, nil
+				}))
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("OnViewSubmission", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "view_submission",
+				"team": {"id": "T12345", "domain": "example"},
+				"user": {"id": "U12345", "username": "alice", "team_id": "T12345"},
+				"trigger_id": "12345.98765.abcd2358fdea",
+				"view": {
+					"id": "VNHU13V36",
+					"type": "modal",
+					"callback_id": "create_task",
+					"state": {
+						"values": {
+							"title_block": {
+								"title_input": {"type": "plain_text_input", "value": ""}
+							}
+						}
+					}
+				}
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the handler returned a response", func() {
+			It("writes the response to the body", func() {
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return slack.NewErrorsViewSubmissionResponse(map[string]string{
+						"title_block": "title must not be empty",
+					}), nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+				Expect(w.Body.String()).To(MatchJSON(`{"response_action":"errors","errors":{"title_block":"title must not be empty"}}`))
+			})
+		})
+
+		Context("when the handler returned nil", func() {
+			It("responds with an empty body", func() {
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return nil, nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when the handler returned an error", func() {
+			It("responds with InternalServerError", func() {
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return slack.NewClearViewSubmissionResponse(), errors.New("something wrong happened")
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		Context("when the callback ID does not match", func() {
+			It("does not call the handler", func() {
+				numHandlerCalled := 0
+				r.OnViewSubmission("another_callback_id", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					numHandlerCalled++
+					return slack.NewClearViewSubmissionResponse(), nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(BeEmpty())
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the predicates do not match", func() {
+			It("responds with an empty body", func() {
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return slack.NewClearViewSubmissionResponse(), nil
+				}), ir.ViewCallbackID("another_callback_id"))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("OnBlockSuggestion", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "block_suggestion",
+				"user": {"id": "U12345", "username": "alice", "team_id": "T12345"},
+				"team": {"id": "T12345", "domain": "example"},
+				"container": {"type": "view", "view_id": "V12345"},
+				"api_app_id": "A12345",
+				"action_id": "assignee_select",
+				"block_id": "assignee_block",
+				"value": "ali",
+				"view": {"id": "V12345", "type": "modal", "callback_id": "create_task"}
+			}`
+			options = []*slack.OptionBlockObject{
+				slack.NewOptionBlockObject("U12345", slack.NewTextBlockObject(slack.PlainTextType, "alice", false, false), nil),
+			}
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the handler returned options", func() {
+			It("writes the options to the body", func() {
+				r.OnBlockSuggestion("assignee_block", "assignee_select", ir.BlockSuggestionHandlerFunc(func(_ context.Context, callback *slack.InteractionCallback) (*ir.BlockSuggestionResponse, error) {
+					Expect(callback.Value).To(Equal("ali"))
+					return &ir.BlockSuggestionResponse{Options: options}, nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(MatchJSON(`{"options":[{"text":{"type":"plain_text","text":"alice"},"value":"U12345"}]}`))
+			})
+		})
+
+		Context("when the handler returned option groups", func() {
+			It("writes the option groups to the body", func() {
+				r.OnBlockSuggestion("assignee_block", "assignee_select", ir.BlockSuggestionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*ir.BlockSuggestionResponse, error) {
+					return &ir.BlockSuggestionResponse{
+						OptionGroups: []*slack.OptionGroupBlockObject{
+							slack.NewOptionGroupBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "members", false, false), options...),
+						},
+					}, nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(MatchJSON(`{"option_groups":[{"label":{"type":"plain_text","text":"members"},"options":[{"text":{"type":"plain_text","text":"alice"},"value":"U12345"}]}]}`))
+			})
+		})
+
+		Context("when the handler returned nil", func() {
+			It("writes empty options to the body", func() {
+				r.OnBlockSuggestion("assignee_block", "assignee_select", ir.BlockSuggestionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*ir.BlockSuggestionResponse, error) {
+					return nil, nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(MatchJSON(`{"options":[]}`))
+			})
+		})
+
+		Context("when the handler is registered to another action", func() {
+			It("does not call the handler", func() {
+				numHandlerCalled := 0
+				r.OnBlockSuggestion("assignee_block", "reviewer_select", ir.BlockSuggestionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*ir.BlockSuggestionResponse, error) {
+					numHandlerCalled++
+					return nil, nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("WithLogger", func() {
+		var (
+			r       *ir.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			logger *recordingLogger
+		)
+		BeforeEach(func() {
+			logger = &recordingLogger{}
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(token), ir.WithLogger(logger))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the signature is invalid", func() {
+			It("writes a log at the info level", func() {
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set(testutils.HeaderSignature, "v0="+hex.EncodeToString([]byte("INVALID_SIGNATURE")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].level).To(Equal("info"))
+			})
+		})
+
+		Context("when the payload is malformed", func() {
+			It("writes a log at the info level", func() {
+				req, err := NewSignedRequest(token, "{", nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].level).To(Equal("info"))
+			})
+		})
+
+		Context("when a handler returned an error", func() {
+			It("writes a log with the trigger_id at the error level", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return errors.New("something wrong happened")
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].level).To(Equal("error"))
+				Expect(logger.entries[0].keysAndValues).To(ContainElement("944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"))
+			})
+
+			It("writes a log with the request ID", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return errors.New("something wrong happened")
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].keysAndValues).To(ContainElements("requestID", w.Result().Header.Get("X-Request-ID")))
+			})
+		})
+
+		Context("when a handler succeeded", func() {
+			It("does not write any logs", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				req, err := NewSignedRequest(token, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(logger.entries).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("RequestID", func() {
+		var (
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+
+		Context("when the request has no X-Request-ID header", func() {
+			It("generates a request ID and passes it to both the response and the handler", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var requestID string
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					requestID = ir.RequestID(ctx)
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Result().Header.Get("X-Request-ID")).NotTo(BeEmpty())
+				Expect(requestID).To(Equal(w.Result().Header.Get("X-Request-ID")))
+			})
+
+			It("generates different IDs for different requests", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				ids := make([]string, 0, 2)
+				for i := 0; i < 2; i++ {
+					req, err := NewRequest(payload)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					ids = append(ids, w.Result().Header.Get("X-Request-ID"))
+				}
+				Expect(ids[0]).NotTo(Equal(ids[1]))
+			})
+		})
+
+		Context("when the request has an X-Request-ID header", func() {
+			It("uses the given ID", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				var requestID string
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					requestID = ir.RequestID(ctx)
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("X-Request-ID", "req-12345")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().Header.Get("X-Request-ID")).To(Equal("req-12345"))
+				Expect(requestID).To(Equal("req-12345"))
+			})
+
+			It("ignores a malformed ID", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("X-Request-ID", "req 12345\nforged log line")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().Header.Get("X-Request-ID")).NotTo(BeEmpty())
+				Expect(w.Result().Header.Get("X-Request-ID")).NotTo(ContainSubstring(" "))
+			})
+		})
+
+		Context("when the Router is in the Async mode", func() {
+			It("passes the request ID to the handler", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async())
+				Expect(err).NotTo(HaveOccurred())
+				requestID := make(chan string, 1)
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					requestID <- ir.RequestID(ctx)
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Eventually(requestID).Should(Receive(Equal(w.Result().Header.Get("X-Request-ID"))))
+			})
+		})
+
+		Context("when the context is not passed from the Router", func() {
+			It("returns an empty string", func() {
+				Expect(ir.RequestID(context.Background())).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Async", func() {
+		var (
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+		)
+
+		Context("when a handler is slow", func() {
+			It("responds with 200 before the handler finishes", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async())
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				finished := make(chan struct{})
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					defer close(finished)
+					return ctx.Err()
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Consistently(finished).ShouldNot(BeClosed())
+				close(release)
+				Eventually(finished).Should(BeClosed())
+			})
+		})
+
+		Context("when a handler returned an error", func() {
+			It("responds with 200 and passes the error to the error handler", func() {
+				theError := errors.New("something wrong happened")
+				handledErrors := make(chan error, 1)
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async(),
+					ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+						w.WriteHeader(http.StatusInternalServerError)
+						handledErrors <- err
+					}))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return theError
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Eventually(handledErrors).Should(Receive(Equal(theError)))
+			})
+		})
+
+		Context("when the request context is canceled after ServeHTTP returns", func() {
+			It("runs the handler and the error handler with a detached context", func() {
+				theError := errors.New("something wrong happened")
+				handledCtxErrs := make(chan error, 1)
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async(),
+					ir.WithErrorHandler(func(_ http.ResponseWriter, req *http.Request, _ error) {
+						handledCtxErrs <- req.Context().Err()
+					}))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				handlerCtxErrs := make(chan error, 1)
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					handlerCtxErrs <- ctx.Err()
+					return theError
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				reqCtx, cancel := context.WithCancel(req.Context())
+				r.ServeHTTP(httptest.NewRecorder(), req.WithContext(reqCtx))
+				cancel()
+				close(release)
+				Eventually(handlerCtxErrs).Should(Receive(BeNil()))
+				Eventually(handledCtxErrs).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when WithMaxConcurrency is given", func() {
+			It("responds with Service Unavailable while the limit is reached", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async(), ir.WithMaxConcurrency(1))
+				Expect(err).NotTo(HaveOccurred())
+				release := make(chan struct{})
+				done := make(chan struct{})
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					<-release
+					done <- struct{}{}
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+
+				req, err = NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w = httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+				close(release)
+				Eventually(done).Should(Receive())
+				Expect(r.Shutdown(context.Background())).To(Succeed())
+			})
+		})
+
+		Context("when WithMaxConcurrency is given a negative number", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.Async(), ir.WithMaxConcurrency(-1))
+				Expect(err).To(MatchError(MatchRegexp("WithMaxConcurrency")))
+			})
+		})
+	})
+
+	Describe("Use", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			calls []string
+			trace = func(name string) ir.Middleware {
+				return func(h ir.Handler) ir.Handler {
+					return ir.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+						calls = append(calls, "before "+name)
+						err := h.HandleInteraction(ctx, callback)
+						calls = append(calls, "after "+name)
+						return err
+					})
+				}
+			}
+		)
+		BeforeEach(func() {
+			calls = nil
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when more than one middlewares are added", func() {
+			It("calls them in the order they are added around the handler", func() {
+				r.Use(trace("first"))
+				r.Use(trace("second"))
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					calls = append(calls, "handler")
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(calls).To(Equal([]string{"before first", "before second", "handler", "after second", "after first"}))
+			})
+		})
+
+		Context("when no handler is interested in the callback", func() {
+			It("calls the middlewares once", func() {
+				r.Use(trace("first"))
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					calls = append(calls, "handler")
+					return routererrors.NotInterested
+				}), ir.ViewCallbackID("another_callback_id"))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(calls).To(Equal([]string{"before first", "after first"}))
+			})
+		})
+
+		Context("when Recover is added and a handler panics", func() {
+			It("responds with InternalServerError", func() {
+				var handledError error
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+					handledError = err
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				r.Use(ir.Recover())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					panic("oops")
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(handledError).To(MatchError(MatchRegexp("oops")))
+			})
+		})
+	})
+
+	Describe("Retry", func() {
+		var (
+			transient = errors.New("transient error")
+			permanent = errors.New("permanent error")
+			ctx       context.Context
+			callback  = &slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: "shortcut_create_task"}
+			failing   = func(numCalled *int, errs ...error) ir.Handler {
+				return ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					*numCalled++
+					if *numCalled <= len(errs) {
+						return errs[*numCalled-1]
+					}
+					return nil
+				})
+			}
+			isTransient = func(err error) bool { return errors.Is(err, transient) }
+			noDelay     = func(_ int) time.Duration { return time.Millisecond }
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+		})
+
+		Context("when a handler fails twice and then succeeds", func() {
+			It("retries until it succeeds", func() {
+				numCalled := 0
+				var delays []int
+				backoff := func(n int) time.Duration {
+					delays = append(delays, n)
+					return time.Millisecond
+				}
+				h := ir.Retry(3, backoff, isTransient)(failing(&numCalled, transient, transient))
+				Expect(h.HandleInteraction(ctx, callback)).To(Succeed())
+				Expect(numCalled).To(Equal(3))
+				Expect(delays).To(Equal([]int{1, 2}))
+			})
+		})
+
+		Context("when a handler keeps failing", func() {
+			It("returns the last error after all attempts", func() {
+				numCalled := 0
+				h := ir.Retry(2, noDelay, isTransient)(failing(&numCalled, transient, transient, transient))
+				Expect(h.HandleInteraction(ctx, callback)).To(MatchError(transient))
+				Expect(numCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the error is not retryable", func() {
+			It("returns it immediately", func() {
+				numCalled := 0
+				h := ir.Retry(3, noDelay, isTransient)(failing(&numCalled, permanent))
+				Expect(h.HandleInteraction(ctx, callback)).To(MatchError(permanent))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the handler is not interested", func() {
+			It("does not retry", func() {
+				numCalled := 0
+				h := ir.Retry(3, noDelay, nil)(failing(&numCalled, routererrors.NotInterested))
+				Expect(h.HandleInteraction(ctx, callback)).To(Equal(routererrors.NotInterested))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the backoff exceeds the deadline of the context", func() {
+			It("gives up without waiting", func() {
+				numCalled := 0
+				ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+				h := ir.Retry(3, func(_ int) time.Duration { return time.Hour }, nil)(failing(&numCalled, transient, transient))
+				start := time.Now()
+				Expect(h.HandleInteraction(ctx, callback)).To(MatchError(transient))
+				Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when it is used as a Middleware of the Router", func() {
+			It("retries handlers", func() {
+				numCalled := 0
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.Use(ir.Retry(3, noDelay, isTransient))
+				r.OnShortcut("shortcut_create_task", failing(&numCalled, transient, transient))
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numCalled).To(Equal(3))
+			})
+		})
+
+		Context("when it wraps one of handlers called with AllMatches", func() {
+			It("retries only that handler", func() {
+				numSucceedingCalled, numFailingCalled := 0, 0
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.AllMatches))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, failing(&numSucceedingCalled))
+				r.On(slack.InteractionTypeShortcut, ir.Retry(3, noDelay, isTransient)(failing(&numFailingCalled, transient)))
+				req, err := NewRequest(`{"type": "shortcut", "callback_id": "shortcut_create_task"}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numSucceedingCalled).To(Equal(1))
+				Expect(numFailingCalled).To(Equal(2))
+			})
+		})
+
+		Context("when an attempt that set a response fails", func() {
+			It("discards the response", func() {
+				numRespondingCalled, numFailingCalled := 0, 0
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.AllMatches))
+				Expect(err).NotTo(HaveOccurred())
+				r.Use(ir.Retry(3, noDelay, isTransient))
+				r.OnViewSubmission("the-view", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					numRespondingCalled++
+					if numRespondingCalled == 1 {
+						return slack.NewClearViewSubmissionResponse(), nil
+					}
+					return nil, nil
+				}))
+				r.On(slack.InteractionTypeViewSubmission, failing(&numFailingCalled, transient))
+				req, err := NewRequest(`{"type": "view_submission", "view": {"callback_id": "the-view"}}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numRespondingCalled).To(Equal(2))
+				Expect(numFailingCalled).To(Equal(2))
+				Expect(w.Body.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when attempts is less than 1", func() {
+			It("panics", func() {
+				Expect(func() { ir.Retry(0, nil, nil) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("WithSigningSecretFunc", func() {
+		var (
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+
+		Context("when the function returns different secrets over time", func() {
+			It("verifies requests with the current secret", func() {
+				current := "FIRST_TOKEN"
+				numCalled := 0
+				r, err := ir.New(ir.WithSigningSecretFunc(func() ([]byte, error) {
+					numCalled++
+					return []byte(current), nil
+				}, 0))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				serve := func(secret string) int {
+					req, err := NewSignedRequest(secret, payload, nil)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					return w.Result().StatusCode
+				}
+				Expect(serve("FIRST_TOKEN")).To(Equal(http.StatusOK))
+				current = "SECOND_TOKEN"
+				Expect(serve("FIRST_TOKEN")).To(Equal(http.StatusUnauthorized))
+				Expect(serve("SECOND_TOKEN")).To(Equal(http.StatusOK))
+				Expect(numCalled).To(Equal(3))
+			})
+		})
+
+		Context("when TTL is given", func() {
+			It("caches the secret", func() {
+				current := "FIRST_TOKEN"
+				numCalled := 0
+				r, err := ir.New(ir.WithSigningSecretFunc(func() ([]byte, error) {
+					numCalled++
+					return []byte(current), nil
+				}, time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				for i := 0; i < 3; i++ {
+					req, err := NewSignedRequest("FIRST_TOKEN", payload, nil)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				}
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when a negative TTL is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.WithSigningSecretFunc(func() ([]byte, error) { return []byte("THE_TOKEN"), nil }, -time.Second))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WithObserver", func() {
+		var (
+			signingSecret = "THE_SIGNING_SECRET"
+			payload       = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+			observer      *recordingObserver
+			r             *ir.Router
+		)
+		BeforeEach(func() {
+			var err error
+			observer = &recordingObserver{}
+			r, err = ir.New(ir.WithSigningSecret(signingSecret), ir.WithObserver(observer))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when a handler processes the callback", func() {
+			It("notifies the observer in order", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				req, err := NewSignedRequest(signingSecret, payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerMatched", "HandlerFinished"}))
+				Expect(observer.callbacks).To(HaveLen(3))
+				for _, c := range observer.callbacks {
+					Expect(c.CallbackID).To(Equal("shortcut_create_task"))
+				}
+				Expect(observer.errs).To(Equal([]error{nil}))
+			})
+		})
+
+		Context("when the handler returns an error", func() {
+			It("notifies the observer of the error", func() {
+				handlerErr := errors.New("something wrong happened")
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return handlerErr
+				}))
+				req, err := NewSignedRequest(signingSecret, payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerMatched", "HandlerFinished"}))
+				Expect(observer.errs).To(Equal([]error{handlerErr}))
+			})
+		})
+
+		Context("when the handler panics", func() {
+			It("notifies the observer of the match before calling the handler", func() {
+				var eventsInHandler []string
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					eventsInHandler = append([]string(nil), observer.events...)
+					panic("oops")
+				}))
+				req, err := NewSignedRequest(signingSecret, payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(eventsInHandler).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerMatched"}))
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerMatched", "HandlerFinished"}))
+			})
+		})
+
+		Context("when the predicates of the handler are false", func() {
+			It("does not notify the observer of a match", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}), ir.CallbackID("another_callback"))
+				req, err := NewSignedRequest(signingSecret, payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerFinished"}))
+			})
+		})
+
+		Context("when no handlers are interested in the callback", func() {
+			It("notifies the observer of NotInterested", func() {
+				req, err := NewSignedRequest(signingSecret, payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "PayloadParsed", "HandlerFinished"}))
+				Expect(observer.errs).To(Equal([]error{routererrors.NotInterested}))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			It("notifies the observer of the failure", func() {
+				req, err := NewSignedRequest("WRONG_SIGNING_SECRET", payload, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(observer.events).To(Equal([]string{"RequestReceived", "SignatureFailed"}))
+				Expect(observer.errs).To(HaveLen(1))
+				Expect(observer.errs[0]).To(HaveOccurred())
+			})
+		})
+
+		Context("when nil is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithObserver(nil))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WithDispatchPolicy", func() {
+		var (
+			payload        = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+			calledFirst    int
+			calledSecond   int
+			calledFallback int
+		)
+		BeforeEach(func() {
+			calledFirst, calledSecond, calledFallback = 0, 0, 0
+		})
+
+		newRouter := func(policy ir.DispatchPolicy, firstErr, secondErr error) *ir.Router {
+			r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(policy))
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				calledFirst++
+				return firstErr
+			}))
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				calledSecond++
+				return secondErr
+			}))
+			r.SetFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				calledFallback++
+				return nil
+			}))
+			return r
+		}
+
+		serve := func(r *ir.Router) *http.Response {
+			req, err := NewRequest(payload)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result()
+		}
+
+		Context("when FirstMatch is given", func() {
+			It("stops at the first interested handler", func() {
+				r := newRouter(ir.FirstMatch, nil, nil)
+				resp := serve(r)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(calledFirst).To(Equal(1))
+				Expect(calledSecond).To(Equal(0))
+				Expect(calledFallback).To(Equal(0))
+			})
+		})
+
+		Context("when AllMatches is given", func() {
+			It("calls all interested handlers", func() {
+				r := newRouter(ir.AllMatches, nil, nil)
+				resp := serve(r)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(calledFirst).To(Equal(1))
+				Expect(calledSecond).To(Equal(1))
+				Expect(calledFallback).To(Equal(0))
+			})
+
+			It("skips handlers that are not interested", func() {
+				r := newRouter(ir.AllMatches, routererrors.NotInterested, nil)
+				resp := serve(r)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(calledFirst).To(Equal(1))
+				Expect(calledSecond).To(Equal(1))
+				Expect(calledFallback).To(Equal(0))
+			})
+
+			It("calls the fallback handler if none of the handlers are interested", func() {
+				r := newRouter(ir.AllMatches, routererrors.NotInterested, routererrors.NotInterested)
+				serve(r)
+				Expect(calledFallback).To(Equal(1))
+			})
+
+			It("aggregates errors returned from the handlers", func() {
+				var handlerErr error
+				firstErr := errors.New("first error")
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.AllMatches),
+					ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+						handlerErr = err
+						w.WriteHeader(routererrors.HTTPStatus(err))
+					}))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return firstErr
+				}))
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return routererrors.ErrConflict
+				}))
+				resp := serve(r)
+				Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+				Expect(handlerErr).To(MatchError("first error; Conflict"))
+				Expect(errors.Is(handlerErr, firstErr)).To(BeTrue())
+				Expect(errors.Is(handlerErr, routererrors.ErrConflict)).To(BeTrue())
+			})
+
+			It("tells which handler returned which error", func() {
+				var handlerErr error
+				firstErr := errors.New("first error")
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.AllMatches),
+					ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+						handlerErr = err
+						w.WriteHeader(routererrors.HTTPStatus(err))
+					}))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return firstErr
+				}))
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return routererrors.NotInterested
+				}))
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return errors.WithMessage(routererrors.ErrConflict, "second error")
+				}))
+				serve(r)
+				var dispatchErr *ir.DispatchError
+				Expect(errors.As(handlerErr, &dispatchErr)).To(BeTrue())
+				Expect(dispatchErr.Type).To(Equal(slack.InteractionTypeShortcut))
+				Expect(dispatchErr.Errors).To(HaveLen(2))
+				Expect(dispatchErr.Errors[0].Index).To(Equal(0))
+				Expect(dispatchErr.Errors[0].Err).To(Equal(firstErr))
+				Expect(dispatchErr.Errors[1].Index).To(Equal(2))
+				Expect(errors.Is(dispatchErr.Errors[1].Err, routererrors.ErrConflict)).To(BeTrue())
+				var httpErr routererrors.HttpError
+				Expect(errors.As(handlerErr, &httpErr)).To(BeTrue())
+				Expect(httpErr).To(Equal(routererrors.ErrConflict))
+				Expect(errors.Is(handlerErr, routererrors.NotInterested)).To(BeFalse())
+			})
+		})
+
+		Context("when an unknown policy is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithDispatchPolicy(ir.DispatchPolicy(42)))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WithHandlerTimeout", func() {
+		var (
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+
+		Context("when the handler exceeds the deadline", func() {
+			It("cancels the context and responds without waiting for the handler", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(50*time.Millisecond))
+				Expect(err).NotTo(HaveOccurred())
+				ctxErr := make(chan error, 1)
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					<-ctx.Done()
+					ctxErr <- ctx.Err()
+					// Simulate a handler that does not return immediately even after the cancellation.
+					time.Sleep(time.Second)
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				start := time.Now()
+				r.ServeHTTP(w, req)
+				Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Eventually(ctxErr).Should(Receive(Equal(context.DeadlineExceeded)))
+			})
+		})
+
+		Context("when the handler returns before the deadline", func() {
+			It("responds normally", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(time.Second))
+				Expect(err).NotTo(HaveOccurred())
+				var deadline time.Time
+				var hasDeadline bool
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					deadline, hasDeadline = ctx.Deadline()
+					return slack.NewClearViewSubmissionResponse(), nil
+				}))
+				req, err := NewRequest(`{"type": "view_submission", "view": {"callback_id": "create_task"}}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(MatchJSON(`{"response_action": "clear"}`))
+				Expect(hasDeadline).To(BeTrue())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), 500*time.Millisecond))
+			})
+		})
+
+		Context("when the Router is in the Async mode", func() {
+			It("sets the deadline to the background context", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async(), ir.WithHandlerTimeout(50*time.Millisecond))
+				Expect(err).NotTo(HaveOccurred())
+				ctxErr := make(chan error, 1)
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					<-ctx.Done()
+					ctxErr <- ctx.Err()
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Eventually(ctxErr).Should(Receive(Equal(context.DeadlineExceeded)))
+			})
+		})
+
+		Context("when a negative value is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(-time.Second))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WarnOnUnhandled", func() {
+		var (
+			logger   *recordingLogger
+			observer *recordingObserver
+			payload  = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+		BeforeEach(func() {
+			logger = &recordingLogger{}
+			observer = &recordingObserver{}
+		})
+
+		Context("when no handler is interested in the callback", func() {
+			It("writes a log with the type and the callback ID without changing the response", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WarnOnUnhandled(), ir.WithLogger(logger), ir.WithObserver(observer))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_delete_task", ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].level).To(Equal("info"))
+				Expect(logger.entries[0].keysAndValues).To(ContainElements(slack.InteractionTypeShortcut, "shortcut_create_task"))
+				Expect(observer.errs).To(ConsistOf(MatchError(routererrors.NotInterested)))
+			})
+		})
+
+		Context("when a handler is interested in the callback", func() {
+			It("does not write any logs", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WarnOnUnhandled(), ir.WithLogger(logger))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_create_task", ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(logger.entries).To(BeEmpty())
+			})
+		})
+
+		Context("when it is not given", func() {
+			It("does not write any logs for unhandled callbacks", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithLogger(logger))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(logger.entries).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WithStrictDecoding", func() {
+		var (
+			numHandlerCalled int
+			handler          = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			knownPayload   = `{"type": "shortcut", "callback_id": "shortcut_create_task", "team": {"id": "T12345"}}`
+			unknownPayload = `{"type": "shortcut", "callback_id": "shortcut_create_task", "brand_new_field": true}`
+			nestedPayload  = `{"type": "shortcut", "callback_id": "shortcut_create_task", "team": {"id": "T12345", "brand_new_field": true}}`
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		Context("when it is not given", func() {
+			It("accepts payloads with unknown fields", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, handler)
+				req, err := NewRequest(unknownPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when it is given", func() {
+			var r *ir.Router
+			BeforeEach(func() {
+				var err error
+				r, err = ir.New(ir.InsecureSkipVerification(), ir.WithStrictDecoding(), ir.VerboseResponse())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, handler)
+			})
+
+			It("accepts payloads without unknown fields", func() {
+				req, err := NewRequest(knownPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("rejects payloads with unknown fields", func() {
+				req, err := NewRequest(unknownPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(ContainSubstring("brand_new_field"))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+
+			It("rejects payloads with unknown fields in nested objects", func() {
+				req, err := NewRequest(nestedPayload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("WithSuccessResponse", func() {
+		var (
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+			handler = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return nil
+			})
+		)
+
+		Context("when it is not given", func() {
+			It("responds with 200 and an empty body", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, handler)
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.Len()).To(Equal(0))
+			})
+		})
+
+		Context("when it is given", func() {
+			It("responds with the given status and body", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithSuccessResponse(http.StatusAccepted, []byte(`{"text": "accepted"}`)))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, handler)
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusAccepted))
+				Expect(w.Body.String()).To(MatchJSON(`{"text": "accepted"}`))
+			})
+
+			It("does not override the response returned from the handler", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithSuccessResponse(http.StatusAccepted, []byte(`{"text": "accepted"}`)))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+					return slack.NewClearViewSubmissionResponse(), nil
+				}))
+				req, err := NewRequest(`{"type": "view_submission", "view": {"callback_id": "create_task"}}`)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(w.Body.String()).To(MatchJSON(`{"response_action": "clear"}`))
+			})
+
+			It("is used in the Async mode", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.Async(), ir.WithSuccessResponse(http.StatusAccepted, []byte("ok")))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, handler)
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusAccepted))
+				Expect(w.Body.String()).To(Equal("ok"))
+				Expect(r.Shutdown(context.Background())).To(Succeed())
+			})
+		})
+
+		Context("when a non-2xx status is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithSuccessResponse(http.StatusBadRequest, nil))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Shutdown", func() {
+		var (
+			r       *ir.Router
+			release chan struct{}
+			started chan struct{}
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.Async())
+			Expect(err).NotTo(HaveOccurred())
+			release = make(chan struct{})
+			started = make(chan struct{}, 1)
+			// Handlers may outlive each spec, so they must not refer to the variables shared among specs.
+			localRelease, localStarted := release, started
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				localStarted <- struct{}{}
+				<-localRelease
+				return nil
+			}))
+		})
+
+		serve := func() *http.Response {
+			req, err := NewRequest(payload)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Result()
+		}
+
+		Context("when running handlers finish before the deadline", func() {
+			It("waits for them to finish", func() {
+				Expect(serve().StatusCode).To(Equal(http.StatusOK))
+				Eventually(started).Should(Receive())
+				done := make(chan error, 1)
+				go func() {
+					done <- r.Shutdown(context.Background())
+				}()
+				Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+				close(release)
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when running handlers do not finish before the deadline", func() {
+			It("returns DeadlineExceeded", func() {
+				defer close(release)
+				Expect(serve().StatusCode).To(Equal(http.StatusOK))
+				Eventually(started).Should(Receive())
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				err := r.Shutdown(ctx)
+				Expect(err).To(Equal(context.DeadlineExceeded))
+			})
+		})
+
+		Context("when a callback comes after Shutdown is called", func() {
+			It("responds with ServiceUnavailable without calling handlers", func() {
+				close(release)
+				err := r.Shutdown(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(serve().StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Consistently(started, 50*time.Millisecond).ShouldNot(Receive())
+			})
+		})
+
+		Context("when a handler outlives WithHandlerTimeout in the synchronous mode", func() {
+			It("waits for it to finish", func() {
+				var err error
+				r, err = ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(50*time.Millisecond))
+				Expect(err).NotTo(HaveOccurred())
+				localRelease := release
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					<-localRelease
+					return nil
+				}))
+				Expect(serve().StatusCode).NotTo(Equal(http.StatusOK))
+				done := make(chan error, 1)
+				go func() {
+					done <- r.Shutdown(context.Background())
+				}()
+				Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+				close(release)
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when a callback comes after Shutdown is called in the synchronous mode with WithHandlerTimeout", func() {
+			BeforeEach(func() {
+				var err error
+				r, err = ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(time.Second))
+				Expect(err).NotTo(HaveOccurred())
+				localStarted := started
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					localStarted <- struct{}{}
+					return nil
+				}))
+				Expect(r.Shutdown(context.Background())).To(Succeed())
+			})
+
+			It("responds with ServiceUnavailable without calling handlers", func() {
+				Expect(serve().StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Consistently(started, 50*time.Millisecond).ShouldNot(Receive())
+			})
+
+			It("returns an error from HandleSocketMode without acknowledging the event", func() {
+				acker := &recordingAcker{}
+				evt := socketmode.Event{
+					Type:    socketmode.EventTypeInteractive,
+					Data:    slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: "shortcut_create_task"},
+					Request: &socketmode.Request{Type: "interactive", EnvelopeID: "ENVELOPE_ID"},
+				}
+				err := r.HandleSocketMode(context.Background(), acker, evt)
+				Expect(errors.Is(err, routererrors.ErrServiceUnavailable)).To(BeTrue())
+				Expect(acker.requests).To(BeEmpty())
+				Consistently(started, 50*time.Millisecond).ShouldNot(Receive())
+			})
+		})
+
+		Context("when callbacks come while Shutdown is running in the synchronous mode with WithHandlerTimeout", func() {
+			It("does not race with Shutdown", func() {
+				var err error
+				r, err = ir.New(ir.InsecureSkipVerification(), ir.WithHandlerTimeout(time.Second))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return nil
+				}))
+				codes := make(chan int, 50)
+				for i := 0; i < cap(codes); i++ {
+					req, err := NewRequest(payload)
+					Expect(err).NotTo(HaveOccurred())
+					go func() {
+						w := httptest.NewRecorder()
+						r.ServeHTTP(w, req)
+						codes <- w.Result().StatusCode
+					}()
+				}
+				Expect(r.Shutdown(context.Background())).To(Succeed())
+				for i := 0; i < cap(codes); i++ {
+					Eventually(codes).Should(Receive(BeElementOf(http.StatusOK, http.StatusServiceUnavailable)))
+				}
+				Expect(serve().StatusCode).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+	})
+
+	Describe("WithoutPanicRecovery", func() {
+		var (
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			panicking = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				panic("oops")
+			})
+		)
+
+		Context("when it is not given and a handler panics", func() {
+			It("logs the panic and responds with InternalServerError", func() {
+				logger := &recordingLogger{}
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithLogger(logger))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, panicking)
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(w.Body.String()).To(BeEmpty())
+				Expect(logger.entries).To(HaveLen(1))
+				Expect(logger.entries[0].level).To(Equal("error"))
+			})
+		})
+
+		Context("when it is given and a handler panics", func() {
+			It("does not recover from the panic", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithoutPanicRecovery())
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, panicking)
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				Expect(func() { r.ServeHTTP(w, req) }).To(PanicWith("oops"))
+			})
+		})
+	})
+
+	Describe("Mounting", func() {
+		var (
+			secret           = "THE_SECRET"
+			payload          = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+			numHandlerCalled int
+			r                *ir.Router
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret))
+			Expect(err).NotTo(HaveOccurred())
+			r.OnShortcut("shortcut_create_task", ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			}))
+		})
+
+		serve := func(h http.Handler, path string) int {
+			req, err := NewSignedRequest(secret, payload, nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.URL.Path = path
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			return w.Result().StatusCode
+		}
+
+		Context("when the Router is mounted at a subpath", func() {
+			It("processes callbacks", func() {
+				mux := http.NewServeMux()
+				mux.Handle("/slack/interactions", r)
+				Expect(serve(mux, "/slack/interactions")).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the Router is mounted under http.StripPrefix", func() {
+			It("processes callbacks", func() {
+				mux := http.NewServeMux()
+				mux.Handle("/slack/", http.StripPrefix("/slack", r))
+				Expect(serve(mux, "/slack/interactions")).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("WithRateLimit", func() {
+		var (
+			numHandlerCalled int
+			handler          = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			})
+			payload = func(teamID, userID string) string {
+				return fmt.Sprintf(`{"type": "shortcut", "callback_id": "shortcut_create_task", "team": {"id": %q}, "user": {"id": %q}}`, teamID, userID)
+			}
+			serve = func(r *ir.Router, payload string) int {
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Result().StatusCode
+			}
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+		})
+
+		Context("when a team exceeds the burst", func() {
+			It("responds with Too Many Requests without calling handlers", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithRateLimit(rate.Limit(0.001), 2, ir.ByTeamID))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_create_task", handler)
+				Expect(serve(r, payload("T1", "U1"))).To(Equal(http.StatusOK))
+				Expect(serve(r, payload("T1", "U2"))).To(Equal(http.StatusOK))
+				Expect(serve(r, payload("T1", "U3"))).To(Equal(http.StatusTooManyRequests))
+				Expect(numHandlerCalled).To(Equal(2))
+
+				By("not limiting other teams")
+				Expect(serve(r, payload("T2", "U1"))).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(3))
+			})
+		})
+
+		Context("when a user exceeds the burst", func() {
+			It("limits only the user", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithRateLimit(rate.Limit(0.001), 1, ir.ByUserID))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_create_task", handler)
+				Expect(serve(r, payload("T1", "U1"))).To(Equal(http.StatusOK))
+				Expect(serve(r, payload("T1", "U1"))).To(Equal(http.StatusTooManyRequests))
+				Expect(serve(r, payload("T1", "U2"))).To(Equal(http.StatusOK))
+				Expect(serve(r, payload("T2", "U1"))).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the callback is received via Socket Mode", func() {
+			It("returns an error", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithRateLimit(rate.Limit(0.001), 1, ir.ByTeamID))
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_create_task", handler)
+				evt := socketmode.Event{
+					Type:    socketmode.EventTypeInteractive,
+					Data:    slack.InteractionCallback{Type: slack.InteractionTypeShortcut, CallbackID: "shortcut_create_task", Team: slack.Team{ID: "T1"}},
+					Request: &socketmode.Request{EnvelopeID: "envelope"},
+				}
+				Expect(r.HandleSocketMode(context.Background(), &recordingAcker{}, evt)).To(Succeed())
+				err = r.HandleSocketMode(context.Background(), &recordingAcker{}, evt)
+				Expect(errors.Is(err, routererrors.HttpError(http.StatusTooManyRequests))).To(BeTrue())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the key function is nil", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithRateLimit(rate.Limit(1), 1, nil))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the burst is not positive", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithRateLimit(rate.Limit(1), 0, ir.ByTeamID))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("WithMaxBodyBytes", func() {
+		var (
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+		)
+
+		Context("when the body exceeds the limit", func() {
+			It("responds with RequestEntityTooLarge", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithMaxBodyBytes(64))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("when the body exceeds the limit and the request is signed", func() {
+			It("responds with RequestEntityTooLarge", func() {
+				secret := "THE_SECRET"
+				r, err := ir.New(ir.WithSigningSecret(secret), ir.WithMaxBodyBytes(64))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("when the body does not exceed the default limit", func() {
+			It("responds with 200", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the limit is math.MaxInt64", func() {
+			It("responds with 200", func() {
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithMaxBodyBytes(math.MaxInt64))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the limit is not positive", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithMaxBodyBytes(-1))
+				Expect(err).To(MatchError(MatchRegexp("WithMaxBodyBytes")))
+			})
+		})
+	})
+
+	Describe("WithRouteTrace", func() {
+		var (
+			payload = `
+			{
+				"type": "block_actions",
+				"actions": [{"block_id": "B1", "action_id": "A2", "action_ts": "1581106241.371594"}]
+			}`
+			logger *recordingLogger
+			r      *ir.Router
+			traces = func() []string {
+				var ts []string
+				for _, e := range logger.entries {
+					if e.msg == "route trace" {
+						ts = append(ts, e.keysAndValues[1].(string))
+					}
+				}
+				return ts
+			}
+			serve = func() {
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+			}
+			nopHandler = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			logger = &recordingLogger{}
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.WithLogger(logger), ir.WithRouteTrace())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when a predicate is considered to be false", func() {
+			It("logs the predicates evaluated so far", func() {
+				r.On(slack.InteractionTypeBlockActions, nopHandler,
+					ir.BlockAction("B1", "A1"), ir.Named("IsAdmin", ir.UserID("U1")), ir.Type(slack.InteractionTypeBlockActions))
+				serve()
+				Expect(traces()).To(Equal([]string{"Type(block_actions)=true, IsAdmin=false -> NotInterested"}))
+			})
+		})
+
+		Context("when all predicates are considered to be true", func() {
+			It("logs the result of the handler", func() {
+				r.On(slack.InteractionTypeBlockActions, nopHandler, ir.BlockID("B1"), ir.Not(ir.ActionID("A1")))
+				serve()
+				Expect(traces()).To(Equal([]string{"Not(ActionID(A1))=true, BlockID(B1)=true -> OK"}))
+			})
+		})
+
+		Context("when predicates of various kinds are evaluated", func() {
+			It("logs their descriptions", func() {
+				r.On(slack.InteractionTypeBlockActions, nopHandler,
+					ir.Not(ir.UserID("U1", "U2")),
+					ir.Not(ir.EnterpriseID("E1")),
+					ir.Not(ir.TeamID("T1")),
+					ir.Not(ir.ActionContainer("message", "view")),
+					ir.Not(ir.ActionFreshness(time.Minute)),
+					ir.Not(ir.AppID("A1")),
+					ir.Not(ir.Channel("C1")),
+					ir.Not(ir.MessageAction("M1")),
+					ir.Not(ir.Shortcut("S1")),
+					ir.Not(ir.ViewExternalID("X1")),
+					ir.Not(ir.ActionValue("B1", "A2", "V1")))
+				serve()
+				Expect(traces()).To(Equal([]string{
+					"Not(ActionValue(B1,A2,V1))=true, Not(ViewExternalID(X1))=true, Not(Shortcut(S1))=true, Not(MessageAction(M1))=true, " +
+						"Not(Channel(C1))=true, Not(AppID(A1))=true, Not(ActionFreshness(1m0s))=true, " +
+						"Not(ActionContainer(message,view))=true, Not(TeamID(T1))=true, Not(EnterpriseID(E1))=true, Not(UserID(U1,U2))=true -> OK",
+				}))
+			})
+		})
+
+		Context("when WithRouteTrace is not given", func() {
+			It("does not log route traces", func() {
+				var err error
+				r, err = ir.New(ir.InsecureSkipVerification(), ir.WithLogger(logger))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeBlockActions, nopHandler, ir.BlockAction("B1", "A1"))
+				serve()
+				Expect(traces()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WithClock", func() {
+		var (
+			secret  = "THE_SECRET"
+			now     = time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+			clock   = func() time.Time { return now }
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1617278340.000000",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			numHandlerCalled int
+			r                *ir.Router
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret), ir.WithClock(clock))
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			}), ir.ActionFreshness(time.Minute))
+		})
+
+		Context("when the request is signed within the tolerance of the clock", func() {
+			It("calls the handler", func() {
+				ts := now.Add(-5 * time.Minute)
+				req, err := NewSignedRequest(secret, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the request is signed before the tolerance of the clock", func() {
+			It("responds with Bad Request", func() {
+				ts := now.Add(-5*time.Minute - time.Second)
+				req, err := NewSignedRequest(secret, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the action is older than ActionFreshness according to the clock", func() {
+			It("does not call the handler", func() {
+				r, err := ir.New(ir.WithSigningSecret(secret), ir.WithClock(func() time.Time { return now.Add(2 * time.Minute) }))
+				Expect(err).NotTo(HaveOccurred())
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numHandlerCalled++
+					return nil
+				}), ir.ActionFreshness(time.Minute))
+				req, err := NewSignedRequest(secret, content, &now)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the clock is nil", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithClock(nil))
+				Expect(err).To(MatchError(MatchRegexp("WithClock")))
+			})
+		})
+	})
+
+	Describe("Signature verification of form bodies", func() {
+		It("verifies the exact bytes of the body without re-encoding it", func() {
+			secret := "THE_SECRET"
+			r, err := ir.New(ir.WithSigningSecret(secret))
+			Expect(err).NotTo(HaveOccurred())
+			var callbackID string
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, callback *slack.InteractionCallback) error {
+				callbackID = callback.CallbackID
+				return nil
+			}))
+			// Keys are not sorted, and spaces are escaped with both `+` and `%20`,
+			// so re-encoding the parsed form would produce different bytes.
+			body := []byte(`token=XXXXXXXXXXXXX&payload=%7B%22type%22%3A+%22shortcut%22%2C%20%22callback_id%22%3A%22shortcut_create_task%22%7D&extra=a+b`)
+			Expect(string(body)).NotTo(Equal(url.Values{
+				"payload": []string{`{"type": "shortcut", "callback_id":"shortcut_create_task"}`},
+				"token":   []string{"XXXXXXXXXXXXX"},
+				"extra":   []string{"a b"},
+			}.Encode()))
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			Expect(testutils.AddSignature(req.Header, []byte(secret), body, time.Now())).To(Succeed())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(callbackID).To(Equal("shortcut_create_task"))
+		})
+	})
+
+	Describe("WithPreVerifyHook", func() {
+		var (
+			secret  = "THE_SECRET"
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			allowlist = func(req *http.Request) error {
+				if req.RemoteAddr != "192.0.2.1:1234" {
+					return errors.Errorf("%s is not allowed", req.RemoteAddr)
+				}
+				return nil
+			}
+			numHandlerCalled int
+			r                *ir.Router
+		)
+		BeforeEach(func() {
+			numHandlerCalled = 0
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret), ir.WithPreVerifyHook(allowlist))
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				numHandlerCalled++
+				return nil
+			}))
+		})
+
+		Context("when the hook rejects the request", func() {
+			It("responds with Forbidden without calling handlers", func() {
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.RemoteAddr = "198.51.100.1:1234"
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusForbidden))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the hook rejects the request with an HttpError", func() {
+			It("responds with its status code", func() {
+				r, err := ir.New(ir.WithSigningSecret(secret), ir.WithPreVerifyHook(func(_ *http.Request) error {
+					return errors.WithMessage(routererrors.HttpError(http.StatusServiceUnavailable), "under maintenance")
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
+			})
+		})
+
+		Context("when the hook accepts the request", func() {
+			It("calls the handler", func() {
+				req, err := NewSignedRequest(secret, content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.RemoteAddr = "192.0.2.1:1234"
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("Malformed payloads", func() {
+		var (
+			secret = "THE_SECRET"
+			r      *ir.Router
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the request has no payload field", func() {
+			It("responds with Bad Request", func() {
+				body := []byte(url.Values{"foo": []string{"bar"}}.Encode())
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader(body))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				Expect(testutils.AddSignature(req.Header, []byte(secret), body, time.Now())).To(Succeed())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(Equal("missing payload"))
+			})
+		})
+
+		Context("when the payload is not valid JSON", func() {
+			It("responds with Bad Request", func() {
+				req, err := NewSignedRequest(secret, "garbage{", nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(HavePrefix("invalid payload JSON: "))
+			})
+		})
+
+		Context("when WithErrorHandler is given", func() {
+			It("passes errors that tell the reason", func() {
+				var handledErrors []error
+				r, err := ir.New(ir.InsecureSkipVerification(), ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+					handledErrors = append(handledErrors, err)
+					w.WriteHeader(routererrors.HTTPStatus(err))
+				}))
+				Expect(err).NotTo(HaveOccurred())
+				for _, payload := range []string{"", "garbage{"} {
+					req, err := NewRequest(payload)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusBadRequest))
+				}
+				Expect(handledErrors).To(HaveLen(2))
+				Expect(errors.Is(handledErrors[0], ir.ErrMissingPayload)).To(BeTrue())
+				Expect(errors.Is(handledErrors[0], ir.ErrInvalidPayload)).To(BeFalse())
+				Expect(errors.Is(handledErrors[1], ir.ErrInvalidPayload)).To(BeTrue())
+				Expect(errors.Is(handledErrors[1], routererrors.ErrBadRequest)).To(BeTrue())
+				var payloadErr *ir.PayloadError
+				Expect(errors.As(handledErrors[1], &payloadErr)).To(BeTrue())
+				var syntaxErr *json.SyntaxError
+				Expect(errors.As(payloadErr.Err, &syntaxErr)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("WithErrorHandler", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+			theError     = errors.New("something wrong happened")
+			handledError error
+		)
+		BeforeEach(func() {
+			handledError = nil
+			var err error
+			r, err = ir.New(ir.InsecureSkipVerification(), ir.WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+				handledError = err
+				w.WriteHeader(http.StatusTeapot)
+				_, _ = w.Write([]byte("custom error"))
+			}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when a handler returned an error", func() {
+			It("calls the error handler", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return theError
+				}))
+				req, err := NewRequest(content)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(r).NotTo(BeNil())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusTeapot))
+				Expect(w.Body.String()).To(Equal("custom error"))
+				Expect(handledError).To(Equal(theError))
 			})
 		})
 
-		Context("when WithSigningSecret is given", func() {
-			It("returns a new Router", func() {
-				r, err := ir.New(ir.WithSigningSecret("THE_TOKEN"))
+		Context("when a handler returned NotInterested", func() {
+			It("does not call the error handler", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return routererrors.NotInterested
+				}))
+				req, err := NewRequest(content)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(r).NotTo(BeNil())
-			})
-		})
-
-		Context("when both WithSigningSecret and InsecureSkipVerification are given", func() {
-			It("returns an error", func() {
-				_, err := ir.New(ir.InsecureSkipVerification(), ir.WithSigningSecret("THE_TOKEN"))
-				Expect(err).To(MatchError(MatchRegexp("WithSigningSecret")))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(handledError).To(BeNil())
 			})
 		})
 	})
@@ -313,6 +4062,98 @@ var _ = Describe("InteractionRouter", func() {
 		})
 	})
 
+	Describe("WithSigningSecrets", func() {
+		var (
+			r       *ir.Router
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecrets("THE_OLD_TOKEN", "THE_NEW_TOKEN"), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the request is signed with the second token", func() {
+			It("responds with 200", func() {
+				req, err := NewSignedRequest("THE_NEW_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the request is signed with an unknown token", func() {
+			It("responds with Unauthorized", func() {
+				req, err := NewSignedRequest("OOPS_I_MISTOOK_THE_TOKEN", content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("WithSignatureTolerance", func() {
+		var (
+			r       *ir.Router
+			token   = "THE_TOKEN"
+			content = `
+			{
+				"type": "shortcut",
+				"token": "XXXXXXXXXXXXX",
+				"action_ts": "1581106241.371594",
+				"callback_id": "shortcut_create_task",
+				"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+			}`
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(token), ir.WithSignatureTolerance(2*time.Hour), ir.VerboseResponse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the timestamp is within the tolerance", func() {
+			It("responds with 200", func() {
+				ts := time.Now().Add(-1 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is out of the tolerance", func() {
+			It("responds with BadRequest", func() {
+				ts := time.Now().Add(-3 * time.Hour)
+				req, err := NewSignedRequest(token, content, &ts)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when a negative tolerance is given", func() {
+			It("returns an error", func() {
+				_, err := ir.New(ir.WithSigningSecret(token), ir.WithSignatureTolerance(-time.Minute))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("InsecureSkipVerification", func() {
 		var (
 			r       *ir.Router
@@ -505,6 +4346,20 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 
+		Context("when a handler returned one of the predefined HttpErrors", func() {
+			It("responds with a corresponding status code", func() {
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					return errors.WithMessage(routererrors.ErrForbidden, "you ain't allowed")
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
 		Context("when a handler returned an error that equals to HttpError using errors.As", func() {
 			It("responds with a corresponding status code", func() {
 				code := http.StatusUnauthorized
@@ -650,6 +4505,107 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 
+		Describe("Fallback with predicates", func() {
+			var (
+				numHandlerCalled  int
+				numFallbackCalled int
+			)
+			BeforeEach(func() {
+				numHandlerCalled = 0
+				numFallbackCalled = 0
+				r.SetFallback(ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+					numFallbackCalled++
+					return nil
+				}))
+			})
+
+			Context("when no handler is registered for the callback", func() {
+				It("calls the fallback handler", func() {
+					r.On(slack.InteractionTypeBlockActions, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+						numHandlerCalled++
+						return nil
+					}))
+					req, err := NewRequest(content)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+					Expect(numHandlerCalled).To(Equal(0))
+					Expect(numFallbackCalled).To(Equal(1))
+				})
+			})
+
+			Context("when predicates of the registered handler are not satisfied", func() {
+				It("calls the fallback handler", func() {
+					r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+						numHandlerCalled++
+						return nil
+					}), ir.CallbackID("shortcut_delete_task"))
+					req, err := NewRequest(content)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+					Expect(numHandlerCalled).To(Equal(0))
+					Expect(numFallbackCalled).To(Equal(1))
+				})
+			})
+
+			Context("when predicates of the registered handler are satisfied", func() {
+				It("does not call the fallback handler", func() {
+					r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+						numHandlerCalled++
+						return nil
+					}), ir.CallbackID("shortcut_create_task"))
+					req, err := NewRequest(content)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+					Expect(numHandlerCalled).To(Equal(1))
+					Expect(numFallbackCalled).To(Equal(0))
+				})
+			})
+
+			Context("when the handler returns NotInterested by itself", func() {
+				It("calls the fallback handler", func() {
+					r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+						numHandlerCalled++
+						return routererrors.NotInterested
+					}), ir.CallbackID("shortcut_create_task"))
+					req, err := NewRequest(content)
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+					Expect(numHandlerCalled).To(Equal(1))
+					Expect(numFallbackCalled).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when the request has a context", func() {
+			It("passes a context derived from it to the handler", func() {
+				type ctxKey struct{}
+				var gotValue interface{}
+				var gotErr error
+				r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+					gotValue = ctx.Value(ctxKey{})
+					gotErr = ctx.Err()
+					return nil
+				}))
+				req, err := NewRequest(content)
+				Expect(err).NotTo(HaveOccurred())
+				ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "the value"))
+				cancel()
+				req = req.WithContext(ctx)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				Expect(gotValue).To(Equal("the value"))
+				Expect(gotErr).To(Equal(context.Canceled))
+			})
+		})
+
 		Context("when no handler except for fallback is registered", func() {
 			It("calls fallback handler", func() {
 				numCalled := 0
@@ -690,8 +4646,208 @@ var _ = Describe("InteractionRouter", func() {
 			})
 		})
 	})
+
+	Describe("IdempotencyKey", func() {
+		var newCallback = func() *slack.InteractionCallback {
+			return &slack.InteractionCallback{
+				Type:      slack.InteractionTypeBlockActions,
+				Team:      slack.Team{ID: "T12345"},
+				User:      slack.User{ID: "U12345"},
+				TriggerID: "12345.98765.abcd2358fdea",
+				ActionCallback: slack.ActionCallbacks{BlockActions: []*slack.BlockAction{
+					{BlockID: "block", ActionID: "action", ActionTs: "1355517523.000005"},
+				}},
+			}
+		}
+
+		It("returns the same key for identical callbacks", func() {
+			Expect(ir.IdempotencyKey(newCallback())).To(Equal(ir.IdempotencyKey(newCallback())))
+		})
+
+		It("returns different keys for different actions", func() {
+			otherTrigger := newCallback()
+			otherTrigger.TriggerID = "12345.98765.ffff2358fdea"
+			otherTs := newCallback()
+			otherTs.ActionCallback.BlockActions[0].ActionTs = "1355517523.000006"
+			otherUser := newCallback()
+			otherUser.User.ID = "U67890"
+			keys := []string{
+				ir.IdempotencyKey(newCallback()),
+				ir.IdempotencyKey(otherTrigger),
+				ir.IdempotencyKey(otherTs),
+				ir.IdempotencyKey(otherUser),
+			}
+			seen := make(map[string]bool)
+			for _, k := range keys {
+				Expect(seen).NotTo(HaveKey(k))
+				seen[k] = true
+			}
+		})
+	})
+
+	Describe("Registration", func() {
+		var (
+			handler = ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return nil
+			})
+			payload = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+		)
+
+		Context("when handlers are registered after ServeHTTP is called", func() {
+			It("panics", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				r.OnShortcut("shortcut_create_task", handler)
+				req, err := NewRequest(payload)
+				Expect(err).NotTo(HaveOccurred())
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				Expect(func() { r.OnShortcut("shortcut_delete_task", handler) }).To(Panic())
+				Expect(func() { r.SetFallback(handler) }).To(Panic())
+				Expect(func() {
+					r.Use(func(h ir.Handler) ir.Handler { return h })
+				}).To(Panic())
+			})
+		})
+
+		Context("when handlers are registered after HandleSocketMode is called", func() {
+			It("panics", func() {
+				r, err := ir.New(ir.InsecureSkipVerification())
+				Expect(err).NotTo(HaveOccurred())
+				_ = r.HandleSocketMode(context.Background(), &recordingAcker{}, socketmode.Event{Type: socketmode.EventTypeHello})
+				Expect(func() { r.OnShortcut("shortcut_create_task", handler) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ViewState", func() {
+		var (
+			callback *slack.InteractionCallback
+			payload  = `
+			{
+				"type": "view_submission",
+				"team": {"id": "T12345", "domain": "example"},
+				"user": {"id": "U12345", "name": "alice"},
+				"view": {
+					"id": "V12345",
+					"type": "modal",
+					"callback_id": "create_task",
+					"state": {
+						"values": {
+							"title": {"title_input": {"type": "plain_text_input", "value": "Write docs"}},
+							"priority": {"priority_select": {"type": "static_select", "selected_option": {"text": {"type": "plain_text", "text": "High"}, "value": "high"}}},
+							"labels": {"labels_select": {"type": "multi_static_select", "selected_options": [
+								{"text": {"type": "plain_text", "text": "Bug"}, "value": "bug"},
+								{"text": {"type": "plain_text", "text": "Docs"}, "value": "docs"}
+							]}},
+							"due": {"due_date": {"type": "datepicker", "selected_date": "2021-08-01"}},
+							"assignees": {"assignees_select": {"type": "multi_users_select", "selected_users": ["U11111", "U22222"]}}
+						}
+					}
+				}
+			}`
+		)
+		BeforeEach(func() {
+			callback = &slack.InteractionCallback{}
+			err := json.Unmarshal([]byte(payload), callback)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the values exist", func() {
+			It("returns them", func() {
+				state := ir.ViewState(callback)
+				Expect(state.PlainText("title", "title_input")).To(Equal("Write docs"))
+				Expect(state.SelectedOption("priority", "priority_select")).To(Equal("high"))
+				Expect(state.SelectedOptions("labels", "labels_select")).To(Equal([]string{"bug", "docs"}))
+				Expect(state.SelectedDate("due", "due_date")).To(Equal("2021-08-01"))
+				Expect(state.SelectedUsers("assignees", "assignees_select")).To(Equal([]string{"U11111", "U22222"}))
+				_, ok := state.Action("title", "title_input")
+				Expect(ok).To(BeTrue())
+			})
+		})
+
+		Context("when the values do not exist", func() {
+			It("returns zero values", func() {
+				state := ir.ViewState(callback)
+				Expect(state.PlainText("title", "no_such_action")).To(Equal(""))
+				Expect(state.SelectedOption("no_such_block", "priority_select")).To(Equal(""))
+				Expect(state.SelectedOptions("labels", "no_such_action")).To(BeNil())
+				Expect(state.SelectedDate("title", "title_input")).To(Equal(""))
+				Expect(state.SelectedUsers("no_such_block", "no_such_action")).To(BeNil())
+				_, ok := state.Action("title", "no_such_action")
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Context("when the callback has no view state", func() {
+			It("returns zero values", func() {
+				state := ir.ViewState(&slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission})
+				Expect(state.PlainText("title", "title_input")).To(Equal(""))
+				Expect(ir.ViewState(nil).SelectedOptions("labels", "labels_select")).To(BeNil())
+			})
+		})
+	})
 })
 
+type logEntry struct {
+	level         string
+	msg           string
+	keysAndValues []interface{}
+}
+
+type recordingObserver struct {
+	ir.NopObserver
+	events    []string
+	callbacks []*slack.InteractionCallback
+	errs      []error
+}
+
+func (o *recordingObserver) RequestReceived(_ *http.Request) {
+	o.events = append(o.events, "RequestReceived")
+}
+
+func (o *recordingObserver) SignatureFailed(_ *http.Request, err error) {
+	o.events = append(o.events, "SignatureFailed")
+	o.errs = append(o.errs, err)
+}
+
+func (o *recordingObserver) PayloadParsed(_ context.Context, callback *slack.InteractionCallback) {
+	o.events = append(o.events, "PayloadParsed")
+	o.callbacks = append(o.callbacks, callback)
+}
+
+func (o *recordingObserver) HandlerMatched(_ context.Context, callback *slack.InteractionCallback) {
+	o.events = append(o.events, "HandlerMatched")
+	o.callbacks = append(o.callbacks, callback)
+}
+
+func (o *recordingObserver) HandlerFinished(_ context.Context, callback *slack.InteractionCallback, d time.Duration, err error) {
+	o.events = append(o.events, "HandlerFinished")
+	o.callbacks = append(o.callbacks, callback)
+	o.errs = append(o.errs, err)
+}
+
+type recordingAcker struct {
+	requests []socketmode.Request
+	payloads [][]interface{}
+}
+
+func (a *recordingAcker) Ack(req socketmode.Request, payload ...interface{}) {
+	a.requests = append(a.requests, req)
+	a.payloads = append(a.payloads, payload)
+}
+
+type recordingLogger struct {
+	entries []logEntry
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.entries = append(l.entries, logEntry{level: "info", msg: msg, keysAndValues: keysAndValues})
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.entries = append(l.entries, logEntry{level: "error", msg: msg, keysAndValues: keysAndValues})
+}
+
 func NewRequest(payload string) (*http.Request, error) {
 	body := buildRequestBody(payload)
 	req, err := http.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader([]byte(body)))
@@ -725,3 +4881,81 @@ func buildRequestBody(payload string) []byte {
 	form.Set("payload", payload)
 	return []byte(form.Encode())
 }
+
+// countingReader counts the number of bytes read from it.
+type countingReader struct {
+	r io.ReadCloser
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	r, body, header := newBenchmarkRouter(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader(body))
+		req.Header = header
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status: %d", w.Code)
+		}
+	}
+}
+
+// BenchmarkServeHTTPParallel is the same as BenchmarkServeHTTP except that requests are processed concurrently,
+// which is where pooled buffers are shared among goroutines.
+func BenchmarkServeHTTPParallel(b *testing.B) {
+	r, body, header := newBenchmarkRouter(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/path/to/callback", bytes.NewReader(body))
+			req.Header = header.Clone()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				b.Errorf("unexpected status: %d", w.Code)
+				return
+			}
+		}
+	})
+}
+
+func newBenchmarkRouter(b *testing.B) (*ir.Router, []byte, http.Header) {
+	b.Helper()
+	secret := "THE_SECRET"
+	payload := `
+	{
+		"type": "shortcut",
+		"token": "XXXXXXXXXXXXX",
+		"action_ts": "1581106241.371594",
+		"callback_id": "shortcut_create_task",
+		"trigger_id": "944799105734.773906753841.38b5894552bdd4a780554ee59d1f3638"
+	}`
+	r, err := ir.New(ir.WithSigningSecret(secret))
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+		return nil
+	}))
+	body := buildRequestBody(payload)
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := testutils.AddSignature(header, []byte(secret), body, time.Now()); err != nil {
+		b.Fatal(err)
+	}
+	return r, body, header
+}
@@ -0,0 +1,95 @@
+// Package predicates implements the boolean algebra of predicates shared by packages for each event type.
+//
+// Predicates in each package are typed by the events they process, so they are converted into Probes to be combined here.
+// This guarantees that combinators such as Not and Any treat `errors.NotInterested` and other errors in the same way everywhere.
+package predicates
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// Probe evaluates a predicate against an event captured by the closure.
+//
+// It calls next with the context that the predicate would pass to the inner handler if and only if the predicate is considered to be "true",
+// and returns what next returns. Otherwise it returns `errors.NotInterested` or any other error returned from the predicate.
+type Probe func(ctx context.Context, next func(context.Context) error) error
+
+type contextKey int
+
+const (
+	probingKey contextKey = iota
+)
+
+// IsProbing reports whether the predicate is being evaluated by Test (and thus by Not, Any, and Require) rather than followed by actual handlers.
+// Predicates that have side effects (e.g. recording what they see) should not do so while probing.
+func IsProbing(ctx context.Context) bool {
+	probing, _ := ctx.Value(probingKey).(bool)
+	return probing
+}
+
+// Test reports whether the predicate is considered to be "true" without calling any actual handlers.
+// It also returns the context that the predicate would pass to the inner handler.
+func Test(ctx context.Context, p Probe) (bool, context.Context, error) {
+	matched := false
+	matchedCtx := ctx
+	err := p(context.WithValue(ctx, probingKey, true), func(innerCtx context.Context) error {
+		matched = true
+		// The context is passed to actual handlers, which are not probing.
+		matchedCtx = context.WithValue(innerCtx, probingKey, false)
+		return nil
+	})
+	if err != nil && !errors.Is(err, routererrors.NotInterested) {
+		return false, ctx, err
+	}
+	return matched, matchedCtx, nil
+}
+
+// Not calls next if and only if the predicate is considered to be "false".
+//
+// If the predicate returns an error other than `errors.NotInterested`, the error is returned as is.
+func Not(ctx context.Context, p Probe, next func(context.Context) error) error {
+	ok, _, err := Test(ctx, p)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return routererrors.NotInterested
+	}
+	return next(ctx)
+}
+
+// Any calls next exactly once if at least one of the predicates is considered to be "true".
+//
+// The predicates are evaluated in order, and the evaluation stops at the first one that is considered to be "true".
+// next is called with the context that the predicate would pass to the inner handler.
+// If one of the predicates returns an error other than `errors.NotInterested`, the evaluation stops and the error is returned as is.
+func Any(ctx context.Context, ps []Probe, next func(context.Context) error) error {
+	for _, p := range ps {
+		ok, matchedCtx, err := Test(ctx, p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return next(matchedCtx)
+		}
+	}
+	return routererrors.NotInterested
+}
+
+// Require calls next if the predicate is considered to be "true", and returns onFail otherwise.
+//
+// If the predicate returns an error other than `errors.NotInterested`, the error is returned as is.
+func Require(ctx context.Context, p Probe, onFail error, next func(context.Context) error) error {
+	ok, matchedCtx, err := Test(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return onFail
+	}
+	return next(matchedCtx)
+}
@@ -0,0 +1,131 @@
+package predicates_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/predicates"
+)
+
+type contextKey int
+
+const valueKey contextKey = iota
+
+var _ = Describe("Predicates", func() {
+	var (
+		ctx         context.Context
+		numCalled   int
+		receivedCtx context.Context
+		next        = func(ctx context.Context) error {
+			numCalled++
+			receivedCtx = ctx
+			return nil
+		}
+		truthy = func(value string) predicates.Probe {
+			return func(ctx context.Context, next func(context.Context) error) error {
+				return next(context.WithValue(ctx, valueKey, value))
+			}
+		}
+		falsy predicates.Probe = func(_ context.Context, _ func(context.Context) error) error {
+			return routererrors.NotInterested
+		}
+		failing predicates.Probe = func(_ context.Context, _ func(context.Context) error) error {
+			return errors.New("something wrong happened")
+		}
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		numCalled = 0
+		receivedCtx = nil
+	})
+
+	Describe("Test", func() {
+		It("reports whether the predicate is true with the context it would pass", func() {
+			ok, matchedCtx, err := predicates.Test(ctx, truthy("a"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(matchedCtx.Value(valueKey)).To(Equal("a"))
+
+			ok, _, err = predicates.Test(ctx, falsy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("marks the context as probing only while the predicate is evaluated", func() {
+			var probing bool
+			p := func(ctx context.Context, next func(context.Context) error) error {
+				probing = predicates.IsProbing(ctx)
+				return next(ctx)
+			}
+			ok, matchedCtx, err := predicates.Test(ctx, p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(probing).To(BeTrue())
+			Expect(predicates.IsProbing(matchedCtx)).To(BeFalse())
+			Expect(predicates.IsProbing(ctx)).To(BeFalse())
+		})
+
+		It("returns errors other than NotInterested", func() {
+			ok, _, err := predicates.Test(ctx, failing)
+			Expect(err).To(MatchError("something wrong happened"))
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Not", func() {
+		It("calls next if and only if the predicate is false", func() {
+			Expect(predicates.Not(ctx, falsy, next)).To(Succeed())
+			Expect(numCalled).To(Equal(1))
+			Expect(receivedCtx).To(Equal(ctx))
+
+			Expect(predicates.Not(ctx, truthy("a"), next)).To(Equal(routererrors.NotInterested))
+			Expect(numCalled).To(Equal(1))
+		})
+
+		It("returns errors other than NotInterested as is", func() {
+			Expect(predicates.Not(ctx, failing, next)).To(MatchError("something wrong happened"))
+			Expect(numCalled).To(Equal(0))
+		})
+	})
+
+	Describe("Any", func() {
+		It("calls next exactly once with the context of the first true predicate", func() {
+			err := predicates.Any(ctx, []predicates.Probe{falsy, truthy("a"), truthy("b")}, next)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numCalled).To(Equal(1))
+			Expect(receivedCtx.Value(valueKey)).To(Equal("a"))
+		})
+
+		It("returns NotInterested if all predicates are false", func() {
+			err := predicates.Any(ctx, []predicates.Probe{falsy, falsy}, next)
+			Expect(err).To(Equal(routererrors.NotInterested))
+			Expect(numCalled).To(Equal(0))
+		})
+
+		It("stops at the first error other than NotInterested", func() {
+			err := predicates.Any(ctx, []predicates.Probe{failing, truthy("a")}, next)
+			Expect(err).To(MatchError("something wrong happened"))
+			Expect(numCalled).To(Equal(0))
+		})
+	})
+
+	Describe("Require", func() {
+		It("calls next if the predicate is true, and returns onFail otherwise", func() {
+			Expect(predicates.Require(ctx, truthy("a"), routererrors.ErrForbidden, next)).To(Succeed())
+			Expect(numCalled).To(Equal(1))
+			Expect(receivedCtx.Value(valueKey)).To(Equal("a"))
+
+			Expect(predicates.Require(ctx, falsy, routererrors.ErrForbidden, next)).To(Equal(routererrors.ErrForbidden))
+			Expect(numCalled).To(Equal(1))
+		})
+
+		It("returns errors other than NotInterested as is", func() {
+			Expect(predicates.Require(ctx, failing, routererrors.ErrForbidden, next)).To(MatchError("something wrong happened"))
+			Expect(numCalled).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,63 @@
+// Package ratelimit implements rate limiting per key, e.g. per team or per user.
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter limits the rate of events for each key with a token bucket.
+//
+// It keeps the buckets of at most maxKeys keys, evicting the least recently used one when a new key comes.
+// An evicted key gets a full bucket when it comes again, so maxKeys should be larger than the number of keys active at the same time.
+type Limiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	maxKeys int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type entry struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+// New creates a new Limiter that allows events of each key up to limit per second with bursts of at most burst events.
+func New(limit rate.Limit, burst, maxKeys int) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		burst:   burst,
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Allow reports whether an event of the key may happen now, consuming a token of its bucket if so.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[key]; ok {
+		l.lru.MoveToFront(el)
+		return el.Value.(*entry).limiter.Allow()
+	}
+	if l.lru.Len() >= l.maxKeys {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.entries, oldest.Value.(*entry).key)
+	}
+	e := &entry{key: key, limiter: rate.NewLimiter(l.limit, l.burst)}
+	l.entries[key] = l.lru.PushFront(e)
+	return e.limiter.Allow()
+}
+
+// Len returns the number of keys whose buckets are kept.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
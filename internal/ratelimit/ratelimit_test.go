@@ -0,0 +1,34 @@
+package ratelimit_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+
+	"github.com/genkami/go-slack-event-router/internal/ratelimit"
+)
+
+var _ = Describe("Limiter", func() {
+	Describe("Allow", func() {
+		It("allows events up to the burst for each key", func() {
+			l := ratelimit.New(rate.Limit(0.001), 2, 10)
+			Expect(l.Allow("T1")).To(BeTrue())
+			Expect(l.Allow("T1")).To(BeTrue())
+			Expect(l.Allow("T1")).To(BeFalse())
+			Expect(l.Allow("T2")).To(BeTrue())
+		})
+
+		It("keeps at most maxKeys keys, evicting the least recently used one", func() {
+			l := ratelimit.New(rate.Limit(0.001), 1, 2)
+			Expect(l.Allow("T1")).To(BeTrue())
+			Expect(l.Allow("T2")).To(BeTrue())
+			Expect(l.Allow("T1")).To(BeFalse())
+			Expect(l.Allow("T3")).To(BeTrue())
+			Expect(l.Len()).To(Equal(2))
+			// T2 has been evicted since T1 was used more recently, so it gets a full bucket again.
+			Expect(l.Allow("T2")).To(BeTrue())
+			// T1 has been evicted in turn.
+			Expect(l.Allow("T1")).To(BeTrue())
+		})
+	})
+})
@@ -1,20 +1,256 @@
 package routerutils
 
 import (
-	"errors"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 
 	routererrors "github.com/genkami/go-slack-event-router/errors"
 )
 
 func RespondWithError(w http.ResponseWriter, err error, verboseResponse bool) {
-	var httpErr routererrors.HttpError
-	if errors.As(err, &httpErr) {
-		w.WriteHeader(int(httpErr))
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
-	}
+	w.WriteHeader(routererrors.HTTPStatus(err))
 	if verboseResponse {
 		_, _ = w.Write([]byte(err.Error()))
 	}
 }
+
+type contextKey int
+
+const (
+	innerEventKey contextKey = iota
+)
+
+// WithInnerEvent returns a context that carries the raw JSON of the inner event being processed,
+// so that predicates can access fields that slackevents does not decode.
+func WithInnerEvent(ctx context.Context, raw json.RawMessage) context.Context {
+	return context.WithValue(ctx, innerEventKey, raw)
+}
+
+// InnerEvent returns the raw JSON of the inner event set by WithInnerEvent, or nil if it is not set.
+func InnerEvent(ctx context.Context) json.RawMessage {
+	raw, _ := ctx.Value(innerEventKey).(json.RawMessage)
+	return raw
+}
+
+// RecoverPanic recovers from a panic and stores it to *errp as an error.
+// It must be called directly by a deferred function, i.e. `defer routerutils.RecoverPanic(&err)`.
+func RecoverPanic(errp *error) {
+	if rec := recover(); rec != nil {
+		*errp = errors.Errorf("panic in handler: %v", rec)
+	}
+}
+
+// IsForm reports whether req has a form body, ignoring parameters of its Content-Type such as `charset=utf-8`.
+func IsForm(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+// DefaultMaxBodyBytes is the default limit of the size of request bodies, which is large enough for any requests from Slack.
+const DefaultMaxBodyBytes = 1 << 20
+
+// LimitBody limits the size of the body of req to n bytes.
+// Reading more than n bytes from the body results in an error that equals to `routererrors.ErrPayloadTooLarge` in the sense of `errors.Is`.
+func LimitBody(req *http.Request, n int64) {
+	if req.Body == nil {
+		return
+	}
+	req.Body = &limitedBody{body: req.Body, remaining: n}
+}
+
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errors.WithMessage(routererrors.ErrPayloadTooLarge, "request body too large")
+	}
+	// Read one more byte than the limit to find out whether the body exceeds it.
+	// Note that b.remaining+1 overflows if the limit is math.MaxInt64.
+	if int64(len(p))-1 > b.remaining {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), errors.WithMessage(routererrors.ErrPayloadTooLarge, "request body too large")
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// maxPooledBufferSize is the maximum capacity of buffers that are returned to the pool,
+// so that a few large requests do not make the pool retain large amount of memory.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// ReadBody reads the entire body of req and replaces the body with an in-memory one so that it can be read again.
+//
+// If the body has already been read by ReadBody, it returns the same bytes without copying them.
+// The returned bytes are backed by a pooled buffer, so they must not be used after ReleaseBody is called.
+func ReadBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	if b, ok := req.Body.(*bufferedBody); ok {
+		if b.buf == nil {
+			return nil, errors.New("the body has already been released")
+		}
+		return b.buf.Bytes(), nil
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if _, err := buf.ReadFrom(req.Body); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	req.Body = &bufferedBody{Reader: bytes.NewReader(buf.Bytes()), buf: buf}
+	return buf.Bytes(), nil
+}
+
+// IsBodyBuffered reports whether the body of req has been read by ReadBody, i.e. it can be read again.
+func IsBodyBuffered(req *http.Request) bool {
+	_, ok := req.Body.(*bufferedBody)
+	return ok
+}
+
+// ReleaseBody returns the buffer allocated by ReadBody to the pool.
+// It does nothing if the body has not been read by ReadBody.
+func ReleaseBody(req *http.Request) {
+	b, ok := req.Body.(*bufferedBody)
+	if !ok || b.buf == nil {
+		return
+	}
+	putBuffer(b.buf)
+	b.buf = nil
+	b.Reader = bytes.NewReader(nil)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+type bufferedBody struct {
+	*bytes.Reader
+	buf *bytes.Buffer
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// HeaderRequestID is the header that carries request IDs.
+const HeaderRequestID = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID returns the request ID given by the X-Request-ID header of req, e.g. set by a load balancer.
+// If the header is missing or malformed, it generates a new one.
+func RequestID(req *http.Request) string {
+	if id := req.Header.Get(HeaderRequestID); validRequestID.MatchString(id) {
+		return id
+	}
+	return NewRequestID()
+}
+
+// NewRequestID generates a new random request ID.
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// This should never happen, but request IDs do not have to be cryptographically secure anyway.
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Registry guards the registration of handlers to a Router, which must complete before the Router starts serving.
+//
+// Routers call Register in every method that modifies their handlers, and Freeze when they start serving.
+// Once frozen, handlers are never modified, so they can be read without locks.
+type Registry struct {
+	mu     sync.Mutex
+	once   sync.Once
+	frozen bool
+}
+
+// Register calls f, which modifies handlers, unless the Registry is frozen.
+// It panics with a message starting with method if the Registry is frozen.
+//
+// f must not call Register of the same Registry.
+func (r *Registry) Register(method string, f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		panic(method + ": handlers must be registered before the Router starts serving")
+	}
+	f()
+}
+
+// Freeze prevents further registration. It is safe to call Freeze more than once.
+//
+// Any registration made before Freeze returns happens before the return of Freeze.
+func (r *Registry) Freeze() {
+	r.once.Do(func() {
+		r.mu.Lock()
+		r.frozen = true
+		r.mu.Unlock()
+	})
+}
+
+// ParseTimestamp parses Slack's timestamps like `1581106241.371594`, which consist of seconds and microseconds since the Unix epoch.
+//
+// The fractional part may be omitted, and digits beyond nanoseconds are ignored.
+func ParseTimestamp(ts string) (time.Time, error) {
+	if ts == "" {
+		return time.Time{}, errors.New("empty timestamp")
+	}
+	strSec, strFrac := ts, ""
+	if i := strings.IndexByte(ts, '.'); i >= 0 {
+		strSec, strFrac = ts[:i], ts[i+1:]
+	}
+	sec, err := strconv.ParseInt(strSec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed timestamp %q: %w", ts, err)
+	}
+	var nsec int64
+	for i, c := range strFrac {
+		if c < '0' || '9' < c {
+			return time.Time{}, fmt.Errorf("malformed timestamp %q", ts)
+		}
+		// Ignore digits beyond nanoseconds.
+		if i < 9 {
+			nsec = nsec*10 + int64(c-'0')
+		}
+	}
+	for i := len(strFrac); i < 9; i++ {
+		nsec *= 10
+	}
+	return time.Unix(sec, nsec), nil
+}
@@ -0,0 +1,161 @@
+// Package items provides handlers to process events about pinned and starred items,
+// i.e. `pin_added`, `pin_removed`, `star_added`, and `star_removed` events.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/pin_added
+//   - https://api.slack.com/events/pin_removed
+//   - https://api.slack.com/events/star_added
+//   - https://api.slack.com/events/star_removed
+package items
+
+import (
+	"context"
+
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// Types of events that this package processes.
+const (
+	PinAdded    = "pin_added"
+	PinRemoved  = "pin_removed"
+	StarAdded   = "star_added"
+	StarRemoved = "star_removed"
+)
+
+// Event is a `pin_added`, `pin_removed`, `star_added`, or `star_removed` event.
+//
+// `slack-go/slack` has different types for these events (and none for `star_*` events in the Events API),
+// so this package has its own type.
+type Event struct {
+	// Type is one of PinAdded, PinRemoved, StarAdded, and StarRemoved.
+	Type string `json:"type"`
+
+	// User is the user who pinned, unpinned, starred, or unstarred the item.
+	User string `json:"user"`
+
+	// ChannelID is the channel that the item is pinned to or unpinned from. It is empty for `star_*` events.
+	ChannelID string `json:"channel_id"`
+
+	Item slack.Item `json:"item"`
+
+	// HasPins reports whether the channel still has pinned items. It is set only for `pin_removed` events.
+	HasPins bool `json:"has_pins"`
+
+	EventTimestamp string `json:"event_ts"`
+}
+
+// Channel returns the channel of the event, i.e. ChannelID for `pin_*` events, or the channel of the item for `star_*` events.
+// It returns an empty string if the item is not in any channels, e.g. a starred file.
+func (e *Event) Channel() string {
+	if e.ChannelID != "" {
+		return e.ChannelID
+	}
+	return e.Item.Channel
+}
+
+// Handler processes `pin_added`, `pin_removed`, `star_added`, and `star_removed` events.
+type Handler interface {
+	HandleItemEvent(context.Context, *Event) error
+}
+
+type HandlerFunc func(context.Context, *Event) error
+
+func (f HandlerFunc) HandleItemEvent(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+type Predicate interface {
+	Wrap(Handler) Handler
+}
+
+type inChannelPredicate struct {
+	ids []string
+}
+
+// InChannel is a predicate that is considered to be "true" if and only if an item is pinned (or starred) in one of the given channels.
+//
+// See Event.Channel for how the channel of an event is determined. Events without channels are never considered to be "true".
+//
+// It panics if no channel ID is given.
+func InChannel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("items.InChannel: at least one channel ID must be given")
+	}
+	return &inChannelPredicate{ids: ids}
+}
+
+func (p *inChannelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		ch := e.Channel()
+		if ch == "" || !contains(p.ids, ch) {
+			return routererrors.NotInterested
+		}
+		return h.HandleItemEvent(ctx, e)
+	})
+}
+
+type itemTypePredicate struct {
+	types []string
+}
+
+// ItemType is a predicate that is considered to be "true" if and only if the type of the item equals to any of the given ones (e.g. `message` or `file`).
+//
+// It panics if no item type is given.
+func ItemType(types ...string) Predicate {
+	if len(types) == 0 {
+		panic("items.ItemType: at least one item type must be given")
+	}
+	return &itemTypePredicate{types: types}
+}
+
+func (p *itemTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if !contains(p.types, e.Item.Type) {
+			return routererrors.NotInterested
+		}
+		return h.HandleItemEvent(ctx, e)
+	})
+}
+
+type fromUserPredicate struct {
+	ids []string
+}
+
+// FromUser is a predicate that is considered to be "true" if and only if an item is pinned, unpinned, starred, or unstarred by one of the given users.
+//
+// It panics if no user ID is given.
+func FromUser(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("items.FromUser: at least one user ID must be given")
+	}
+	return &fromUserPredicate{ids: ids}
+}
+
+func (p *fromUserPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *Event) error {
+		if !contains(p.ids, e.User) {
+			return routererrors.NotInterested
+		}
+		return h.HandleItemEvent(ctx, e)
+	})
+}
+
+// Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func Build(h Handler, preds ...Predicate) Handler {
+	for _, p := range preds {
+		h = p.Wrap(h)
+	}
+	return h
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
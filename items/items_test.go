@@ -0,0 +1,143 @@
+package items_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/items"
+)
+
+var _ = Describe("Items", func() {
+	var (
+		numHandlerCalled int
+		innerHandler     = items.HandlerFunc(func(_ context.Context, _ *items.Event) error {
+			numHandlerCalled++
+			return nil
+		})
+		ctx context.Context
+	)
+	BeforeEach(func() {
+		numHandlerCalled = 0
+		ctx = context.Background()
+	})
+
+	Describe("Build", func() {
+		Context("when no predicate is given", func() {
+			It("returns the original handler", func() {
+				h := items.Build(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when one of the predicates does not match", func() {
+			It("does not call the inner handler", func() {
+				h := items.Build(innerHandler, items.InChannel("C12345"), items.ItemType("file"))
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, ChannelID: "C12345", Item: slack.Item{Type: "message"}})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("InChannel", func() {
+		Context("when the item is pinned in one of the given channels", func() {
+			It("calls the inner handler", func() {
+				h := items.InChannel("C12345", "C67890").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, ChannelID: "C67890"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the item is pinned in another channel", func() {
+			It("does not call the inner handler", func() {
+				h := items.InChannel("C12345").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, ChannelID: "C67890"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when a message in one of the given channels is starred", func() {
+			It("calls the inner handler", func() {
+				h := items.InChannel("C12345").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.StarAdded, Item: slack.Item{Type: "message", Channel: "C12345"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the event has no channel", func() {
+			It("does not call the inner handler", func() {
+				h := items.InChannel("C12345").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.StarAdded, Item: slack.Item{Type: "file"}})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel ID is given", func() {
+			It("panics", func() {
+				Expect(func() { items.InChannel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ItemType", func() {
+		Context("when the type of the item is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := items.ItemType("message", "file").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, Item: slack.Item{Type: "message"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the type of the item differs", func() {
+			It("does not call the inner handler", func() {
+				h := items.ItemType("message").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, Item: slack.Item{Type: "file"}})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no item type is given", func() {
+			It("panics", func() {
+				Expect(func() { items.ItemType() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromUser", func() {
+		Context("when the item is pinned by one of the given users", func() {
+			It("calls the inner handler", func() {
+				h := items.FromUser("U12345", "U67890").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, User: "U67890"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the item is pinned by another user", func() {
+			It("does not call the inner handler", func() {
+				h := items.FromUser("U12345").Wrap(innerHandler)
+				err := h.HandleItemEvent(ctx, &items.Event{Type: items.PinAdded, User: "U67890"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no user ID is given", func() {
+			It("panics", func() {
+				Expect(func() { items.FromUser() }).To(Panic())
+			})
+		})
+	})
+})
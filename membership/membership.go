@@ -0,0 +1,175 @@
+// Package membership provides handlers to process `team_join` and `member_joined_channel` events.
+//
+// For more details, see the following pages:
+//   - https://api.slack.com/events/team_join
+//   - https://api.slack.com/events/member_joined_channel
+package membership
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+)
+
+// TeamJoinHandler processes `team_join` events.
+type TeamJoinHandler interface {
+	HandleTeamJoinEvent(context.Context, *slack.TeamJoinEvent) error
+}
+
+type TeamJoinHandlerFunc func(context.Context, *slack.TeamJoinEvent) error
+
+func (f TeamJoinHandlerFunc) HandleTeamJoinEvent(ctx context.Context, e *slack.TeamJoinEvent) error {
+	return f(ctx, e)
+}
+
+// ChannelJoinHandler processes `member_joined_channel` events.
+type ChannelJoinHandler interface {
+	HandleChannelJoinEvent(context.Context, *slackevents.MemberJoinedChannelEvent) error
+}
+
+type ChannelJoinHandlerFunc func(context.Context, *slackevents.MemberJoinedChannelEvent) error
+
+func (f ChannelJoinHandlerFunc) HandleChannelJoinEvent(ctx context.Context, e *slackevents.MemberJoinedChannelEvent) error {
+	return f(ctx, e)
+}
+
+// Predicate disthinguishes whether or not a certain handler should process coming events.
+// This can be used with both `TeamJoinHandler` and `ChannelJoinHandler`.
+type Predicate interface {
+	WrapTeamJoin(TeamJoinHandler) TeamJoinHandler
+	WrapChannelJoin(ChannelJoinHandler) ChannelJoinHandler
+}
+
+type inChannelPredicate struct {
+	ids []string
+}
+
+// InChannel is a predicate that is considered to be "true" if and only if a member joined one of the given channels.
+//
+// `team_join` events have no channels, so they are never considered to be "true".
+//
+// It panics if no channel ID is given.
+func InChannel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("membership.InChannel: at least one channel ID must be given")
+	}
+	return &inChannelPredicate{ids: ids}
+}
+
+func (p *inChannelPredicate) WrapTeamJoin(h TeamJoinHandler) TeamJoinHandler {
+	return TeamJoinHandlerFunc(func(_ context.Context, _ *slack.TeamJoinEvent) error {
+		return routererrors.NotInterested
+	})
+}
+
+func (p *inChannelPredicate) WrapChannelJoin(h ChannelJoinHandler) ChannelJoinHandler {
+	return ChannelJoinHandlerFunc(func(ctx context.Context, e *slackevents.MemberJoinedChannelEvent) error {
+		if !contains(p.ids, e.Channel) {
+			return routererrors.NotInterested
+		}
+		return h.HandleChannelJoinEvent(ctx, e)
+	})
+}
+
+type userIDPredicate struct {
+	ids []string
+}
+
+// UserID is a predicate that is considered to be "true" if and only if the member who joined is one of the given users.
+//
+// It panics if no user ID is given.
+func UserID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("membership.UserID: at least one user ID must be given")
+	}
+	return &userIDPredicate{ids: ids}
+}
+
+func (p *userIDPredicate) WrapTeamJoin(h TeamJoinHandler) TeamJoinHandler {
+	return TeamJoinHandlerFunc(func(ctx context.Context, e *slack.TeamJoinEvent) error {
+		if !contains(p.ids, e.User.ID) {
+			return routererrors.NotInterested
+		}
+		return h.HandleTeamJoinEvent(ctx, e)
+	})
+}
+
+func (p *userIDPredicate) WrapChannelJoin(h ChannelJoinHandler) ChannelJoinHandler {
+	return ChannelJoinHandlerFunc(func(ctx context.Context, e *slackevents.MemberJoinedChannelEvent) error {
+		if !contains(p.ids, e.User) {
+			return routererrors.NotInterested
+		}
+		return h.HandleChannelJoinEvent(ctx, e)
+	})
+}
+
+// BuildTeamJoin decorates `TeamJoinHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func BuildTeamJoin(h TeamJoinHandler, preds ...Predicate) TeamJoinHandler {
+	for _, p := range preds {
+		h = p.WrapTeamJoin(h)
+	}
+	return h
+}
+
+// BuildChannelJoin decorates `ChannelJoinHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
+func BuildChannelJoin(h ChannelJoinHandler, preds ...Predicate) ChannelJoinHandler {
+	for _, p := range preds {
+		h = p.WrapChannelJoin(h)
+	}
+	return h
+}
+
+// MessagePoster is a client that posts messages. `*slack.Client` implements this.
+type MessagePoster interface {
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+}
+
+// ErrNoClient is returned from SendDirectMessage when no client is given.
+var ErrNoClient = stderrors.New("no client is given to the Router")
+
+type contextKey int
+
+const (
+	clientKey contextKey = iota
+)
+
+// WithClient returns a copy of ctx that carries client, which SendDirectMessage uses to post messages.
+//
+// `eventrouter.Router.OnTeamJoin` and `eventrouter.Router.OnMemberJoinedChannel` set this automatically if `eventrouter.WithClient` is given,
+// so this is needed only when handlers are called without the Router, e.g. in tests.
+func WithClient(ctx context.Context, client MessagePoster) context.Context {
+	return context.WithValue(ctx, clientKey, client)
+}
+
+// SendDirectMessage sends a direct message to the user, e.g. to welcome a new member.
+//
+//	r.OnTeamJoin(membership.TeamJoinHandlerFunc(func(ctx context.Context, e *slack.TeamJoinEvent) error {
+//		return membership.SendDirectMessage(ctx, e.User.ID, slack.MsgOptionText("Welcome!", false))
+//	}))
+//
+// It returns ErrNoClient if no client is given (see WithClient).
+func SendDirectMessage(ctx context.Context, userID string, options ...slack.MsgOption) error {
+	client, ok := ctx.Value(clientKey).(MessagePoster)
+	if !ok || client == nil {
+		return ErrNoClient
+	}
+	// Posting a message to a user ID sends it to the direct message channel with the user.
+	if _, _, err := client.PostMessageContext(ctx, userID, options...); err != nil {
+		return errors.WithMessagef(err, "failed to send a direct message to %s", userID)
+	}
+	return nil
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
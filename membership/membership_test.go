@@ -0,0 +1,169 @@
+package membership_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/membership"
+)
+
+type stubPoster struct {
+	channelID string
+	err       error
+}
+
+func (p *stubPoster) PostMessageContext(_ context.Context, channelID string, _ ...slack.MsgOption) (string, string, error) {
+	p.channelID = channelID
+	return channelID, "1234567890.123456", p.err
+}
+
+var _ = Describe("Membership", func() {
+	var (
+		numTeamJoinCalled    int
+		numChannelJoinCalled int
+		teamJoinHandler      = membership.TeamJoinHandlerFunc(func(_ context.Context, _ *slack.TeamJoinEvent) error {
+			numTeamJoinCalled++
+			return nil
+		})
+		channelJoinHandler = membership.ChannelJoinHandlerFunc(func(_ context.Context, _ *slackevents.MemberJoinedChannelEvent) error {
+			numChannelJoinCalled++
+			return nil
+		})
+		ctx context.Context
+	)
+	BeforeEach(func() {
+		numTeamJoinCalled = 0
+		numChannelJoinCalled = 0
+		ctx = context.Background()
+	})
+
+	Describe("InChannel", func() {
+		Context("when a member joined one of the given channels", func() {
+			It("calls the inner handler", func() {
+				h := membership.InChannel("C12345", "C67890").WrapChannelJoin(channelJoinHandler)
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U12345", Channel: "C67890"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numChannelJoinCalled).To(Equal(1))
+			})
+		})
+
+		Context("when a member joined another channel", func() {
+			It("does not call the inner handler", func() {
+				h := membership.InChannel("C12345").WrapChannelJoin(channelJoinHandler)
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U12345", Channel: "C67890"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numChannelJoinCalled).To(Equal(0))
+			})
+		})
+
+		Context("when a member joined the team", func() {
+			It("does not call the inner handler", func() {
+				h := membership.InChannel("C12345").WrapTeamJoin(teamJoinHandler)
+				err := h.HandleTeamJoinEvent(ctx, &slack.TeamJoinEvent{User: slack.User{ID: "U12345"}})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numTeamJoinCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel ID is given", func() {
+			It("panics", func() {
+				Expect(func() { membership.InChannel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("UserID", func() {
+		Context("when one of the given users joined the team", func() {
+			It("calls the inner handler", func() {
+				h := membership.UserID("U12345", "U67890").WrapTeamJoin(teamJoinHandler)
+				err := h.HandleTeamJoinEvent(ctx, &slack.TeamJoinEvent{User: slack.User{ID: "U67890"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numTeamJoinCalled).To(Equal(1))
+			})
+		})
+
+		Context("when another user joined the team", func() {
+			It("does not call the inner handler", func() {
+				h := membership.UserID("U12345").WrapTeamJoin(teamJoinHandler)
+				err := h.HandleTeamJoinEvent(ctx, &slack.TeamJoinEvent{User: slack.User{ID: "U67890"}})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numTeamJoinCalled).To(Equal(0))
+			})
+		})
+
+		Context("when one of the given users joined a channel", func() {
+			It("calls the inner handler", func() {
+				h := membership.UserID("U12345").WrapChannelJoin(channelJoinHandler)
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U12345", Channel: "C12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numChannelJoinCalled).To(Equal(1))
+			})
+		})
+
+		Context("when another user joined a channel", func() {
+			It("does not call the inner handler", func() {
+				h := membership.UserID("U12345").WrapChannelJoin(channelJoinHandler)
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U67890", Channel: "C12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numChannelJoinCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no user ID is given", func() {
+			It("panics", func() {
+				Expect(func() { membership.UserID() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("BuildChannelJoin", func() {
+		Context("when all predicates are true", func() {
+			It("calls the inner handler", func() {
+				h := membership.BuildChannelJoin(channelJoinHandler, membership.InChannel("C12345"), membership.UserID("U12345"))
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U12345", Channel: "C12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numChannelJoinCalled).To(Equal(1))
+			})
+		})
+
+		Context("when any of the predicates is false", func() {
+			It("does not call the inner handler", func() {
+				h := membership.BuildChannelJoin(channelJoinHandler, membership.InChannel("C12345"), membership.UserID("U67890"))
+				err := h.HandleChannelJoinEvent(ctx, &slackevents.MemberJoinedChannelEvent{User: "U12345", Channel: "C12345"})
+				Expect(err).To(Equal(routererrors.NotInterested))
+				Expect(numChannelJoinCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("SendDirectMessage", func() {
+		It("posts a message to the user", func() {
+			client := &stubPoster{}
+			err := membership.SendDirectMessage(membership.WithClient(ctx, client), "U12345", slack.MsgOptionText("Welcome!", false))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.channelID).To(Equal("U12345"))
+		})
+
+		Context("when the client fails", func() {
+			It("returns the error", func() {
+				e := errors.New("failed to post")
+				client := &stubPoster{err: e}
+				err := membership.SendDirectMessage(membership.WithClient(ctx, client), "U12345")
+				Expect(errors.Is(err, e)).To(BeTrue())
+			})
+		})
+
+		Context("when no client is given", func() {
+			It("returns ErrNoClient", func() {
+				err := membership.SendDirectMessage(ctx, "U12345")
+				Expect(err).To(Equal(membership.ErrNoClient))
+			})
+		})
+	})
+})
@@ -5,11 +5,22 @@ package message
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/predicates"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
 )
 
 // Handler processes `message` events.
@@ -33,58 +44,1315 @@ type textRegexpPredicate struct {
 }
 
 // TextRegexp is a predicate that is considered to be "true" if and only if a text of a message matches to the given regexp.
+//
+// The submatches of the regexp are passed to the inner handler through the context. They can be retrieved with Submatches and NamedSubmatches.
+//
+// Note that edited messages (see IsEdit) have an empty text. Use EditedText to get the text after the edit.
 func TextRegexp(re *regexp.Regexp) Predicate {
 	return &textRegexpPredicate{re: re}
 }
 
-func (p *textRegexpPredicate) Wrap(h Handler) Handler {
+// TextRegexpString is the same as TextRegexp, except that it takes a pattern instead of a compiled regexp.
+//
+// Patterns are compiled with CompileRegexp, so handlers with the same pattern share a compiled regexp.
+//
+// It panics if the pattern is invalid.
+func TextRegexpString(pattern string) Predicate {
+	re, err := CompileRegexp(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("message.TextRegexpString: %v", err))
+	}
+	return TextRegexp(re)
+}
+
+var regexpCache sync.Map
+
+// CompileRegexp compiles the pattern in the same way as `regexp.Compile`, except that compiled regexps are cached by their patterns.
+// It returns the cached one if the same pattern has been compiled before.
+//
+// The cache is never evicted, so it grows without bound if patterns are generated dynamically (e.g. from user inputs).
+// Use `regexp.Compile` and TextRegexp for such patterns, or call ClearRegexpCache periodically.
+func CompileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	// Another goroutine may have compiled the same pattern in the meantime; use the first one.
+	actual, _ := regexpCache.LoadOrStore(pattern, re)
+	return actual.(*regexp.Regexp), nil
+}
+
+// ClearRegexpCache removes all regexps cached by CompileRegexp. Predicates that have already been created keep their regexps.
+func ClearRegexpCache() {
+	regexpCache.Range(func(key, _ interface{}) bool {
+		regexpCache.Delete(key)
+		return true
+	})
+}
+
+func (p *textRegexpPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		submatches := p.re.FindStringSubmatch(e.Text)
+		if submatches == nil {
+			return errors.NotInterested
+		}
+		ctx = context.WithValue(ctx, submatchesKey, &regexpMatch{re: p.re, submatches: submatches})
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type contextKey int
+
+const (
+	submatchesKey contextKey = iota
+	teamIDKey
+	replierKey
+	invocationKey
+)
+
+type regexpMatch struct {
+	re         *regexp.Regexp
+	submatches []string
+}
+
+// Submatches returns the submatches of the regexp given to TextRegexp, in the same form as `regexp.Regexp.FindStringSubmatch`.
+//
+// If more than one TextRegexp predicates are given, the one closest to the handler takes precedence.
+// If no TextRegexp predicate has matched, it returns nil.
+func Submatches(ctx context.Context) []string {
+	m, ok := ctx.Value(submatchesKey).(*regexpMatch)
+	if !ok {
+		return nil
+	}
+	return m.submatches
+}
+
+// NamedSubmatches returns a map from the names of the named capturing groups in the regexp given to TextRegexp to the corresponding submatches.
+//
+// If more than one TextRegexp predicates are given, the one closest to the handler takes precedence.
+// If no TextRegexp predicate has matched, it returns nil.
+func NamedSubmatches(ctx context.Context) map[string]string {
+	m, ok := ctx.Value(submatchesKey).(*regexpMatch)
+	if !ok {
+		return nil
+	}
+	named := make(map[string]string)
+	for i, name := range m.re.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		named[name] = m.submatches[i]
+	}
+	return named
+}
+
+type textContainsPredicate struct {
+	substrs []string
+	fold    bool
+}
+
+// TextContains is a predicate that is considered to be "true" if and only if a text of a message contains at least one of the given substrings.
+//
+// Unlike TextRegexp, the substrings are treated literally, so there is no need to escape regexp metacharacters.
+//
+// It panics if no substring is given.
+func TextContains(substrs ...string) Predicate {
+	if len(substrs) == 0 {
+		panic("message.TextContains: at least one substring must be given")
+	}
+	return &textContainsPredicate{substrs: substrs}
+}
+
+// TextContainsFold is a case-insensitive version of TextContains.
+//
+// It panics if no substring is given.
+func TextContainsFold(substrs ...string) Predicate {
+	if len(substrs) == 0 {
+		panic("message.TextContainsFold: at least one substring must be given")
+	}
+	lowered := make([]string, 0, len(substrs))
+	for _, s := range substrs {
+		lowered = append(lowered, strings.ToLower(s))
+	}
+	return &textContainsPredicate{substrs: lowered, fold: true}
+}
+
+func (p *textContainsPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		text := e.Text
+		if p.fold {
+			text = strings.ToLower(text)
+		}
+		for _, s := range p.substrs {
+			if strings.Contains(text, s) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+type textMatchPredicate struct {
+	values []string
+	fold   bool
+	match  func(text, value string) bool
+}
+
+func newTextMatchPredicate(values []string, fold bool, match func(text, value string) bool) *textMatchPredicate {
+	if fold {
+		lowered := make([]string, 0, len(values))
+		for _, v := range values {
+			lowered = append(lowered, strings.ToLower(v))
+		}
+		values = lowered
+	}
+	return &textMatchPredicate{values: values, fold: fold, match: match}
+}
+
+// TextEquals is a predicate that is considered to be "true" if and only if a text of a message is equal to the given string.
+//
+// The text is compared as it is. Leading and trailing whitespaces are not trimmed.
+func TextEquals(s string) Predicate {
+	return newTextMatchPredicate([]string{s}, false, func(text, value string) bool { return text == value })
+}
+
+// TextEqualsFold is a case-insensitive version of TextEquals.
+func TextEqualsFold(s string) Predicate {
+	return newTextMatchPredicate([]string{s}, true, func(text, value string) bool { return text == value })
+}
+
+// TextPrefix is a predicate that is considered to be "true" if and only if a text of a message starts with at least one of the given prefixes.
+//
+// As with TextContains, the prefixes are treated literally. Leading whitespaces in the text are not trimmed.
+//
+// It panics if no prefix is given.
+func TextPrefix(prefixes ...string) Predicate {
+	if len(prefixes) == 0 {
+		panic("message.TextPrefix: at least one prefix must be given")
+	}
+	return newTextMatchPredicate(prefixes, false, strings.HasPrefix)
+}
+
+// TextPrefixFold is a case-insensitive version of TextPrefix.
+//
+// It panics if no prefix is given.
+func TextPrefixFold(prefixes ...string) Predicate {
+	if len(prefixes) == 0 {
+		panic("message.TextPrefixFold: at least one prefix must be given")
+	}
+	return newTextMatchPredicate(prefixes, true, strings.HasPrefix)
+}
+
+// TextSuffix is a predicate that is considered to be "true" if and only if a text of a message ends with at least one of the given suffixes.
+//
+// As with TextContains, the suffixes are treated literally. Trailing whitespaces in the text are not trimmed.
+//
+// It panics if no suffix is given.
+func TextSuffix(suffixes ...string) Predicate {
+	if len(suffixes) == 0 {
+		panic("message.TextSuffix: at least one suffix must be given")
+	}
+	return newTextMatchPredicate(suffixes, false, strings.HasSuffix)
+}
+
+// TextSuffixFold is a case-insensitive version of TextSuffix.
+//
+// It panics if no suffix is given.
+func TextSuffixFold(suffixes ...string) Predicate {
+	if len(suffixes) == 0 {
+		panic("message.TextSuffixFold: at least one suffix must be given")
+	}
+	return newTextMatchPredicate(suffixes, true, strings.HasSuffix)
+}
+
+func (p *textMatchPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		text := e.Text
+		if p.fold {
+			text = strings.ToLower(text)
+		}
+		for _, v := range p.values {
+			if p.match(text, v) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+type textLengthPredicate struct {
+	min int
+	max int
+}
+
+// TextLengthBetween is a predicate that is considered to be "true" if and only if the length of the text is between min and max (inclusive).
+//
+// The length is the number of runes rather than bytes, so that e.g. an emoji or a non-ASCII letter counts as one character.
+// Note that emoji written in the `:name:` form (as Slack usually sends them) count as the length of their names.
+// Give a large enough max (e.g. `math.MaxInt32`) to set no upper bound.
+//
+// It panics if min is negative or greater than max.
+func TextLengthBetween(min, max int) Predicate {
+	if min < 0 {
+		panic("message.TextLengthBetween: min must not be negative")
+	}
+	if min > max {
+		panic("message.TextLengthBetween: min must not be greater than max")
+	}
+	return &textLengthPredicate{min: min, max: max}
+}
+
+func (p *textLengthPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		n := utf8.RuneCountInString(e.Text)
+		if n < p.min || p.max < n {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+// Invocation is a pseudo-command in a message, e.g. `!deploy prod`, parsed by Command.
+type Invocation struct {
+	// Name is the command word without the prefix, e.g. `deploy`.
+	Name string
+
+	// Args is the text following the command word with surrounding whitespace trimmed, e.g. `prod`.
+	Args string
+
+	// Argv is Args split into arguments by the Tokenizer.
+	Argv []string
+}
+
+// Tokenizer splits the arguments of a pseudo-command. It returns an error if the arguments are malformed.
+type Tokenizer func(args string) ([]string, error)
+
+// SplitFields is a Tokenizer that splits arguments around whitespace, in the same way as `strings.Fields`.
+func SplitFields(args string) ([]string, error) {
+	return strings.Fields(args), nil
+}
+
+// SplitQuoted is a Tokenizer that splits arguments around whitespace, except in quoted strings.
+//
+// Strings are quoted with `"`, `'`, or `“` and `”` (which Slack clients may convert `"` into). The quotes are removed from arguments.
+// Double quotes in the middle of an argument are also recognized, so `--env="prod east"` becomes `--env=prod east`.
+// Single quotes are recognized only at the start of an argument, so apostrophes such as `don't` are kept as is.
+// It returns an error if a quote is not closed.
+func SplitQuoted(args string) ([]string, error) {
+	var (
+		argv    []string
+		current strings.Builder
+		inArg   bool
+		closing rune
+	)
+	for _, c := range args {
+		switch {
+		case closing != 0:
+			if c == closing {
+				closing = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || (c == '\'' && !inArg):
+			closing, inArg = c, true
+		case c == '“':
+			closing, inArg = '”', true
+		case unicode.IsSpace(c):
+			if inArg {
+				argv = append(argv, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if closing != 0 {
+		return nil, fmt.Errorf("unclosed quote %q", closing)
+	}
+	if inArg {
+		argv = append(argv, current.String())
+	}
+	return argv, nil
+}
+
+type commandPredicate struct {
+	prefix   string
+	tokenize Tokenizer
+}
+
+// Command is a predicate that is considered to be "true" if and only if the text is a pseudo-command, i.e. it starts with the prefix
+// immediately followed by a command word, e.g. `!deploy prod` with the prefix `!`. Whitespace around the text is ignored.
+//
+// The parsed command is passed to the inner handler through the context. It can be retrieved with CommandInvocation.
+// Its arguments are split with SplitQuoted. Use CommandWithTokenizer to split them in another way.
+// Texts whose arguments cannot be split (e.g. because of an unclosed quote) are considered to be "false".
+//
+// It panics if prefix is empty.
+func Command(prefix string) Predicate {
+	return CommandWithTokenizer(prefix, SplitQuoted)
+}
+
+// CommandWithTokenizer is the same as Command, except that arguments are split with the given Tokenizer.
+//
+// It panics if prefix is empty or tokenize is nil.
+func CommandWithTokenizer(prefix string, tokenize Tokenizer) Predicate {
+	if prefix == "" {
+		panic("message.Command: prefix must not be empty")
+	}
+	if tokenize == nil {
+		panic("message.Command: tokenize must not be nil")
+	}
+	return &commandPredicate{prefix: prefix, tokenize: tokenize}
+}
+
+func (p *commandPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		text := strings.TrimSpace(e.Text)
+		if !strings.HasPrefix(text, p.prefix) {
+			return errors.NotInterested
+		}
+		text = text[len(p.prefix):]
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end < 0 {
+			end = len(text)
+		}
+		if end == 0 {
+			return errors.NotInterested
+		}
+		inv := &Invocation{Name: text[:end], Args: strings.TrimSpace(text[end:])}
+		argv, err := p.tokenize(inv.Args)
+		if err != nil {
+			return errors.NotInterested
+		}
+		inv.Argv = argv
+		return h.HandleMessageEvent(context.WithValue(ctx, invocationKey, inv), e)
+	})
+}
+
+// CommandInvocation returns the pseudo-command parsed by Command.
+//
+// It returns nil if the handler is not wrapped with Command.
+func CommandInvocation(ctx context.Context) *Invocation {
+	inv, _ := ctx.Value(invocationKey).(*Invocation)
+	return inv
+}
+
+type channelPredicate struct {
+	ids []string
+}
+
+// Channel is a predicate that is considered to be "true" if and only if a message is posted to one of the given channels.
+//
+// It panics if no channel ID is given.
+func Channel(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.Channel: at least one channel ID must be given")
+	}
+	return &channelPredicate{ids: ids}
+}
+
+// InChannel is the same as Channel. It is named after the predicates of the other packages.
+func InChannel(ids ...string) Predicate {
+	return Channel(ids...)
+}
+
+func (p *channelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if !contains(p.ids, e.Channel) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type channelTypePredicate struct {
+	types []string
+}
+
+// ChannelType is a predicate that is considered to be "true" if and only if a message is posted to a channel of one of the given types.
+//
+// Possible types are `channel`, `group`, `im`, and `mpim`.
+// Slack does not always populate the channel type. Messages without channel types are never considered to be "true".
+//
+// It panics if no channel type is given.
+func ChannelType(types ...string) Predicate {
+	if len(types) == 0 {
+		panic("message.ChannelType: at least one channel type must be given")
+	}
+	return &channelTypePredicate{types: types}
+}
+
+func (p *channelTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.ChannelType == "" || !contains(p.types, e.ChannelType) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+// IsDirectMessage is a predicate that is considered to be "true" if and only if a message is a direct message.
+//
+// This is equivalent to `ChannelType("im")`.
+func IsDirectMessage() Predicate {
+	return ChannelType("im")
+}
+
+// IsPrivateChannel is a predicate that is considered to be "true" if and only if a message is posted to a private channel.
+//
+// This is equivalent to `ChannelType("group")`.
+func IsPrivateChannel() Predicate {
+	return ChannelType("group")
+}
+
+type fromUserPredicate struct {
+	ids []string
+}
+
+// FromUser is a predicate that is considered to be "true" if and only if a message is posted by one of the given users.
+//
+// This matches messages from human users only. Messages posted by bots (e.g. `bot_message`) have an empty `User` and a non-empty `BotID`,
+// so they never satisfy this predicate. Use FromBot to process such messages.
+//
+// It panics if no user ID is given.
+func FromUser(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.FromUser: at least one user ID must be given")
+	}
+	return &fromUserPredicate{ids: ids}
+}
+
+func (p *fromUserPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.User == "" || !contains(p.ids, e.User) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type mentionsUserPredicate struct {
+	ids []string
+}
+
+var userMention = regexp.MustCompile(`<@([^|>]+)(?:\|[^>]*)?>`)
+
+// MentionsUser is a predicate that is considered to be "true" if and only if the text of a message mentions one of the given users.
+//
+// Both `<@USERID>` and `<@USERID|name>` forms of mentions are recognized.
+//
+// It panics if no user ID is given.
+func MentionsUser(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.MentionsUser: at least one user ID must be given")
+	}
+	return &mentionsUserPredicate{ids: ids}
+}
+
+func (p *mentionsUserPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, m := range userMention.FindAllStringSubmatch(e.Text, -1) {
+			if contains(p.ids, m[1]) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
+type fromBotPredicate struct{}
+
+// FromBot is a predicate that is considered to be "true" if and only if a message is posted by a bot.
+//
+// A message is considered to be posted by a bot if it has a non-empty `BotID` or its subtype is `bot_message`.
+func FromBot() Predicate {
+	return &fromBotPredicate{}
+}
+
+func (p *fromBotPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if !isBot(e) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type excludeBotPredicate struct{}
+
+// ExcludeBot is a predicate that is considered to be "true" if and only if a message is not posted by a bot.
+//
+// This is the opposite of FromBot.
+func ExcludeBot() Predicate {
+	return &excludeBotPredicate{}
+}
+
+func (p *excludeBotPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if isBot(e) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func isBot(e *slackevents.MessageEvent) bool {
+	return e.BotID != "" || e.SubType == "bot_message"
+}
+
+type fromBotIDPredicate struct {
+	ids []string
+}
+
+// FromBotID is a predicate that is considered to be "true" if and only if a message is posted by one of the given bots.
+//
+// Each integration (an app, an incoming webhook, or a legacy bot) has its own bot ID, so this can be used to route messages from specific integrations.
+// Unlike FromUsername, this also matches messages posted by apps with their bot tokens, which have both `User` and `BotID`.
+//
+// It panics if no bot ID is given.
+func FromBotID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.FromBotID: at least one bot ID must be given")
+	}
+	return &fromBotIDPredicate{ids: ids}
+}
+
+func (p *fromBotIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.BotID == "" || !contains(p.ids, e.BotID) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type fromAppPredicate struct {
+	ids []string
+}
+
+// FromApp is a predicate that is considered to be "true" if and only if a message is posted by one of the given apps,
+// which are identified by the App IDs (e.g. "A0123456789") shown on the configuration pages of the apps.
+//
+// Slack populates the fields differently depending on how a message is posted:
+//
+//   - Messages from incoming webhooks of apps have the subtype `bot_message`, `bot_id`, `username`, and `app_id`, but no `user`.
+//   - Messages posted by apps with their bot tokens have `user` (the bot user ID), `bot_id`, `app_id`, and `bot_profile.app_id`, but usually no subtype.
+//   - Messages from legacy integrations (e.g. custom bots and incoming webhooks that do not belong to any app) have no App ID, so this never matches them.
+//
+// This matches both of the former two; use FromBotID or FromUsername for the latter.
+//
+// Note that slackevents.MessageEvent does not have App IDs, so this reads them from the raw event passed by eventrouter.Router.
+// It is considered to be "false" if the handler is not called by eventrouter.Router.
+//
+// It panics if no App ID is given.
+func FromApp(appIDs ...string) Predicate {
+	if len(appIDs) == 0 {
+		panic("message.FromApp: at least one App ID must be given")
+	}
+	return &fromAppPredicate{ids: appIDs}
+}
+
+func (p *fromAppPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.BotID == "" || !contains(p.ids, appID(ctx)) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+// appID returns the App ID of the message being processed, or an empty string if it is unknown.
+func appID(ctx context.Context) string {
+	raw := routerutils.InnerEvent(ctx)
+	if raw == nil {
+		return ""
+	}
+	var e struct {
+		AppID      string `json:"app_id"`
+		BotProfile *struct {
+			AppID string `json:"app_id"`
+		} `json:"bot_profile"`
+	}
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return ""
+	}
+	if e.AppID != "" {
+		return e.AppID
+	}
+	if e.BotProfile != nil {
+		return e.BotProfile.AppID
+	}
+	return ""
+}
+
+type fromUsernamePredicate struct {
+	usernames []string
+}
+
+// FromUsername is a predicate that is considered to be "true" if and only if a message is a `bot_message` without `User`
+// and its `Username` is one of the given ones, e.g. "GitHub".
+//
+// Slack populates the fields differently depending on how a message is posted:
+//
+//   - Messages from incoming webhooks and legacy bots have the subtype `bot_message`, `BotID`, and `Username` (the name of the integration, or the one overridden by the poster), but no `User`.
+//   - Messages posted by apps with their bot tokens have `User` (the bot user ID) and `BotID`, but usually neither the subtype nor `Username`.
+//
+// So this predicate matches the former only. Note that the username can be overridden by whoever posts the message; use FromApp or FromBotID to identify integrations reliably.
+//
+// It panics if no username is given.
+func FromUsername(usernames ...string) Predicate {
+	if len(usernames) == 0 {
+		panic("message.FromUsername: at least one username must be given")
+	}
+	return &fromUsernamePredicate{usernames: usernames}
+}
+
+func (p *fromUsernamePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.User != "" || e.SubType != "bot_message" || !contains(p.usernames, e.Username) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type excludeBotIDPredicate struct {
+	ids []string
+}
+
+// ExcludeBotID is a predicate that is considered to be "true" if and only if a message is not posted by any of the given bots.
+//
+// Unlike ExcludeBot, messages posted by other bots are considered to be "true".
+//
+// It panics if no bot ID is given.
+func ExcludeBotID(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.ExcludeBotID: at least one bot ID must be given")
+	}
+	return &excludeBotIDPredicate{ids: ids}
+}
+
+func (p *excludeBotIDPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.BotID != "" && contains(p.ids, e.BotID) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type excludeSelfPredicate struct {
+	botUserID string
+	botID     string
+}
+
+// ExcludeSelf is a predicate that is considered to be "true" if and only if a message is not posted by the bot itself.
+// This is useful to prevent the bot from reacting to its own messages.
+//
+// A message is considered to be posted by the bot if its `User` equals to botUserID, or its `BotID` equals to botID.
+// Both IDs can be obtained with `auth.test` (i.e. `AuthTestResponse.UserID` and `AuthTestResponse.BotID` of `slack.Client.AuthTest`).
+// botID may be empty, in which case only botUserID is checked.
+//
+// Unlike ExcludeBot, messages posted by other bots are considered to be "true".
+//
+// It panics if botUserID is empty.
+func ExcludeSelf(botUserID, botID string) Predicate {
+	if botUserID == "" {
+		panic("message.ExcludeSelf: botUserID must not be empty")
+	}
+	return &excludeSelfPredicate{botUserID: botUserID, botID: botID}
+}
+
+func (p *excludeSelfPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.User == p.botUserID || (p.botID != "" && e.BotID == p.botID) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+// SeenStore remembers `client_msg_id`s of messages to detect messages that are processed more than once.
+//
+// `dedup.MemoryStore` implements this.
+type SeenStore interface {
+	// Contains reports whether the given ID has been recorded.
+	Contains(id string) bool
+
+	// Add records the given ID.
+	Add(id string)
+}
+
+type excludeSeenPredicate struct {
+	store SeenStore
+}
+
+// ExcludeSeen is a predicate that is considered to be "true" if and only if the `client_msg_id` (`ClientMsgID`) of a message has not been seen by the given store.
+//
+// Messages posted by users carry `client_msg_id`, which is stable even if Slack delivers the event more than once,
+// so this gives idempotency at the message level without relying on `event_id` (see also IdempotencyKey).
+// Bot messages and some other subtypes (e.g. `message_changed`) have no `client_msg_id`.
+// Such messages are not deduplicated, i.e. they are always considered to be "true" and nothing is recorded to the store.
+//
+// A message is recorded only after the inner handler returns nil, so retries of messages whose handlers failed are processed again.
+// Nothing is recorded while the predicate is evaluated by Not or Any, which do not call the inner handler through it,
+// so use this outside of them.
+//
+// It panics if store is nil.
+func ExcludeSeen(store SeenStore) Predicate {
+	if store == nil {
+		panic("message.ExcludeSeen: store must not be nil")
+	}
+	return &excludeSeenPredicate{store: store}
+}
+
+func (p *excludeSeenPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.ClientMsgID == "" {
+			return h.HandleMessageEvent(ctx, e)
+		}
+		if p.store.Contains(e.ClientMsgID) {
+			return errors.NotInterested
+		}
+		if err := h.HandleMessageEvent(ctx, e); err != nil {
+			return err
+		}
+		if !predicates.IsProbing(ctx) {
+			p.store.Add(e.ClientMsgID)
+		}
+		return nil
+	})
+}
+
+// WithTeamID returns a copy of ctx that carries the ID of the workspace that receives events.
+//
+// `eventrouter.Router.OnMessage` sets the `team_id` of each event callback automatically,
+// so this is needed only when handlers are called without the Router, e.g. in tests.
+func WithTeamID(ctx context.Context, teamID string) context.Context {
+	return context.WithValue(ctx, teamIDKey, teamID)
+}
+
+// TeamID returns the ID of the workspace that receives events, which is set by WithTeamID.
+//
+// It returns an empty string if the ID is not set.
+func TeamID(ctx context.Context) string {
+	teamID, _ := ctx.Value(teamIDKey).(string)
+	return teamID
+}
+
+// EphemeralPoster is a client that posts ephemeral messages. `*slack.Client` implements this.
+type EphemeralPoster interface {
+	PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error)
+}
+
+// ErrNoClient is returned from PostEphemeral (and predicates that need a client, e.g. ChannelNameMatches) when no client is given.
+var ErrNoClient = stderrors.New("no client is given to the Router")
+
+type replier struct {
+	client EphemeralPoster
+	event  *slackevents.MessageEvent
+}
+
+// WithClient returns a copy of ctx that carries client, which PostEphemeral uses to reply to the message event e.
+//
+// Predicates that call other APIs (e.g. ChannelNameMatches) also use the client, as long as it implements the required methods.
+//
+// `eventrouter.Router.OnMessage` sets this automatically if `eventrouter.WithClient` is given,
+// so this is needed only when handlers are called without the Router, e.g. in tests.
+func WithClient(ctx context.Context, client EphemeralPoster, e *slackevents.MessageEvent) context.Context {
+	return context.WithValue(ctx, replierKey, &replier{client: client, event: e})
+}
+
+// PostEphemeral posts an ephemeral message that is visible only to the author of the message being processed, in the channel the message is posted.
+// If the message is in a thread, so is the ephemeral message.
+//
+// The options are applied after the text, so they can add blocks, etc. to the message.
+// It returns the timestamp of the posted message, or ErrNoClient if no client is given (see WithClient).
+func PostEphemeral(ctx context.Context, text string, options ...slack.MsgOption) (string, error) {
+	r, ok := ctx.Value(replierKey).(*replier)
+	if !ok || r.client == nil {
+		return "", ErrNoClient
+	}
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if r.event.ThreadTimeStamp != "" {
+		opts = append(opts, slack.MsgOptionTS(r.event.ThreadTimeStamp))
+	}
+	opts = append(opts, options...)
+	return r.client.PostEphemeralContext(ctx, r.event.Channel, r.event.User, opts...)
+}
+
+// ConversationInfoGetter is a client that gets information about conversations. `*slack.Client` implements this.
+type ConversationInfoGetter interface {
+	GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error)
+}
+
+// DefaultChannelNameTTL is how long ChannelNameMatches caches channel names.
+const DefaultChannelNameTTL = 10 * time.Minute
+
+type channelName struct {
+	name    string
+	expires time.Time
+}
+
+type channelNameMatchesPredicate struct {
+	re    *regexp.Regexp
+	ttl   time.Duration
+	mu    sync.Mutex
+	names map[string]channelName
+}
+
+// ChannelNameMatches is a predicate that is considered to be "true" if and only if the name (without `#`) of the channel a message is posted in matches to the given regexp,
+// e.g. `regexp.MustCompile("^incident-")`.
+//
+// Events carry only channel IDs, so it gets channel names by calling `conversations.info` with the client given to WithClient (or `eventrouter.WithClient`),
+// which must implement ConversationInfoGetter. Names are cached for DefaultChannelNameTTL; use ChannelNameMatchesTTL to change it.
+// Each predicate has its own cache. Messages without channel IDs are never considered to be "true".
+//
+// If no client is given, it returns an error that equals to ErrNoClient in the sense of `errors.Is`.
+// Errors from the API are also returned as they are.
+func ChannelNameMatches(re *regexp.Regexp) Predicate {
+	return ChannelNameMatchesTTL(re, DefaultChannelNameTTL)
+}
+
+// ChannelNameMatchesTTL is the same as ChannelNameMatches, except that channel names are cached for ttl.
+//
+// It panics if ttl is not positive.
+func ChannelNameMatchesTTL(re *regexp.Regexp, ttl time.Duration) Predicate {
+	if ttl <= 0 {
+		panic("message.ChannelNameMatches: ttl must be positive")
+	}
+	return &channelNameMatchesPredicate{re: re, ttl: ttl, names: make(map[string]channelName)}
+}
+
+func (p *channelNameMatchesPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
-		idx := p.re.FindStringIndex(e.Text)
-		if len(idx) == 0 {
+		if e.Channel == "" {
+			return errors.NotInterested
+		}
+		name, err := p.channelName(ctx, e.Channel)
+		if err != nil {
+			return err
+		}
+		if !p.re.MatchString(name) {
 			return errors.NotInterested
 		}
 		return h.HandleMessageEvent(ctx, e)
 	})
 }
 
-type channelPredicate struct {
-	id string
+func (p *channelNameMatchesPredicate) channelName(ctx context.Context, channelID string) (string, error) {
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.names[channelID]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.name, nil
+	}
+
+	r, ok := ctx.Value(replierKey).(*replier)
+	if !ok || r.client == nil {
+		return "", fmt.Errorf("message.ChannelNameMatches requires a client: %w", ErrNoClient)
+	}
+	client, ok := r.client.(ConversationInfoGetter)
+	if !ok {
+		return "", fmt.Errorf("message.ChannelNameMatches requires a client that implements ConversationInfoGetter, but got %T", r.client)
+	}
+	ch, err := client.GetConversationInfoContext(ctx, channelID, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the name of the channel %s: %w", channelID, err)
+	}
+	p.mu.Lock()
+	// Prune expired entries so that the cache does not grow with channels that are no longer active.
+	for id, cached := range p.names {
+		if !now.Before(cached.expires) {
+			delete(p.names, id)
+		}
+	}
+	p.names[channelID] = channelName{name: ch.Name, expires: now.Add(p.ttl)}
+	p.mu.Unlock()
+	return ch.Name, nil
 }
 
-// Channel is a predicate that is considered to be "true" if and only if a message is posted to the given channel.
-func Channel(id string) Predicate {
-	return &channelPredicate{id: id}
+type isExternalSharedPredicate struct{}
+
+// IsExternalShared is a predicate that is considered to be "true" if and only if a message is posted by a user of another organization
+// in a channel shared with it (i.e. a Slack Connect channel).
+//
+// Messages in shared channels have the following fields, which are empty in other channels:
+//   - `UserTeam` is the workspace that the author belongs to.
+//   - `SourceTeam` is the workspace that the message is posted from.
+//
+// A message is considered to be external if its `UserTeam` differs from the workspace that receives the event (see TeamID).
+// Note that in Enterprise Grid, messages from other workspaces of the same organization are also considered to be external,
+// since events do not tell which organization a workspace belongs to.
+//
+// Messages whose receiving workspace is unknown are never considered to be "true".
+func IsExternalShared() Predicate {
+	return &isExternalSharedPredicate{}
 }
 
-func (p *channelPredicate) Wrap(h Handler) Handler {
+func (p *isExternalSharedPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		teamID := TeamID(ctx)
+		if teamID == "" || e.UserTeam == "" || e.UserTeam == teamID {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type sourceTeamPredicate struct {
+	ids []string
+}
+
+// SourceTeam is a predicate that is considered to be "true" if and only if a message in a shared channel is posted from one of the given workspaces.
+//
+// Messages in channels that are not shared have no source team, so they are never considered to be "true".
+// See IsExternalShared for the fields that distinguish internal and external authors.
+//
+// It panics if no team ID is given.
+func SourceTeam(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("message.SourceTeam: at least one team ID must be given")
+	}
+	return &sourceTeamPredicate{ids: ids}
+}
+
+func (p *sourceTeamPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.SourceTeam == "" || !contains(p.ids, e.SourceTeam) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type hasFilesPredicate struct{}
+
+// HasFiles is a predicate that is considered to be "true" if and only if a message has at least one file attached.
+//
+// Note that messages with files usually have the subtype `file_share`, so they never satisfy NoSubType.
+func HasFiles() Predicate {
+	return &hasFilesPredicate{}
+}
+
+func (p *hasFilesPredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
-		if e.Channel != p.id {
+		if len(e.Files) == 0 {
 			return errors.NotInterested
 		}
 		return h.HandleMessageEvent(ctx, e)
 	})
 }
 
+type hasFileTypePredicate struct {
+	types []string
+}
+
+// HasFileType is a predicate that is considered to be "true" if and only if a message has at least one file of the given types.
+//
+// Each type is compared to both the filetype (e.g. `png`, `pdf`) and the MIME type (e.g. `image/png`) of files.
+// As with HasFiles, such messages usually have the subtype `file_share`.
+//
+// It panics if no type is given.
+func HasFileType(types ...string) Predicate {
+	if len(types) == 0 {
+		panic("message.HasFileType: at least one file type must be given")
+	}
+	return &hasFileTypePredicate{types: types}
+}
+
+func (p *hasFileTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		for _, f := range e.Files {
+			if contains(p.types, f.Filetype) || contains(p.types, f.Mimetype) {
+				return h.HandleMessageEvent(ctx, e)
+			}
+		}
+		return errors.NotInterested
+	})
+}
+
 type subTypePredicate struct {
-	subType string
+	subTypes []string
 }
 
-// SubType is a predicate that is considered to be "true" is and only if a subtype of a message is the given one.
+// SubType is a predicate that is considered to be "true" is and only if a subtype of a message is one of the given ones.
 //
 // The full list of all subtypes is described here: https://api.slack.com/events/message
-func SubType(subType string) Predicate {
-	return &subTypePredicate{subType: subType}
+//
+// It panics if no subtype is given.
+func SubType(subTypes ...string) Predicate {
+	if len(subTypes) == 0 {
+		panic("message.SubType: at least one subtype must be given")
+	}
+	return &subTypePredicate{subTypes: subTypes}
 }
 
 func (p *subTypePredicate) Wrap(h Handler) Handler {
 	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
-		if e.SubType != p.subType {
+		if !contains(p.subTypes, e.SubType) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type noSubTypePredicate struct{}
+
+// NoSubType is a predicate that is considered to be "true" if and only if a message has no subtype, that is, it is a plain message posted by a user.
+//
+// This is useful to ignore system messages such as `channel_join`.
+func NoSubType() Predicate {
+	return &noSubTypePredicate{}
+}
+
+func (p *noSubTypePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if e.SubType != "" {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type isThreadReplyPredicate struct{}
+
+// IsThreadReply is a predicate that is considered to be "true" if and only if a message is a reply in a thread.
+//
+// Note that the parent message of a thread may also have `ThreadTimeStamp`, which equals to its own `TimeStamp`.
+// Such messages are not considered to be replies.
+// Replies that are also sent to the channel (i.e. `thread_broadcast`) are considered to be replies.
+func IsThreadReply() Predicate {
+	return &isThreadReplyPredicate{}
+}
+
+func (p *isThreadReplyPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if !isThreadReply(e) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+type isTopLevelPredicate struct{}
+
+// IsTopLevel is a predicate that is considered to be "true" if and only if a message is not a reply in a thread.
+//
+// This is the opposite of IsThreadReply. That is, the parent message of a thread, whose `ThreadTimeStamp` equals to its own `TimeStamp`,
+// is considered to be a top-level message as well as the messages that have no `ThreadTimeStamp`.
+func IsTopLevel() Predicate {
+	return &isTopLevelPredicate{}
+}
+
+func (p *isTopLevelPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		if isThreadReply(e) {
+			return errors.NotInterested
+		}
+		return h.HandleMessageEvent(ctx, e)
+	})
+}
+
+func isThreadReply(e *slackevents.MessageEvent) bool {
+	return e.ThreadTimeStamp != "" && e.ThreadTimeStamp != e.TimeStamp
+}
+
+// IsThreadBroadcast is a predicate that is considered to be "true" if and only if a message is a reply in a thread that is also sent to the channel.
+//
+// This is equivalent to `SubType("thread_broadcast")`.
+func IsThreadBroadcast() Predicate {
+	return SubType("thread_broadcast")
+}
+
+// IsEdit is a predicate that is considered to be "true" if and only if a message is an edit of another message.
+//
+// Such events have the subtype `message_changed`, and their `Text` is empty.
+// The edited message and the previous one can be retrieved with EditedText and PreviousText.
+// This is equivalent to `SubType("message_changed")`.
+func IsEdit() Predicate {
+	return SubType("message_changed")
+}
+
+// EditedText returns the text of the message after an edit, i.e. `e.Message.Text` of a `message_changed` event.
+//
+// It returns `e.Text` if e is not an edit, so that the same logic can be applied to both new and edited messages.
+func EditedText(e *slackevents.MessageEvent) string {
+	if e.SubType != "message_changed" || e.Message == nil {
+		return e.Text
+	}
+	return e.Message.Text
+}
+
+// PreviousText returns the text of the message before an edit, i.e. `e.PreviousMessage.Text` of a `message_changed` event.
+//
+// It returns an empty string if e is not an edit.
+func PreviousText(e *slackevents.MessageEvent) string {
+	if e.SubType != "message_changed" || e.PreviousMessage == nil {
+		return ""
+	}
+	return e.PreviousMessage.Text
+}
+
+type timeRangePredicate struct {
+	start time.Duration
+	end   time.Duration
+	loc   *time.Location
+}
+
+// WithinTimeRange is a predicate that is considered to be "true" if and only if a message is posted within the given daily time window.
+//
+// Only the wall clock (hour, minute, and second) of `start` and `end` is used regardless of their locations,
+// and it is interpreted in `loc`, so the window follows daylight saving time of `loc`.
+// The window includes `start` and excludes `end`. If `end` is earlier than `start`, the window spans midnight,
+// e.g. 22:00 to 06:00 matches to messages posted at night.
+//
+// Messages with malformed timestamps are never considered to be "true". See also EventTime.
+//
+// It panics if `loc` is nil.
+func WithinTimeRange(start, end time.Time, loc *time.Location) Predicate {
+	if loc == nil {
+		panic("message.WithinTimeRange: loc must not be nil")
+	}
+	return &timeRangePredicate{start: timeOfDay(start), end: timeOfDay(end), loc: loc}
+}
+
+func (p *timeRangePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		t, err := EventTime(e)
+		if err != nil {
+			return errors.NotInterested
+		}
+		if !p.contains(timeOfDay(t.In(p.loc))) {
 			return errors.NotInterested
 		}
 		return h.HandleMessageEvent(ctx, e)
 	})
 }
 
+func (p *timeRangePredicate) contains(d time.Duration) bool {
+	if p.start <= p.end {
+		return p.start <= d && d < p.end
+	}
+	return p.start <= d || d < p.end
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// EventTime returns the time when a message is posted, which is taken from the `ts` field of the message.
+func EventTime(e *slackevents.MessageEvent) (time.Time, error) {
+	return ParseTimestamp(e.TimeStamp)
+}
+
+// ParseTimestamp parses Slack's timestamps like `1581106241.371594`, which consist of seconds and microseconds since the Unix epoch.
+//
+// The fractional part may be omitted, and digits beyond nanoseconds are ignored.
+func ParseTimestamp(ts string) (time.Time, error) {
+	return routerutils.ParseTimestamp(ts)
+}
+
+// FormatTimestamp formats the given time in the same way as Slack's timestamps, i.e. seconds and microseconds since the Unix epoch.
+//
+// Digits beyond microseconds are truncated.
+func FormatTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+type notPredicate struct {
+	pred Predicate
+}
+
+// Not is a predicate that is considered to be "true" if and only if the given Predicate is considered to be "false".
+//
+// If the given Predicate returns an error other than `errors.NotInterested`, the error is returned as is.
+func Not(pred Predicate) Predicate {
+	return &notPredicate{pred: pred}
+}
+
+func (p *notPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		return predicates.Not(ctx, probe(p.pred, e), handle(h, e))
+	})
+}
+
+type anyPredicate struct {
+	preds []Predicate
+}
+
+// Any is a predicate that is considered to be "true" if and only if at least one of the given Predicates is considered to be "true".
+//
+// The given Predicates are evaluated in order without calling the inner handler, and the evaluation stops at the first one that is considered to be "true".
+// Then the inner handler is called exactly once.
+// If one of the given Predicates returns an error other than `errors.NotInterested`, the evaluation stops and the error is returned as is.
+//
+// It panics if no Predicate is given.
+func Any(preds ...Predicate) Predicate {
+	if len(preds) == 0 {
+		panic("message.Any: at least one predicate must be given")
+	}
+	return &anyPredicate{preds: preds}
+}
+
+func (p *anyPredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		probes := make([]predicates.Probe, 0, len(p.preds))
+		for _, pred := range p.preds {
+			probes = append(probes, probe(pred, e))
+		}
+		return predicates.Any(ctx, probes, handle(h, e))
+	})
+}
+
+type requirePredicate struct {
+	pred   Predicate
+	onFail error
+}
+
+// Require is a predicate that is considered to be "true" if and only if the given Predicate is considered to be "true".
+//
+// Unlike the given Predicate itself, it returns `onFail` instead of `errors.NotInterested` when the Predicate is considered to be "false",
+// so the Router stops falling back to other handlers and responds with the error.
+// This is useful to reject requests explicitly, e.g. `Require(FromUser(admins...), errors.ErrForbidden)` responds with Forbidden to non-admin users.
+//
+// It panics if `onFail` is nil.
+func Require(pred Predicate, onFail error) Predicate {
+	if onFail == nil {
+		panic("message.Require: onFail must not be nil")
+	}
+	return &requirePredicate{pred: pred, onFail: onFail}
+}
+
+func (p *requirePredicate) Wrap(h Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, e *slackevents.MessageEvent) error {
+		return predicates.Require(ctx, probe(p.pred, e), p.onFail, handle(h, e))
+	})
+}
+
+// probe converts the given Predicate into a Probe for the event.
+func probe(pred Predicate, e *slackevents.MessageEvent) predicates.Probe {
+	return func(ctx context.Context, next func(context.Context) error) error {
+		return pred.Wrap(HandlerFunc(func(innerCtx context.Context, _ *slackevents.MessageEvent) error {
+			return next(innerCtx)
+		})).HandleMessageEvent(ctx, e)
+	}
+}
+
+// handle returns a function that calls h with the event.
+func handle(h Handler, e *slackevents.MessageEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return h.HandleMessageEvent(ctx, e)
+	}
+}
+
 // Build decorates `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func Build(h Handler, preds ...Predicate) Handler {
 	for _, p := range preds {
@@ -92,3 +1360,113 @@ func Build(h Handler, preds ...Predicate) Handler {
 	}
 	return h
 }
+
+// IdempotencyKey returns a key that identifies the message of the event e, which is useful to make handlers idempotent
+// by storing the keys of processed messages and skipping the ones already seen.
+//
+// The key consists of the team, the channel, the author (the user, or the bot if the message has no user), and the timestamp of the message,
+// so the same message always has the same key. The team is the source team, which is empty unless the channel is shared with other workspaces.
+//
+// This is different from deduplication by `event_id` (see `eventrouter.WithDedupStore`), which works at the transport level.
+// `event_id` identifies a delivery rather than a message, so it is not enough in some cases:
+// e.g. the same message is delivered more than once with different event IDs when the app subscribes to more than one event type that covers it,
+// or when a retry is delivered after the original event has expired from the store (or, in rare edge cases, with a new ID).
+func IdempotencyKey(e *slackevents.MessageEvent) string {
+	author := e.User
+	if author == "" {
+		author = e.BotID
+	}
+	return strings.Join([]string{"message", e.SourceTeam, e.Channel, author, e.TimeStamp}, ":")
+}
+
+// Conditions accumulates Predicates to build a Handler in a fluent way, e.g.
+//
+//	h := message.Where().InChannel("C12345").NotThreaded().FromHuman().Build(handler)
+//
+// This is equivalent to `Build(handler, InChannel("C12345"), IsTopLevel(), ExcludeBot())`.
+// Each method returns a new Conditions, so a partially built one can be shared and extended safely.
+type Conditions struct {
+	preds []Predicate
+}
+
+// Where returns a new Conditions that starts with the given Predicates.
+func Where(preds ...Predicate) *Conditions {
+	return &Conditions{preds: append([]Predicate(nil), preds...)}
+}
+
+// And adds arbitrary Predicates to the Conditions.
+func (c *Conditions) And(preds ...Predicate) *Conditions {
+	next := make([]Predicate, 0, len(c.preds)+len(preds))
+	next = append(next, c.preds...)
+	next = append(next, preds...)
+	return &Conditions{preds: next}
+}
+
+// InChannel adds Channel(ids...) to the Conditions.
+func (c *Conditions) InChannel(ids ...string) *Conditions {
+	return c.And(Channel(ids...))
+}
+
+// ChannelType adds ChannelType(types...) to the Conditions.
+func (c *Conditions) ChannelType(types ...string) *Conditions {
+	return c.And(ChannelType(types...))
+}
+
+// FromUser adds FromUser(ids...) to the Conditions.
+func (c *Conditions) FromUser(ids ...string) *Conditions {
+	return c.And(FromUser(ids...))
+}
+
+// FromHuman adds ExcludeBot() to the Conditions.
+func (c *Conditions) FromHuman() *Conditions {
+	return c.And(ExcludeBot())
+}
+
+// FromBot adds FromBot() to the Conditions.
+func (c *Conditions) FromBot() *Conditions {
+	return c.And(FromBot())
+}
+
+// Threaded adds IsThreadReply() to the Conditions.
+func (c *Conditions) Threaded() *Conditions {
+	return c.And(IsThreadReply())
+}
+
+// NotThreaded adds IsTopLevel() to the Conditions.
+func (c *Conditions) NotThreaded() *Conditions {
+	return c.And(IsTopLevel())
+}
+
+// TextRegexp adds TextRegexp(re) to the Conditions.
+func (c *Conditions) TextRegexp(re *regexp.Regexp) *Conditions {
+	return c.And(TextRegexp(re))
+}
+
+// TextContains adds TextContains(substrs...) to the Conditions.
+func (c *Conditions) TextContains(substrs ...string) *Conditions {
+	return c.And(TextContains(substrs...))
+}
+
+// MentionsUser adds MentionsUser(ids...) to the Conditions.
+func (c *Conditions) MentionsUser(ids ...string) *Conditions {
+	return c.And(MentionsUser(ids...))
+}
+
+// Predicates returns the accumulated Predicates, which can be passed to functions that take Predicates, e.g. `eventrouter.Router.OnMessage`.
+func (c *Conditions) Predicates() []Predicate {
+	return append([]Predicate(nil), c.preds...)
+}
+
+// Build decorates `h` with the accumulated Predicates in the same way as the package-level Build.
+func (c *Conditions) Build(h Handler) Handler {
+	return Build(h, c.preds...)
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
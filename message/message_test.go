@@ -2,13 +2,25 @@ package message_test
 
 import (
 	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
 	"regexp"
+	"testing"
+	"time"
+	_ "time/tzdata"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
+	"github.com/genkami/go-slack-event-router/dedup"
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
 	"github.com/genkami/go-slack-event-router/message"
 )
 
@@ -101,6 +113,68 @@ var _ = Describe("Message", func() {
 		})
 	})
 
+	Describe("TextRegexpString", func() {
+		Context("When the text matches to the pattern", func() {
+			It("calls the inner handler with the submatches", func() {
+				var submatches []string
+				h := message.TextRegexpString(`deploy (\w+)`).Wrap(message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+					submatches = message.Submatches(ctx)
+					return nil
+				}))
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "deploy prod"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(submatches).To(Equal([]string{"deploy prod", "prod"}))
+			})
+		})
+
+		Context("When the text does not match to the pattern", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextRegexpString(`\bapple\b`).Wrap(innerHandler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "I ate a pineapple"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("When the pattern is invalid", func() {
+			It("panics", func() {
+				Expect(func() { message.TextRegexpString(`(`) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("CompileRegexp", func() {
+		AfterEach(func() {
+			message.ClearRegexpCache()
+		})
+
+		It("returns the same instance for identical patterns", func() {
+			re1, err := message.CompileRegexp(`^shared pattern$`)
+			Expect(err).NotTo(HaveOccurred())
+			re2, err := message.CompileRegexp(`^shared pattern$`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(re1).To(BeIdenticalTo(re2))
+
+			re3, err := message.CompileRegexp(`^another pattern$`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(re3).NotTo(BeIdenticalTo(re1))
+		})
+
+		It("compiles patterns again after the cache is cleared", func() {
+			re1, err := message.CompileRegexp(`^cleared pattern$`)
+			Expect(err).NotTo(HaveOccurred())
+			message.ClearRegexpCache()
+			re2, err := message.CompileRegexp(`^cleared pattern$`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(re2).NotTo(BeIdenticalTo(re1))
+		})
+
+		It("returns an error for invalid patterns", func() {
+			_, err := message.CompileRegexp(`(`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("TextRegexp", func() {
 		Context("When the text matches to the pattern", func() {
 			It("calls the inner handler", func() {
@@ -127,26 +201,105 @@ var _ = Describe("Message", func() {
 		})
 	})
 
-	Describe("Channel", func() {
-		Context("when the message is posted to the given channel", func() {
-			It("calls the inner handler", func() {
-				h := message.Channel("THECHANNEL").Wrap(innerHandler)
+	Describe("Submatches", func() {
+		var (
+			submatches       []string
+			namedSubmatches  map[string]string
+			capturingHandler = message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				submatches = message.Submatches(ctx)
+				namedSubmatches = message.NamedSubmatches(ctx)
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			submatches = nil
+			namedSubmatches = nil
+		})
+
+		Context("when the text matches to the pattern", func() {
+			It("passes the submatches to the inner handler", func() {
+				h := message.TextRegexp(regexp.MustCompile(`deploy (?P<service>\w+) to (\w+)`)).Wrap(capturingHandler)
+				e := &slackevents.MessageEvent{
+					Text: "please deploy api to production",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(submatches).To(Equal([]string{"deploy api to production", "api", "production"}))
+				Expect(namedSubmatches).To(Equal(map[string]string{"service": "api"}))
+			})
+		})
+
+		Context("when TextRegexp is used inside Any", func() {
+			It("passes the submatches to the inner handler", func() {
+				h := message.Any(
+					message.TextRegexp(regexp.MustCompile(`release (?P<service>\w+)`)),
+					message.TextRegexp(regexp.MustCompile(`deploy (?P<service>\w+)`)),
+				).Wrap(capturingHandler)
+				e := &slackevents.MessageEvent{
+					Text: "please deploy api",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(namedSubmatches).To(Equal(map[string]string{"service": "api"}))
+			})
+		})
+
+		Context("when no TextRegexp is used", func() {
+			It("returns nil", func() {
+				h := message.InChannel("THECHANNEL").Wrap(capturingHandler)
 				e := &slackevents.MessageEvent{
-					Text:    "hello",
 					Channel: "THECHANNEL",
 				}
 				err := h.HandleMessageEvent(ctx, e)
 				Expect(err).ToNot(HaveOccurred())
+				Expect(submatches).To(BeNil())
+				Expect(namedSubmatches).To(BeNil())
+			})
+		})
+	})
+
+	Describe("TextContains", func() {
+		Context("when the text contains one of the substrings", func() {
+			It("calls the inner handler", func() {
+				h := message.TextContains("apple", "banana").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "I ate a banana",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
 				Expect(numHandlerCalled).To(Equal(1))
 			})
 		})
 
-		Context("when the message is posted to a channel other than a given one", func() {
+		Context("when the text contains regexp metacharacters", func() {
+			It("matches them literally", func() {
+				h := message.TextContains("a.b").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "axb",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the text contains none of the substrings", func() {
 			It("does not call the inner handler", func() {
-				h := message.Channel("THECHANNEL").Wrap(innerHandler)
+				h := message.TextContains("apple", "banana").Wrap(innerHandler)
 				e := &slackevents.MessageEvent{
-					Text:    "hello",
-					Channel: "ANOTHERCHANNEL",
+					Text: "I ate an orange",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the case of the text differs", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextContains("apple").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "I ate an APPLE",
 				}
 				err := h.HandleMessageEvent(ctx, e)
 				Expect(err).To(Equal(errors.NotInterested))
@@ -155,12 +308,12 @@ var _ = Describe("Message", func() {
 		})
 	})
 
-	Describe("SubType", func() {
-		Context("when the subtype of themessage equals to the given one", func() {
+	Describe("TextContainsFold", func() {
+		Context("when the text contains one of the substrings in a different case", func() {
 			It("calls the inner handler", func() {
-				h := message.SubType("channel_join").Wrap(innerHandler)
+				h := message.TextContainsFold("Apple", "banana").Wrap(innerHandler)
 				e := &slackevents.MessageEvent{
-					SubType: "channel_join",
+					Text: "I ate an APPLE",
 				}
 				err := h.HandleMessageEvent(ctx, e)
 				Expect(err).ToNot(HaveOccurred())
@@ -168,11 +321,11 @@ var _ = Describe("Message", func() {
 			})
 		})
 
-		Context("when the subtype of the message differs from the given one", func() {
+		Context("when the text contains none of the substrings", func() {
 			It("does not call the inner handler", func() {
-				h := message.SubType("channel_join").Wrap(innerHandler)
+				h := message.TextContainsFold("apple", "banana").Wrap(innerHandler)
 				e := &slackevents.MessageEvent{
-					SubType: "pinned_channel",
+					Text: "I ate an orange",
 				}
 				err := h.HandleMessageEvent(ctx, e)
 				Expect(err).To(Equal(errors.NotInterested))
@@ -180,4 +333,2115 @@ var _ = Describe("Message", func() {
 			})
 		})
 	})
-})
+
+	Describe("TextEquals", func() {
+		Context("when the text is equal to the given string", func() {
+			It("calls the inner handler", func() {
+				h := message.TextEquals("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text only starts with the given string", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextEquals("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy now",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the text has surrounding whitespaces", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextEquals("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: " deploy ",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the case of the text differs", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextEquals("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "Deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("TextEqualsFold", func() {
+		Context("when the text is equal to the given string in a different case", func() {
+			It("calls the inner handler", func() {
+				h := message.TextEqualsFold("Deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "DEPLOY",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text is not equal to the given string", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextEqualsFold("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy now",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("TextPrefix", func() {
+		Context("when the text starts with one of the prefixes", func() {
+			It("calls the inner handler", func() {
+				h := message.TextPrefix("deploy ", "release ").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "release v1.0.0",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the prefix contains regexp metacharacters", func() {
+			It("matches them literally", func() {
+				h := message.TextPrefix("a.b").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "axb",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the text does not start with any of the prefixes", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextPrefix("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "please deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the case of the text differs", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextPrefix("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "Deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no prefix is given", func() {
+			It("panics", func() {
+				Expect(func() { message.TextPrefix() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("TextPrefixFold", func() {
+		Context("when the text starts with one of the prefixes in a different case", func() {
+			It("calls the inner handler", func() {
+				h := message.TextPrefixFold("Deploy ").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "DEPLOY production",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text does not start with any of the prefixes", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextPrefixFold("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "please deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("TextSuffix", func() {
+		Context("when the text ends with one of the suffixes", func() {
+			It("calls the inner handler", func() {
+				h := message.TextSuffix("?", "please").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "can you deploy?",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text does not end with any of the suffixes", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextSuffix("deploy").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy now",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the case of the text differs", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextSuffix("please").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy PLEASE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no suffix is given", func() {
+			It("panics", func() {
+				Expect(func() { message.TextSuffix() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("TextSuffixFold", func() {
+		Context("when the text ends with one of the suffixes in a different case", func() {
+			It("calls the inner handler", func() {
+				h := message.TextSuffixFold("Please").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "deploy PLEASE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text does not end with any of the suffixes", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextSuffixFold("please").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "please deploy",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("TextLengthBetween", func() {
+		Context("when the number of runes is within the range", func() {
+			It("calls the inner handler", func() {
+				h := message.TextLengthBetween(3, 5).Wrap(innerHandler)
+				// 5 runes but 15 bytes.
+				e := &slackevents.MessageEvent{
+					Text: "こんにちは",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the text is an emoji shorter than min", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextLengthBetween(2, math.MaxInt32).Wrap(innerHandler)
+				// 1 rune but 4 bytes.
+				e := &slackevents.MessageEvent{
+					Text: "🎉",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the number of runes exceeds max", func() {
+			It("does not call the inner handler", func() {
+				h := message.TextLengthBetween(0, 4).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "こんにちは",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the range is invalid", func() {
+			It("panics", func() {
+				Expect(func() { message.TextLengthBetween(-1, 5) }).To(Panic())
+				Expect(func() { message.TextLengthBetween(5, 3) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("Command", func() {
+		var (
+			received *message.Invocation
+			handler  = message.HandlerFunc(func(ctx context.Context, _ *slackevents.MessageEvent) error {
+				received = message.CommandInvocation(ctx)
+				return nil
+			})
+		)
+		BeforeEach(func() {
+			received = nil
+		})
+
+		Context("when the text is a command with the prefix", func() {
+			It("passes the parsed command to the inner handler", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "!deploy prod east"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received).To(Equal(&message.Invocation{Name: "deploy", Args: "prod east", Argv: []string{"prod", "east"}}))
+			})
+		})
+
+		Context("when the text has extra whitespace", func() {
+			It("ignores it", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "  !deploy \t prod   east \n"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received).To(Equal(&message.Invocation{Name: "deploy", Args: "prod   east", Argv: []string{"prod", "east"}}))
+			})
+		})
+
+		Context("when the arguments are quoted", func() {
+			It("keeps quoted strings together", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: `!say "hello world" 'it is' “fine” --to="team a" ""`})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received.Name).To(Equal("say"))
+				Expect(received.Argv).To(Equal([]string{"hello world", "it is", "fine", "--to=team a", ""}))
+			})
+		})
+
+		Context("when the arguments have apostrophes", func() {
+			It("does not treat them as quotes", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: `!say don't panic, 'it is' fine`})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received.Argv).To(Equal([]string{"don't", "panic,", "it is", "fine"}))
+			})
+		})
+
+		Context("when a quote is not closed", func() {
+			It("does not call the inner handler", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: `!say "hello world`})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(received).To(BeNil())
+			})
+		})
+
+		Context("when the command has no arguments", func() {
+			It("passes an empty argument list", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "!status"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received.Name).To(Equal("status"))
+				Expect(received.Args).To(BeEmpty())
+				Expect(received.Argv).To(BeEmpty())
+			})
+		})
+
+		Context("when the prefix is not followed by a command word", func() {
+			It("does not call the inner handler", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "! deploy"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(received).To(BeNil())
+			})
+		})
+
+		Context("when the text does not start with the prefix", func() {
+			It("does not call the inner handler", func() {
+				h := message.Command("!").Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: "please !deploy"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(received).To(BeNil())
+			})
+		})
+
+		Context("when another tokenizer is given", func() {
+			It("splits arguments with it", func() {
+				h := message.CommandWithTokenizer("bot ", message.SplitFields).Wrap(handler)
+				err := h.HandleMessageEvent(ctx, &slackevents.MessageEvent{Text: `bot say "hello world"`})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received.Name).To(Equal("say"))
+				Expect(received.Argv).To(Equal([]string{`"hello`, `world"`}))
+			})
+		})
+
+		Context("when the prefix is empty", func() {
+			It("panics", func() {
+				Expect(func() { message.Command("") }).To(Panic())
+			})
+		})
+	})
+
+	Describe("Channel", func() {
+		Context("when the message is posted to the given channel", func() {
+			It("calls the inner handler", func() {
+				h := message.Channel("THECHANNEL").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					Channel: "THECHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to a channel other than a given one", func() {
+			It("does not call the inner handler", func() {
+				h := message.Channel("THECHANNEL").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					Channel: "ANOTHERCHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel is given", func() {
+			It("panics", func() {
+				Expect(func() { message.Channel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("InChannel", func() {
+		Context("when the message is posted to the given channel", func() {
+			It("calls the inner handler", func() {
+				h := message.InChannel("THECHANNEL").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					Channel: "THECHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to one of the given channels", func() {
+			It("calls the inner handler", func() {
+				h := message.InChannel("THECHANNEL", "ANOTHERCHANNEL").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					Channel: "ANOTHERCHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to a channel other than the given ones", func() {
+			It("does not call the inner handler", func() {
+				h := message.InChannel("THECHANNEL", "ANOTHERCHANNEL").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					Channel: "YETANOTHERCHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no channel is given", func() {
+			It("panics", func() {
+				Expect(func() { message.InChannel() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ChannelType", func() {
+		Context("when the message is posted to a channel of one of the given types", func() {
+			It("calls the inner handler", func() {
+				h := message.ChannelType("im", "mpim").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "mpim",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to a channel of another type", func() {
+			It("does not call the inner handler", func() {
+				h := message.ChannelType("im", "mpim").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "channel",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the channel type is empty", func() {
+			It("does not call the inner handler", func() {
+				h := message.ChannelType("im", "mpim", "channel", "group").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsDirectMessage", func() {
+		Context("when the message is a direct message", func() {
+			It("calls the inner handler", func() {
+				h := message.IsDirectMessage().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "im",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to a channel", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsDirectMessage().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "channel",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsPrivateChannel", func() {
+		Context("when the message is posted to a private channel", func() {
+			It("calls the inner handler", func() {
+				h := message.IsPrivateChannel().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "group",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to a public channel", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsPrivateChannel().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					ChannelType: "channel",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("FromUser", func() {
+		Context("when the message is posted by one of the given users", func() {
+			It("calls the inner handler", func() {
+				h := message.FromUser("ALICE", "BOB").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "BOB",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by a user other than the given ones", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromUser("ALICE", "BOB").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "CHARLIE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by a bot", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromUser("ALICE").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "B12345",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no user is given", func() {
+			It("panics", func() {
+				Expect(func() { message.FromUser() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("MentionsUser", func() {
+		Context("when the message mentions one of the given users", func() {
+			It("calls the inner handler", func() {
+				h := message.MentionsUser("UONCALL1", "UONCALL2").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "<@UXXXXXXX> <@UONCALL2> the server is down"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message mentions one of the given users with the display name", func() {
+			It("calls the inner handler", func() {
+				h := message.MentionsUser("UONCALL1").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "help <@UONCALL1|alice>"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message does not mention any of the given users", func() {
+			It("does not call the inner handler", func() {
+				h := message.MentionsUser("UONCALL1").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "<@UONCALL10> UONCALL1 <#UONCALL1>"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no user is given", func() {
+			It("panics", func() {
+				Expect(func() { message.MentionsUser() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromBot", func() {
+		Context("when the message is posted by a bot", func() {
+			It("calls the inner handler", func() {
+				h := message.FromBot().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:  "hello",
+					BotID: "B12345",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the subtype of the message is bot_message", func() {
+			It("calls the inner handler", func() {
+				h := message.FromBot().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromBot().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("ExcludeBot", func() {
+		Context("when the message is posted by a bot", func() {
+			It("does not call the inner handler", func() {
+				h := message.ExcludeBot().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "B12345",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeBot().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("FromBotID", func() {
+		Context("when the message is posted by one of the given bots", func() {
+			It("calls the inner handler", func() {
+				h := message.FromBotID("B12345", "B67890").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:  "hello",
+					User:  "UBOT",
+					BotID: "B67890",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by another bot", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromBotID("B12345").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "B67890",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromBotID("B12345").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no bot ID is given", func() {
+			It("panics", func() {
+				Expect(func() { message.FromBotID() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromApp", func() {
+		var (
+			// A synthetic bot_message event from an incoming webhook of an app.
+			webhookMessage = `
+			{
+				"type": "message",
+				"subtype": "bot_message",
+				"channel": "C12345",
+				"text": "PR opened",
+				"bot_id": "B12345",
+				"username": "GitHub",
+				"app_id": "A12345",
+				"ts": "1355517523.000005"
+			}`
+			// A synthetic message event posted by an app with its bot token.
+			appMessage = `
+			{
+				"type": "message",
+				"channel": "C12345",
+				"user": "UBOT",
+				"text": "hello",
+				"bot_id": "B67890",
+				"bot_profile": {"id": "B67890", "app_id": "A67890", "name": "the-app"},
+				"ts": "1355517523.000005"
+			}`
+			parse = func(raw string) (context.Context, *slackevents.MessageEvent) {
+				e := &slackevents.MessageEvent{}
+				Expect(json.Unmarshal([]byte(raw), e)).To(Succeed())
+				return routerutils.WithInnerEvent(ctx, json.RawMessage(raw)), e
+			}
+		)
+
+		Context("when the bot_message is posted by one of the given apps", func() {
+			It("calls the inner handler", func() {
+				h := message.FromApp("A00000", "A12345").Wrap(innerHandler)
+				err := h.HandleMessageEvent(parse(webhookMessage))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by one of the given apps with its bot token", func() {
+			It("calls the inner handler", func() {
+				h := message.FromApp("A67890").Wrap(innerHandler)
+				err := h.HandleMessageEvent(parse(appMessage))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by another app", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromApp("A67890").Wrap(innerHandler)
+				err := h.HandleMessageEvent(parse(webhookMessage))
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromApp("A12345").Wrap(innerHandler)
+				err := h.HandleMessageEvent(parse(`{"type": "message", "user": "ALICE", "text": "hello", "app_id": "A12345"}`))
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the raw event is not available", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromApp("A12345").Wrap(innerHandler)
+				_, e := parse(webhookMessage)
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no App ID is given", func() {
+			It("panics", func() {
+				Expect(func() { message.FromApp() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("FromUsername", func() {
+		Context("when the message is a bot_message posted with one of the given usernames", func() {
+			It("calls the inner handler", func() {
+				h := message.FromUsername("GitHub", "CircleCI").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:     "PR opened",
+					SubType:  "bot_message",
+					BotID:    "B12345",
+					Username: "GitHub",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is a bot_message posted with another username", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromUsername("GitHub").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:     "build passed",
+					SubType:  "bot_message",
+					BotID:    "B67890",
+					Username: "CircleCI",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message has a user", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromUsername("GitHub").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:     "PR opened",
+					SubType:  "bot_message",
+					User:     "UBOT",
+					BotID:    "B12345",
+					Username: "GitHub",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is not a bot_message", func() {
+			It("does not call the inner handler", func() {
+				h := message.FromUsername("GitHub").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:     "PR opened",
+					Username: "GitHub",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no username is given", func() {
+			It("panics", func() {
+				Expect(func() { message.FromUsername() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ExcludeBotID", func() {
+		Context("when the message is posted by one of the given bots", func() {
+			It("does not call the inner handler", func() {
+				h := message.ExcludeBotID("B12345", "B67890").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "B67890",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by another bot", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeBotID("B12345").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "B67890",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeBotID("B12345").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("ChannelNameMatches", func() {
+		var (
+			client *stubConversationClient
+			e      *slackevents.MessageEvent
+		)
+		BeforeEach(func() {
+			client = &stubConversationClient{names: map[string]string{"C12345": "incident-42", "C67890": "general"}}
+			e = &slackevents.MessageEvent{Text: "hello", User: "U12345", Channel: "C12345"}
+		})
+
+		Context("when the channel name matches", func() {
+			It("calls the inner handler", func() {
+				h := message.ChannelNameMatches(regexp.MustCompile(`^incident-`)).Wrap(innerHandler)
+				err := h.HandleMessageEvent(message.WithClient(ctx, client, e), e)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the channel name does not match", func() {
+			It("does not call the inner handler", func() {
+				h := message.ChannelNameMatches(regexp.MustCompile(`^incident-`)).Wrap(innerHandler)
+				e.Channel = "C67890"
+				err := h.HandleMessageEvent(message.WithClient(ctx, client, e), e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the same channel comes again", func() {
+			It("uses the cached name until it expires", func() {
+				h := message.ChannelNameMatchesTTL(regexp.MustCompile(`^incident-`), 50*time.Millisecond).Wrap(innerHandler)
+				for i := 0; i < 3; i++ {
+					Expect(h.HandleMessageEvent(message.WithClient(ctx, client, e), e)).To(Succeed())
+				}
+				Expect(client.numCalled).To(Equal(1))
+
+				time.Sleep(60 * time.Millisecond)
+				Expect(h.HandleMessageEvent(message.WithClient(ctx, client, e), e)).To(Succeed())
+				Expect(client.numCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the message has no channel ID", func() {
+			It("does not call the API nor the inner handler", func() {
+				h := message.ChannelNameMatches(regexp.MustCompile(`.*`)).Wrap(innerHandler)
+				e.Channel = ""
+				err := h.HandleMessageEvent(message.WithClient(ctx, client, e), e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(client.numCalled).To(Equal(0))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no client is given", func() {
+			It("returns ErrNoClient", func() {
+				h := message.ChannelNameMatches(regexp.MustCompile(`^incident-`)).Wrap(innerHandler)
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(stderrors.Is(err, message.ErrNoClient)).To(BeTrue())
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the client cannot get channel information", func() {
+			It("returns an error", func() {
+				h := message.ChannelNameMatches(regexp.MustCompile(`^incident-`)).Wrap(innerHandler)
+				err := h.HandleMessageEvent(message.WithClient(ctx, &stubEphemeralPoster{}, e), e)
+				Expect(err).To(HaveOccurred())
+				Expect(err).NotTo(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IdempotencyKey", func() {
+		var newEvent = func() *slackevents.MessageEvent {
+			return &slackevents.MessageEvent{
+				Type:           "message",
+				Text:           "hello",
+				User:           "U12345",
+				Channel:        "C12345",
+				TimeStamp:      "1355517523.000005",
+				EventTimeStamp: "1355517523.000005",
+			}
+		}
+
+		It("returns the same key for identical events", func() {
+			Expect(message.IdempotencyKey(newEvent())).To(Equal(message.IdempotencyKey(newEvent())))
+		})
+
+		It("returns different keys for different messages", func() {
+			base := message.IdempotencyKey(newEvent())
+			otherTs := newEvent()
+			otherTs.TimeStamp = "1355517523.000006"
+			otherChannel := newEvent()
+			otherChannel.Channel = "C67890"
+			otherUser := newEvent()
+			otherUser.User = "U67890"
+			otherTeam := newEvent()
+			otherTeam.SourceTeam = "T67890"
+			keys := []string{
+				base,
+				message.IdempotencyKey(otherTs),
+				message.IdempotencyKey(otherChannel),
+				message.IdempotencyKey(otherUser),
+				message.IdempotencyKey(otherTeam),
+			}
+			seen := make(map[string]bool)
+			for _, k := range keys {
+				Expect(seen).NotTo(HaveKey(k))
+				seen[k] = true
+			}
+		})
+
+		It("uses the bot ID when the message has no user", func() {
+			e1 := newEvent()
+			e1.User = ""
+			e1.BotID = "B12345"
+			e2 := newEvent()
+			e2.User = ""
+			e2.BotID = "B67890"
+			Expect(message.IdempotencyKey(e1)).NotTo(Equal(message.IdempotencyKey(e2)))
+		})
+	})
+
+	Describe("PostEphemeral", func() {
+		Context("when a client is given", func() {
+			It("posts an ephemeral message to the author in the channel", func() {
+				client := &stubEphemeralPoster{}
+				e := &slackevents.MessageEvent{Text: "hello", User: "U12345", Channel: "C12345"}
+				ts, err := message.PostEphemeral(message.WithClient(ctx, client, e), "hi there")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ts).To(Equal("1234567890.123456"))
+				Expect(client.channelID).To(Equal("C12345"))
+				Expect(client.userID).To(Equal("U12345"))
+				Expect(client.values.Get("text")).To(Equal("hi there"))
+				Expect(client.values.Get("thread_ts")).To(BeEmpty())
+			})
+		})
+
+		Context("when the message is in a thread", func() {
+			It("posts an ephemeral message in the thread", func() {
+				client := &stubEphemeralPoster{}
+				e := &slackevents.MessageEvent{Text: "hello", User: "U12345", Channel: "C12345", ThreadTimeStamp: "1355517523.000005"}
+				_, err := message.PostEphemeral(message.WithClient(ctx, client, e), "hi there")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(client.values.Get("thread_ts")).To(Equal("1355517523.000005"))
+			})
+		})
+
+		Context("when no client is given", func() {
+			It("returns ErrNoClient", func() {
+				_, err := message.PostEphemeral(ctx, "hi there")
+				Expect(err).To(MatchError(message.ErrNoClient))
+			})
+		})
+	})
+
+	Describe("IsExternalShared", func() {
+		var sharedMessage = func(userTeam string) *slackevents.MessageEvent {
+			return &slackevents.MessageEvent{
+				Text:       "hello",
+				User:       "U12345",
+				Channel:    "C12345",
+				UserTeam:   userTeam,
+				SourceTeam: userTeam,
+			}
+		}
+
+		Context("when the message is posted by a user of another workspace", func() {
+			It("calls the inner handler", func() {
+				h := message.IsExternalShared().Wrap(innerHandler)
+				err := h.HandleMessageEvent(message.WithTeamID(ctx, "THOME"), sharedMessage("TEXTERNAL"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by a user of the receiving workspace", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsExternalShared().Wrap(innerHandler)
+				err := h.HandleMessageEvent(message.WithTeamID(ctx, "THOME"), sharedMessage("THOME"))
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the channel is not shared", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsExternalShared().Wrap(innerHandler)
+				err := h.HandleMessageEvent(message.WithTeamID(ctx, "THOME"), sharedMessage(""))
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the receiving workspace is unknown", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsExternalShared().Wrap(innerHandler)
+				err := h.HandleMessageEvent(ctx, sharedMessage("TEXTERNAL"))
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("SourceTeam", func() {
+		Context("when the message is posted from one of the given workspaces", func() {
+			It("calls the inner handler", func() {
+				h := message.SourceTeam("T12345", "T67890").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "hello", UserTeam: "T67890", SourceTeam: "T67890"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted from another workspace", func() {
+			It("does not call the inner handler", func() {
+				h := message.SourceTeam("T12345").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "hello", UserTeam: "T67890", SourceTeam: "T67890"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no team ID is given", func() {
+			It("panics", func() {
+				Expect(func() { message.SourceTeam() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ExcludeSelf", func() {
+		Context("when the message is posted by the bot user", func() {
+			It("does not call the inner handler", func() {
+				h := message.ExcludeSelf("UBOT", "BBOT").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:  "hello",
+					User:  "UBOT",
+					BotID: "BBOT",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message has the bot's bot ID but no user", func() {
+			It("does not call the inner handler", func() {
+				h := message.ExcludeSelf("UBOT", "BBOT").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "BBOT",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by another bot", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeSelf("UBOT", "BBOT").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "BOTHER",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted by a user", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeSelf("UBOT", "BBOT").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when botID is empty", func() {
+			It("checks botUserID only", func() {
+				h := message.ExcludeSelf("UBOT", "").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "BBOT",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when botUserID is empty", func() {
+			It("panics", func() {
+				Expect(func() { message.ExcludeSelf("", "BBOT") }).To(Panic())
+			})
+		})
+	})
+
+	Describe("ExcludeSeen", func() {
+		Context("when the message has not been seen", func() {
+			It("calls the inner handler", func() {
+				h := message.ExcludeSeen(dedup.NewMemoryStore(10, 0)).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:        "hello",
+					User:        "ALICE",
+					ClientMsgID: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when a message with the same client_msg_id has been seen", func() {
+			It("does not call the inner handler", func() {
+				h := message.ExcludeSeen(dedup.NewMemoryStore(10, 0)).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:        "hello",
+					User:        "ALICE",
+					ClientMsgID: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+				}
+				Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the inner handler fails", func() {
+			It("does not record the message", func() {
+				failed := false
+				h := message.ExcludeSeen(dedup.NewMemoryStore(10, 0)).Wrap(message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+					numHandlerCalled++
+					if !failed {
+						failed = true
+						return stderrors.New("temporary error")
+					}
+					return nil
+				}))
+				e := &slackevents.MessageEvent{
+					Text:        "hello",
+					User:        "ALICE",
+					ClientMsgID: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+				}
+				Expect(h.HandleMessageEvent(ctx, e)).To(MatchError("temporary error"))
+				Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(h.HandleMessageEvent(ctx, e)).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the predicate is evaluated by Any", func() {
+			It("does not record the message", func() {
+				store := dedup.NewMemoryStore(10, 0)
+				h := message.Any(message.ExcludeSeen(store)).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:        "hello",
+					User:        "ALICE",
+					ClientMsgID: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+				}
+				Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(store.Contains("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee")).To(BeFalse())
+			})
+		})
+
+		Context("when the message has no client_msg_id", func() {
+			It("always calls the inner handler", func() {
+				h := message.ExcludeSeen(dedup.NewMemoryStore(10, 0)).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "hello",
+					SubType: "bot_message",
+					BotID:   "BBOT",
+				}
+				Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(h.HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the store is nil", func() {
+			It("panics", func() {
+				Expect(func() { message.ExcludeSeen(nil) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("SubType", func() {
+		Context("when the subtype of themessage equals to the given one", func() {
+			It("calls the inner handler", func() {
+				h := message.SubType("channel_join").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "channel_join",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the subtype of the message differs from the given one", func() {
+			It("does not call the inner handler", func() {
+				h := message.SubType("channel_join").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "pinned_channel",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the subtype of the message is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := message.SubType("channel_join", "channel_leave").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "channel_leave",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message has no subtype", func() {
+			It("does not call the inner handler", func() {
+				h := message.SubType("channel_join").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no subtype is given", func() {
+			It("panics", func() {
+				Expect(func() { message.SubType() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("NoSubType", func() {
+		Context("when the message has no subtype", func() {
+			It("calls the inner handler", func() {
+				h := message.NoSubType().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text: "hello",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is a system message", func() {
+			It("does not call the inner handler", func() {
+				h := message.NoSubType().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Text:    "<@U12345> has joined the channel",
+					SubType: "channel_join",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsThreadReply", func() {
+		Context("when the message is a reply in a thread", func() {
+			It("calls the inner handler", func() {
+				h := message.IsThreadReply().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp:       "1355517523.000008",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is the parent of a thread", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsThreadReply().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp:       "1355517523.000005",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is not in a thread", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsThreadReply().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsTopLevel", func() {
+		Context("when the message is not in a thread", func() {
+			It("calls the inner handler", func() {
+				h := message.IsTopLevel().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is the parent of a thread", func() {
+			It("calls the inner handler", func() {
+				h := message.IsTopLevel().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp:       "1355517523.000005",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is a reply in a thread", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsTopLevel().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp:       "1355517523.000008",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsThreadBroadcast", func() {
+		Context("when the message is a reply that is also sent to the channel", func() {
+			It("calls the inner handler", func() {
+				h := message.IsThreadBroadcast().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType:         "thread_broadcast",
+					TimeStamp:       "1355517523.000008",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is an ordinary reply", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsThreadBroadcast().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					TimeStamp:       "1355517523.000008",
+					ThreadTimeStamp: "1355517523.000005",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("IsEdit", func() {
+		var (
+			edited *slackevents.MessageEvent
+		)
+		BeforeEach(func() {
+			edited = &slackevents.MessageEvent{}
+			err := json.Unmarshal([]byte(`
+			{
+				"type": "message",
+				"subtype": "message_changed",
+				"hidden": true,
+				"channel": "C123ABC456",
+				"ts": "1358878755.000001",
+				"event_ts": "1358878755.000001",
+				"message": {
+					"type": "message",
+					"user": "U12345",
+					"text": "deploy to production",
+					"ts": "1358878749.000002",
+					"edited": {"user": "U12345", "ts": "1358878755.000001"}
+				},
+				"previous_message": {
+					"type": "message",
+					"user": "U12345",
+					"text": "deploy to staging",
+					"ts": "1358878749.000002"
+				}
+			}`), edited)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the message is an edit", func() {
+			It("calls the inner handler", func() {
+				h := message.IsEdit().Wrap(innerHandler)
+				err := h.HandleMessageEvent(ctx, edited)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(edited.Text).To(BeEmpty())
+				Expect(message.EditedText(edited)).To(Equal("deploy to production"))
+				Expect(message.PreviousText(edited)).To(Equal("deploy to staging"))
+			})
+		})
+
+		Context("when the message is a new one", func() {
+			It("does not call the inner handler", func() {
+				h := message.IsEdit().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "deploy to production"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+				Expect(message.EditedText(e)).To(Equal("deploy to production"))
+				Expect(message.PreviousText(e)).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("HasFiles", func() {
+		Context("when the message has files", func() {
+			It("calls the inner handler", func() {
+				h := message.HasFiles().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "file_share",
+					Files:   []slackevents.File{{ID: "F12345678", Filetype: "png"}},
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message has no files", func() {
+			It("does not call the inner handler", func() {
+				h := message.HasFiles().Wrap(innerHandler)
+				e := &slackevents.MessageEvent{Text: "hello"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("HasFileType", func() {
+		Context("when the message has a file whose filetype is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := message.HasFileType("png", "pdf").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "file_share",
+					Files: []slackevents.File{
+						{ID: "F12345678", Filetype: "text", Mimetype: "text/plain"},
+						{ID: "F87654321", Filetype: "pdf", Mimetype: "application/pdf"},
+					},
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message has a file whose MIME type is one of the given ones", func() {
+			It("calls the inner handler", func() {
+				h := message.HasFileType("image/png").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "file_share",
+					Files:   []slackevents.File{{ID: "F12345678", Filetype: "png", Mimetype: "image/png"}},
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when none of the files is of the given types", func() {
+			It("does not call the inner handler", func() {
+				h := message.HasFileType("png", "pdf").Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					SubType: "file_share",
+					Files:   []slackevents.File{{ID: "F12345678", Filetype: "text", Mimetype: "text/plain"}},
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no type is given", func() {
+			It("panics", func() {
+				Expect(func() { message.HasFileType() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("WithinTimeRange", func() {
+		var (
+			jst   = time.FixedZone("Asia/Tokyo", 9*60*60)
+			clock = func(hour, min int) time.Time {
+				return time.Date(2000, 1, 1, hour, min, 0, 0, jst)
+			}
+			// 2021-08-01 09:00:00 +09:00
+			nineAM = time.Date(2021, 8, 1, 9, 0, 0, 0, jst).Unix()
+			ts     = func(sec int64) string {
+				return fmt.Sprintf("%d.000100", sec)
+			}
+		)
+
+		Context("when the message is posted at the start of the window", func() {
+			It("calls the inner handler", func() {
+				h := message.WithinTimeRange(clock(9, 0), clock(18, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: fmt.Sprintf("%d", nineAM)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted just before the window", func() {
+			It("does not call the inner handler", func() {
+				h := message.WithinTimeRange(clock(9, 0), clock(18, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: ts(nineAM - 1)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted at the end of the window", func() {
+			It("does not call the inner handler", func() {
+				h := message.WithinTimeRange(clock(9, 0), clock(18, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: fmt.Sprintf("%d", nineAM+9*60*60)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the window spans midnight", func() {
+			It("calls the inner handler at night", func() {
+				h := message.WithinTimeRange(clock(22, 0), clock(6, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: ts(nineAM - 4*60*60)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+
+			It("does not call the inner handler in the daytime", func() {
+				h := message.WithinTimeRange(clock(22, 0), clock(6, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: ts(nineAM)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the bounds are given in another location", func() {
+			It("uses their wall clock in loc", func() {
+				utcClock := func(hour, min int) time.Time {
+					return time.Date(2000, 1, 1, hour, min, 0, 0, time.UTC)
+				}
+				h := message.WithinTimeRange(utcClock(9, 0), utcClock(18, 0), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: ts(nineAM)}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when loc observes daylight saving time", func() {
+			It("follows the wall clock in both standard and daylight saving time", func() {
+				newYork, err := time.LoadLocation("America/New_York")
+				Expect(err).NotTo(HaveOccurred())
+				h := message.WithinTimeRange(clock(9, 0), clock(17, 0), newYork).Wrap(innerHandler)
+
+				// 09:30 EST (-05:00) and 09:30 EDT (-04:00)
+				winter := time.Date(2021, 1, 15, 9, 30, 0, 0, newYork).Unix()
+				summer := time.Date(2021, 7, 15, 9, 30, 0, 0, newYork).Unix()
+				Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: ts(winter)})).To(Succeed())
+				Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: ts(summer)})).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(2))
+
+				// 08:30 EDT, which is 09:30 in EST
+				early := time.Date(2021, 7, 15, 8, 30, 0, 0, newYork).Unix()
+				Expect(h.HandleMessageEvent(ctx, &slackevents.MessageEvent{TimeStamp: ts(early)})).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the timestamp is malformed", func() {
+			It("does not call the inner handler", func() {
+				h := message.WithinTimeRange(clock(0, 0), clock(23, 59), jst).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{TimeStamp: "yesterday"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("EventTime", func() {
+		It("parses the timestamp of the message", func() {
+			t, err := message.EventTime(&slackevents.MessageEvent{TimeStamp: "1581106241.371594"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Equal(time.Unix(1581106241, 371594000))).To(BeTrue())
+		})
+
+		It("returns an error if the timestamp is malformed", func() {
+			for _, ts := range []string{"", "abc", "1581106241.37x", "1581106241.-1"} {
+				_, err := message.EventTime(&slackevents.MessageEvent{TimeStamp: ts})
+				Expect(err).To(HaveOccurred(), "timestamp: %q", ts)
+			}
+		})
+	})
+
+	Describe("ParseTimestamp", func() {
+		It("parses a timestamp with microseconds", func() {
+			t, err := message.ParseTimestamp("1581106241.371594")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Equal(time.Unix(1581106241, 371594000))).To(BeTrue())
+		})
+
+		It("parses a timestamp without the fractional part", func() {
+			t, err := message.ParseTimestamp("1581106241")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Equal(time.Unix(1581106241, 0))).To(BeTrue())
+		})
+
+		It("ignores digits beyond nanoseconds", func() {
+			t, err := message.ParseTimestamp("1581106241.1234567891")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.Equal(time.Unix(1581106241, 123456789))).To(BeTrue())
+		})
+
+		It("returns an error if the timestamp is empty", func() {
+			_, err := message.ParseTimestamp("")
+			Expect(err).To(MatchError(MatchRegexp("empty")))
+		})
+	})
+
+	Describe("FormatTimestamp", func() {
+		It("formats the time in the same way as Slack", func() {
+			Expect(message.FormatTimestamp(time.Unix(1581106241, 371594999))).To(Equal("1581106241.371594"))
+			Expect(message.FormatTimestamp(time.Unix(1581106241, 1000))).To(Equal("1581106241.000001"))
+		})
+
+		It("round-trips with ParseTimestamp", func() {
+			for _, ts := range []string{"1581106241.371594", "1581106241.000000", "0.000001"} {
+				t, err := message.ParseTimestamp(ts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(message.FormatTimestamp(t)).To(Equal(ts))
+			}
+		})
+	})
+
+	Describe("Not", func() {
+		Context("when the inner predicate does not match to the message", func() {
+			It("calls the inner handler", func() {
+				h := message.Not(message.InChannel("THECHANNEL")).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Channel: "ANOTHERCHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the inner predicate matches to the message", func() {
+			It("does not call the inner handler", func() {
+				h := message.Not(message.InChannel("THECHANNEL")).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Channel: "THECHANNEL",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the inner predicate returns an error", func() {
+			It("returns the error without calling the inner handler", func() {
+				theError := fmt.Errorf("something wrong happened")
+				failing := predicateFunc(func(_ message.Handler) message.Handler {
+					return message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+						return theError
+					})
+				})
+				h := message.Not(failing).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(theError))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Where", func() {
+		var (
+			h message.Handler
+		)
+		BeforeEach(func() {
+			h = message.Where().InChannel("C12345", "C67890").NotThreaded().FromHuman().Build(innerHandler)
+		})
+
+		Context("when all the conditions are satisfied", func() {
+			It("calls the inner handler", func() {
+				e := &slackevents.MessageEvent{Text: "hello", Channel: "C67890", User: "ALICE", TimeStamp: "1355517523.000005"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the message is posted to another channel", func() {
+			It("does not call the inner handler", func() {
+				e := &slackevents.MessageEvent{Text: "hello", Channel: "C99999", User: "ALICE", TimeStamp: "1355517523.000005"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is a thread reply", func() {
+			It("does not call the inner handler", func() {
+				e := &slackevents.MessageEvent{Text: "hello", Channel: "C12345", User: "ALICE", TimeStamp: "1355517523.000005", ThreadTimeStamp: "1355517500.000001"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the message is posted by a bot", func() {
+			It("does not call the inner handler", func() {
+				e := &slackevents.MessageEvent{Text: "hello", Channel: "C12345", BotID: "B12345", SubType: "bot_message", TimeStamp: "1355517523.000005"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when a partially built one is extended in different ways", func() {
+			It("does not share the predicates between them", func() {
+				base := message.Where().InChannel("C12345")
+				fromAlice := base.FromUser("ALICE")
+				fromBob := base.FromUser("BOB")
+				Expect(base.Predicates()).To(HaveLen(1))
+				Expect(fromAlice.Predicates()).To(HaveLen(2))
+				Expect(fromBob.Predicates()).To(HaveLen(2))
+
+				e := &slackevents.MessageEvent{Text: "hello", Channel: "C12345", User: "ALICE"}
+				Expect(fromAlice.Build(innerHandler).HandleMessageEvent(ctx, e)).To(Succeed())
+				Expect(fromBob.Build(innerHandler).HandleMessageEvent(ctx, e)).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("Require", func() {
+		Context("when the given predicate matches", func() {
+			It("calls the inner handler", func() {
+				h := message.Require(message.FromUser("UADMIN"), errors.ErrForbidden).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{User: "UADMIN"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the given predicate does not match", func() {
+			It("returns the given error", func() {
+				h := message.Require(message.FromUser("UADMIN"), errors.ErrForbidden).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{User: "UGUEST"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.ErrForbidden))
+				Expect(errors.HTTPStatus(err)).To(Equal(http.StatusForbidden))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the inner handler returns NotInterested", func() {
+			It("does not replace the error", func() {
+				h := message.Require(message.FromUser("UADMIN"), errors.ErrForbidden).Wrap(
+					message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+						return errors.NotInterested
+					}))
+				e := &slackevents.MessageEvent{User: "UADMIN"}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+			})
+		})
+
+		Context("when onFail is nil", func() {
+			It("panics", func() {
+				Expect(func() { message.Require(message.FromUser("UADMIN"), nil) }).To(Panic())
+			})
+		})
+	})
+
+	Describe("Any", func() {
+		Context("when none of the predicates matches to the message", func() {
+			It("does not call the inner handler", func() {
+				h := message.Any(
+					message.InChannel("THECHANNEL"),
+					message.FromUser("ALICE"),
+				).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Channel: "ANOTHERCHANNEL",
+					User:    "BOB",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the first predicate matches to the message", func() {
+			It("calls the inner handler exactly once", func() {
+				h := message.Any(
+					message.InChannel("THECHANNEL"),
+					message.FromUser("ALICE"),
+				).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Channel: "THECHANNEL",
+					User:    "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when only the last predicate matches to the message", func() {
+			It("calls the inner handler exactly once", func() {
+				h := message.Any(
+					message.InChannel("THECHANNEL"),
+					message.FromUser("ALICE"),
+				).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					Channel: "ANOTHERCHANNEL",
+					User:    "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+			})
+		})
+
+		Context("when one of the predicates returns an error", func() {
+			It("returns the error without evaluating the rest", func() {
+				theError := fmt.Errorf("something wrong happened")
+				failing := predicateFunc(func(_ message.Handler) message.Handler {
+					return message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+						return theError
+					})
+				})
+				h := message.Any(failing, message.FromUser("ALICE")).Wrap(innerHandler)
+				e := &slackevents.MessageEvent{
+					User: "ALICE",
+				}
+				err := h.HandleMessageEvent(ctx, e)
+				Expect(err).To(Equal(theError))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no predicate is given", func() {
+			It("panics", func() {
+				Expect(func() { message.Any() }).To(Panic())
+			})
+		})
+	})
+})
+
+func BenchmarkTextContains(b *testing.B) {
+	h := message.TextContains("deploy", "release").Wrap(nopHandler)
+	benchmarkPredicate(b, h)
+}
+
+func BenchmarkTextRegexp(b *testing.B) {
+	h := message.TextRegexp(regexp.MustCompile(`deploy|release`)).Wrap(nopHandler)
+	benchmarkPredicate(b, h)
+}
+
+var nopHandler = message.HandlerFunc(func(_ context.Context, _ *slackevents.MessageEvent) error {
+	return nil
+})
+
+func benchmarkPredicate(b *testing.B, h message.Handler) {
+	ctx := context.Background()
+	e := &slackevents.MessageEvent{
+		Text: "could you please release the latest version of the service to the production environment?",
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = h.HandleMessageEvent(ctx, e)
+	}
+}
+
+type predicateFunc func(message.Handler) message.Handler
+
+func (f predicateFunc) Wrap(h message.Handler) message.Handler {
+	return f(h)
+}
+
+type stubEphemeralPoster struct {
+	channelID string
+	userID    string
+	values    url.Values
+}
+
+func (p *stubEphemeralPoster) PostEphemeralContext(_ context.Context, channelID, userID string, options ...slack.MsgOption) (string, error) {
+	p.channelID = channelID
+	p.userID = userID
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", err
+	}
+	p.values = values
+	return "1234567890.123456", nil
+}
+
+type stubConversationClient struct {
+	stubEphemeralPoster
+	names     map[string]string
+	numCalled int
+}
+
+func (c *stubConversationClient) GetConversationInfoContext(_ context.Context, channelID string, _ bool) (*slack.Channel, error) {
+	c.numCalled++
+	ch := &slack.Channel{}
+	ch.ID = channelID
+	ch.Name = c.names[channelID]
+	return ch, nil
+}
@@ -7,9 +7,15 @@ package reaction
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/predicates"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
@@ -43,17 +49,22 @@ type Predicate interface {
 }
 
 type namePredicate struct {
-	reaction string
+	reactions []string
 }
 
-// Name is a predicate that is considered to be "true" if and only if a reaction name equals to the given one.
-func Name(reaction string) Predicate {
-	return &namePredicate{reaction: reaction}
+// Name is a predicate that is considered to be "true" if and only if a reaction name equals to any of the given ones.
+//
+// Reaction names do not contain surrounding colons, e.g. `thumbsup` rather than `:thumbsup:`.
+func Name(reactions ...string) Predicate {
+	if len(reactions) == 0 {
+		panic("reaction.Name: at least one reaction must be given")
+	}
+	return &namePredicate{reactions: reactions}
 }
 
 func (p *namePredicate) WrapAdded(h AddedHandler) AddedHandler {
 	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
-		if p.reaction != e.Reaction {
+		if !contains(p.reactions, e.Reaction) {
 			return errors.NotInterested
 		}
 		return h.HandleReactionAddedEvent(ctx, e)
@@ -62,7 +73,7 @@ func (p *namePredicate) WrapAdded(h AddedHandler) AddedHandler {
 
 func (p *namePredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
 	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
-		if p.reaction != e.Reaction {
+		if !contains(p.reactions, e.Reaction) {
 			return errors.NotInterested
 		}
 		return h.HandleReactionRemovedEvent(ctx, e)
@@ -161,6 +172,309 @@ func (p *itemUserPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
 	})
 }
 
+type itemTypePredicate struct {
+	types []string
+}
+
+// ItemType is a predicate that is considered to be "true" if and only if the type of the reacted item equals to any of the given ones (e.g. `message` or `file`).
+func ItemType(types ...string) Predicate {
+	if len(types) == 0 {
+		panic("reaction.ItemType: at least one item type must be given")
+	}
+	return &itemTypePredicate{types: types}
+}
+
+func (p *itemTypePredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		if !contains(p.types, e.Item.Type) {
+			return errors.NotInterested
+		}
+		return h.HandleReactionAddedEvent(ctx, e)
+	})
+}
+
+func (p *itemTypePredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		if !contains(p.types, e.Item.Type) {
+			return errors.NotInterested
+		}
+		return h.HandleReactionRemovedEvent(ctx, e)
+	})
+}
+
+type userPredicate struct {
+	ids []string
+}
+
+// User is a predicate that is considered to be "true" if and only if the user who added or removed the reaction is any of the given ones.
+//
+// See also ItemUser, which matches the author of the reacted item instead.
+func User(ids ...string) Predicate {
+	if len(ids) == 0 {
+		panic("reaction.User: at least one user ID must be given")
+	}
+	return &userPredicate{ids: ids}
+}
+
+func (p *userPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		if !contains(p.ids, e.User) {
+			return errors.NotInterested
+		}
+		return h.HandleReactionAddedEvent(ctx, e)
+	})
+}
+
+func (p *userPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		if !contains(p.ids, e.User) {
+			return errors.NotInterested
+		}
+		return h.HandleReactionRemovedEvent(ctx, e)
+	})
+}
+
+type notPredicate struct {
+	pred Predicate
+}
+
+// Not is a predicate that is considered to be "true" if and only if the given Predicate is considered to be "false".
+//
+// If the given Predicate returns an error other than `errors.NotInterested`, the error is returned as is.
+func Not(pred Predicate) Predicate {
+	return &notPredicate{pred: pred}
+}
+
+func (p *notPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		return predicates.Not(ctx, probeAdded(p.pred, e), handleAdded(h, e))
+	})
+}
+
+func (p *notPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		return predicates.Not(ctx, probeRemoved(p.pred, e), handleRemoved(h, e))
+	})
+}
+
+type anyPredicate struct {
+	preds []Predicate
+}
+
+// Any is a predicate that is considered to be "true" if and only if at least one of the given Predicates is considered to be "true".
+//
+// The given Predicates are evaluated in order without calling the inner handler, and the evaluation stops at the first one that is considered to be "true".
+// Then the inner handler is called exactly once.
+// If one of the given Predicates returns an error other than `errors.NotInterested`, the evaluation stops and the error is returned as is.
+//
+// It panics if no Predicate is given.
+func Any(preds ...Predicate) Predicate {
+	if len(preds) == 0 {
+		panic("reaction.Any: at least one predicate must be given")
+	}
+	return &anyPredicate{preds: preds}
+}
+
+func (p *anyPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		probes := make([]predicates.Probe, 0, len(p.preds))
+		for _, pred := range p.preds {
+			probes = append(probes, probeAdded(pred, e))
+		}
+		return predicates.Any(ctx, probes, handleAdded(h, e))
+	})
+}
+
+func (p *anyPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		probes := make([]predicates.Probe, 0, len(p.preds))
+		for _, pred := range p.preds {
+			probes = append(probes, probeRemoved(pred, e))
+		}
+		return predicates.Any(ctx, probes, handleRemoved(h, e))
+	})
+}
+
+// ReactionsGetter is a client that gets reactions to items. `*slack.Client` implements this.
+type ReactionsGetter interface {
+	GetReactionsContext(ctx context.Context, item slack.ItemRef, params slack.GetReactionsParameters) ([]slack.ItemReaction, error)
+}
+
+// ErrNoClient is returned from predicates that call Slack APIs when no client is given.
+var ErrNoClient = stderrors.New("no client is given to the Router")
+
+type contextKey int
+
+const (
+	clientKey contextKey = iota
+)
+
+// WithClient returns a copy of ctx that carries the client, which is used by predicates such as CountAtLeast.
+//
+// `eventrouter.Router.OnReactionAdded` and `eventrouter.Router.OnReactionRemoved` set this automatically if `eventrouter.WithClient` is given,
+// so this is needed only when handlers are called without the Router, e.g. in tests.
+func WithClient(ctx context.Context, client ReactionsGetter) context.Context {
+	return context.WithValue(ctx, clientKey, client)
+}
+
+// DefaultCountTTL is how long CountAtLeast caches reaction counts.
+const DefaultCountTTL = 5 * time.Second
+
+type reactionCount struct {
+	count   int
+	expires time.Time
+}
+
+type countAtLeastPredicate struct {
+	n      int
+	ttl    time.Duration
+	mu     sync.Mutex
+	counts map[string]reactionCount
+}
+
+// CountAtLeast is a predicate that is considered to be "true" if and only if the reacted item has at least n reactions of the emoji in the event,
+// e.g. to pin a message when 5 people react to it with `:pushpin:` (combine this with Name to limit emojis).
+//
+// Events do not carry the total counts, so it gets them by calling `reactions.get` with the client given to WithClient (or `eventrouter.WithClient`).
+// Counts are cached for DefaultCountTTL to avoid calling the API for each of rapid reactions; use CountAtLeastTTL to change it. Each predicate has its own cache.
+// A cached count is used only if the event cannot change the result, i.e. a count below n is fetched again for `reaction_added` events
+// and a count of n or more is fetched again for `reaction_removed` events, so the threshold is never missed because of the cache.
+//
+// Items other than messages, files, and file comments are never considered to be "true".
+// If no client is given, it returns an error that equals to ErrNoClient in the sense of `errors.Is`.
+// Errors from the API are also returned as they are.
+//
+// It panics if n is not positive.
+func CountAtLeast(n int) Predicate {
+	return CountAtLeastTTL(n, DefaultCountTTL)
+}
+
+// CountAtLeastTTL is the same as CountAtLeast, except that reaction counts are cached for ttl.
+//
+// It panics if n or ttl is not positive.
+func CountAtLeastTTL(n int, ttl time.Duration) Predicate {
+	if n <= 0 {
+		panic("reaction.CountAtLeast: n must be positive")
+	}
+	if ttl <= 0 {
+		panic("reaction.CountAtLeast: ttl must be positive")
+	}
+	return &countAtLeastPredicate{n: n, ttl: ttl, counts: make(map[string]reactionCount)}
+}
+
+func (p *countAtLeastPredicate) WrapAdded(h AddedHandler) AddedHandler {
+	return AddedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionAddedEvent) error {
+		count, err := p.count(ctx, &e.Item, e.Reaction, func(cached int) bool { return cached >= p.n })
+		if err != nil {
+			return err
+		}
+		if count < p.n {
+			return errors.NotInterested
+		}
+		return h.HandleReactionAddedEvent(ctx, e)
+	})
+}
+
+func (p *countAtLeastPredicate) WrapRemoved(h RemovedHandler) RemovedHandler {
+	return RemovedHandlerFunc(func(ctx context.Context, e *slackevents.ReactionRemovedEvent) error {
+		count, err := p.count(ctx, &e.Item, e.Reaction, func(cached int) bool { return cached < p.n })
+		if err != nil {
+			return err
+		}
+		if count < p.n {
+			return errors.NotInterested
+		}
+		return h.HandleReactionRemovedEvent(ctx, e)
+	})
+}
+
+// count returns the number of the reactions to the item. The cached count is used only if trust returns true for it.
+func (p *countAtLeastPredicate) count(ctx context.Context, item *slackevents.Item, name string, trust func(int) bool) (int, error) {
+	ref, key, ok := itemRef(item)
+	if !ok {
+		return 0, errors.NotInterested
+	}
+	key = key + ":" + name
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.counts[key]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) && trust(cached.count) {
+		return cached.count, nil
+	}
+
+	client, ok := ctx.Value(clientKey).(ReactionsGetter)
+	if !ok || client == nil {
+		return 0, fmt.Errorf("reaction.CountAtLeast requires a client: %w", ErrNoClient)
+	}
+	reactions, err := client.GetReactionsContext(ctx, ref, slack.NewGetReactionsParameters())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reactions to the item: %w", err)
+	}
+	count := 0
+	for _, r := range reactions {
+		if r.Name == name {
+			count = r.Count
+			break
+		}
+	}
+	p.mu.Lock()
+	for k, c := range p.counts {
+		if !now.Before(c.expires) {
+			delete(p.counts, k)
+		}
+	}
+	p.counts[key] = reactionCount{count: count, expires: now.Add(p.ttl)}
+	p.mu.Unlock()
+	return count, nil
+}
+
+// itemRef returns the reference to the item and the key that identifies it.
+func itemRef(item *slackevents.Item) (slack.ItemRef, string, bool) {
+	switch {
+	case item.Type == "message" && item.Channel != "" && item.Timestamp != "":
+		return slack.NewRefToMessage(item.Channel, item.Timestamp), "message:" + item.Channel + ":" + item.Timestamp, true
+	case item.Type == "file" && item.File != nil:
+		return slack.NewRefToFile(item.File.ID), "file:" + item.File.ID, true
+	case item.Type == "file_comment" && item.Comment != nil:
+		return slack.NewRefToComment(item.Comment.ID), "file_comment:" + item.Comment.ID, true
+	default:
+		return slack.ItemRef{}, "", false
+	}
+}
+
+// probeAdded converts the given Predicate into a Probe for the `reaction_added` event.
+func probeAdded(pred Predicate, e *slackevents.ReactionAddedEvent) predicates.Probe {
+	return func(ctx context.Context, next func(context.Context) error) error {
+		return pred.WrapAdded(AddedHandlerFunc(func(innerCtx context.Context, _ *slackevents.ReactionAddedEvent) error {
+			return next(innerCtx)
+		})).HandleReactionAddedEvent(ctx, e)
+	}
+}
+
+// probeRemoved converts the given Predicate into a Probe for the `reaction_removed` event.
+func probeRemoved(pred Predicate, e *slackevents.ReactionRemovedEvent) predicates.Probe {
+	return func(ctx context.Context, next func(context.Context) error) error {
+		return pred.WrapRemoved(RemovedHandlerFunc(func(innerCtx context.Context, _ *slackevents.ReactionRemovedEvent) error {
+			return next(innerCtx)
+		})).HandleReactionRemovedEvent(ctx, e)
+	}
+}
+
+// handleAdded returns a function that calls h with the event.
+func handleAdded(h AddedHandler, e *slackevents.ReactionAddedEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return h.HandleReactionAddedEvent(ctx, e)
+	}
+}
+
+// handleRemoved returns a function that calls h with the event.
+func handleRemoved(h RemovedHandler, e *slackevents.ReactionRemovedEvent) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return h.HandleReactionRemovedEvent(ctx, e)
+	}
+}
+
 // BuildAdded decorates `AddedHandler` `h` with the given Predicates and returns a new Handler that calls the original handler `h` if and only if all the given Predicates are considered to be "true".
 func BuildAdded(h AddedHandler, preds ...Predicate) AddedHandler {
 	for _, p := range preds {
@@ -176,3 +490,12 @@ func BuildRemoved(h RemovedHandler, preds ...Predicate) RemovedHandler {
 	}
 	return h
 }
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
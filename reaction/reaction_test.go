@@ -2,10 +2,12 @@ package reaction_test
 
 import (
 	"context"
+	stderrors "errors"
 	"regexp"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 
 	"github.com/genkami/go-slack-event-router/errors"
@@ -205,6 +207,24 @@ var _ = Describe("Reaction", func() {
 					Expect(numHandlerCalled).To(Equal(0))
 				})
 			})
+
+			Context("When the reaction's name is any of the predicate's", func() {
+				It("calls the inner handler", func() {
+					h := reaction.Name("smile", "sob").WrapAdded(innerAddedHandler)
+					e := &slackevents.ReactionAddedEvent{
+						Reaction: "sob",
+					}
+					err := h.HandleReactionAddedEvent(ctx, e)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(numHandlerCalled).To(Equal(1))
+				})
+			})
+
+			Context("When no name is given", func() {
+				It("panics", func() {
+					Expect(func() { reaction.Name() }).To(Panic())
+				})
+			})
 		})
 
 		Describe("WrapRemoved", func() {
@@ -455,4 +475,286 @@ var _ = Describe("Reaction", func() {
 			})
 		})
 	})
+
+	Describe("ItemType", func() {
+		Describe("WrapAdded", func() {
+			Context("When the type of the reacted item is any of the given ones", func() {
+				It("calls the inner handler", func() {
+					h := reaction.ItemType("message", "file").WrapAdded(innerAddedHandler)
+					e := &slackevents.ReactionAddedEvent{
+						Reaction: "smile",
+						Item:     slackevents.Item{Type: "file"},
+					}
+					err := h.HandleReactionAddedEvent(ctx, e)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(numHandlerCalled).To(Equal(1))
+				})
+			})
+
+			Context("When the type of the reacted item is different from the given ones", func() {
+				It("does not call the inner handler", func() {
+					h := reaction.ItemType("message", "file").WrapAdded(innerAddedHandler)
+					e := &slackevents.ReactionAddedEvent{
+						Reaction: "smile",
+						Item:     slackevents.Item{Type: "file_comment"},
+					}
+					err := h.HandleReactionAddedEvent(ctx, e)
+					Expect(err).To(Equal(errors.NotInterested))
+					Expect(numHandlerCalled).To(Equal(0))
+				})
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			Context("When the type of the reacted item is any of the given ones", func() {
+				It("calls the inner handler", func() {
+					h := reaction.ItemType("message", "file").WrapRemoved(innerRemovedHandler)
+					e := &slackevents.ReactionRemovedEvent{
+						Reaction: "smile",
+						Item:     slackevents.Item{Type: "file"},
+					}
+					err := h.HandleReactionRemovedEvent(ctx, e)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(numHandlerCalled).To(Equal(1))
+				})
+			})
+
+			Context("When the type of the reacted item is different from the given ones", func() {
+				It("does not call the inner handler", func() {
+					h := reaction.ItemType("message", "file").WrapRemoved(innerRemovedHandler)
+					e := &slackevents.ReactionRemovedEvent{
+						Reaction: "smile",
+						Item:     slackevents.Item{Type: "file_comment"},
+					}
+					err := h.HandleReactionRemovedEvent(ctx, e)
+					Expect(err).To(Equal(errors.NotInterested))
+					Expect(numHandlerCalled).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Describe("User", func() {
+		Describe("WrapAdded", func() {
+			Context("When the user who reacted is any of the given ones", func() {
+				It("calls the inner handler", func() {
+					h := reaction.User("XXX", "YYY").WrapAdded(innerAddedHandler)
+					e := &slackevents.ReactionAddedEvent{
+						Reaction: "smile",
+						User:     "YYY",
+					}
+					err := h.HandleReactionAddedEvent(ctx, e)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(numHandlerCalled).To(Equal(1))
+				})
+			})
+
+			Context("When the user who reacted is different from the given ones", func() {
+				It("does not call the inner handler", func() {
+					h := reaction.User("XXX", "YYY").WrapAdded(innerAddedHandler)
+					e := &slackevents.ReactionAddedEvent{
+						Reaction: "smile",
+						User:     "ZZZ",
+					}
+					err := h.HandleReactionAddedEvent(ctx, e)
+					Expect(err).To(Equal(errors.NotInterested))
+					Expect(numHandlerCalled).To(Equal(0))
+				})
+			})
+		})
+
+		Describe("WrapRemoved", func() {
+			Context("When the user who reacted is any of the given ones", func() {
+				It("calls the inner handler", func() {
+					h := reaction.User("XXX", "YYY").WrapRemoved(innerRemovedHandler)
+					e := &slackevents.ReactionRemovedEvent{
+						Reaction: "smile",
+						User:     "YYY",
+					}
+					err := h.HandleReactionRemovedEvent(ctx, e)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(numHandlerCalled).To(Equal(1))
+				})
+			})
+
+			Context("When the user who reacted is different from the given ones", func() {
+				It("does not call the inner handler", func() {
+					h := reaction.User("XXX", "YYY").WrapRemoved(innerRemovedHandler)
+					e := &slackevents.ReactionRemovedEvent{
+						Reaction: "smile",
+						User:     "ZZZ",
+					}
+					err := h.HandleReactionRemovedEvent(ctx, e)
+					Expect(err).To(Equal(errors.NotInterested))
+					Expect(numHandlerCalled).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Describe("Not", func() {
+		Context("when the given predicate does not match", func() {
+			It("calls the inner handler", func() {
+				pred := reaction.Not(reaction.Name("smile"))
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "thumbsup"})
+				Expect(err).NotTo(HaveOccurred())
+				err = pred.WrapRemoved(innerRemovedHandler).HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "thumbsup"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the given predicate matches", func() {
+			It("does not call the inner handler", func() {
+				pred := reaction.Not(reaction.Name("smile"))
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile"})
+				Expect(err).To(Equal(errors.NotInterested))
+				err = pred.WrapRemoved(innerRemovedHandler).HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "smile"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Any", func() {
+		Context("when one of the given predicates matches", func() {
+			It("calls the inner handler exactly once", func() {
+				pred := reaction.Any(reaction.Name("smile"), reaction.User("U12345"))
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile", User: "U12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				err = pred.WrapRemoved(innerRemovedHandler).HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "thumbsup", User: "U12345"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(2))
+			})
+		})
+
+		Context("when none of the given predicates match", func() {
+			It("does not call the inner handler", func() {
+				pred := reaction.Any(reaction.Name("smile"), reaction.User("U12345"))
+				err := pred.WrapAdded(innerAddedHandler).HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "thumbsup", User: "U67890"})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when no predicate is given", func() {
+			It("panics", func() {
+				Expect(func() { reaction.Any() }).To(Panic())
+			})
+		})
+	})
+
+	Describe("CountAtLeast", func() {
+		var (
+			client *stubReactionsGetter
+			item   = slackevents.Item{Type: "message", Channel: "C12345", Timestamp: "1234567890.123456"}
+		)
+		BeforeEach(func() {
+			client = &stubReactionsGetter{reactions: []slack.ItemReaction{{Name: "smile", Count: 2}, {Name: "pushpin", Count: 5}}}
+			ctx = reaction.WithClient(ctx, client)
+		})
+
+		Context("when the count reaches the threshold", func() {
+			It("calls the inner handler", func() {
+				h := reaction.CountAtLeast(5).WrapAdded(innerAddedHandler)
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "pushpin", Item: item})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(client.items).To(Equal([]slack.ItemRef{slack.NewRefToMessage("C12345", "1234567890.123456")}))
+			})
+		})
+
+		Context("when the count is below the threshold", func() {
+			It("does not call the inner handler", func() {
+				h := reaction.CountAtLeast(5).WrapAdded(innerAddedHandler)
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "smile", Item: item})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the item has no reactions of the emoji", func() {
+			It("does not call the inner handler", func() {
+				h := reaction.CountAtLeast(1).WrapRemoved(innerRemovedHandler)
+				err := h.HandleReactionRemovedEvent(ctx, &slackevents.ReactionRemovedEvent{Reaction: "tada", Item: item})
+				Expect(err).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when reactions are added rapidly after the threshold", func() {
+			It("uses the cached count", func() {
+				h := reaction.CountAtLeast(5).WrapAdded(innerAddedHandler)
+				e := &slackevents.ReactionAddedEvent{Reaction: "pushpin", Item: item}
+				Expect(h.HandleReactionAddedEvent(ctx, e)).To(Succeed())
+				Expect(h.HandleReactionAddedEvent(ctx, e)).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(2))
+				Expect(client.items).To(HaveLen(1))
+			})
+		})
+
+		Context("when a reaction is added while the cached count is below the threshold", func() {
+			It("gets the count again", func() {
+				h := reaction.CountAtLeast(3).WrapAdded(innerAddedHandler)
+				e := &slackevents.ReactionAddedEvent{Reaction: "smile", Item: item}
+				Expect(h.HandleReactionAddedEvent(ctx, e)).To(Equal(errors.NotInterested))
+				client.reactions[0].Count = 3
+				Expect(h.HandleReactionAddedEvent(ctx, e)).To(Succeed())
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(client.items).To(HaveLen(2))
+			})
+		})
+
+		Context("when a reaction is removed while the cached count reaches the threshold", func() {
+			It("gets the count again", func() {
+				h := reaction.CountAtLeast(5).WrapRemoved(innerRemovedHandler)
+				e := &slackevents.ReactionRemovedEvent{Reaction: "pushpin", Item: item}
+				Expect(h.HandleReactionRemovedEvent(ctx, e)).To(Succeed())
+				client.reactions[1].Count = 4
+				Expect(h.HandleReactionRemovedEvent(ctx, e)).To(Equal(errors.NotInterested))
+				Expect(numHandlerCalled).To(Equal(1))
+				Expect(client.items).To(HaveLen(2))
+			})
+		})
+
+		Context("when no client is given", func() {
+			It("returns ErrNoClient", func() {
+				h := reaction.CountAtLeast(5).WrapAdded(innerAddedHandler)
+				err := h.HandleReactionAddedEvent(context.Background(), &slackevents.ReactionAddedEvent{Reaction: "pushpin", Item: item})
+				Expect(stderrors.Is(err, reaction.ErrNoClient)).To(BeTrue())
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when the API returns an error", func() {
+			It("returns the error", func() {
+				client.err = stderrors.New("ratelimited")
+				h := reaction.CountAtLeast(5).WrapAdded(innerAddedHandler)
+				err := h.HandleReactionAddedEvent(ctx, &slackevents.ReactionAddedEvent{Reaction: "pushpin", Item: item})
+				Expect(stderrors.Is(err, client.err)).To(BeTrue())
+				Expect(numHandlerCalled).To(Equal(0))
+			})
+		})
+
+		Context("when n is not positive", func() {
+			It("panics", func() {
+				Expect(func() { reaction.CountAtLeast(0) }).To(Panic())
+			})
+		})
+	})
 })
+
+type stubReactionsGetter struct {
+	reactions []slack.ItemReaction
+	err       error
+	items     []slack.ItemRef
+}
+
+func (c *stubReactionsGetter) GetReactionsContext(_ context.Context, item slack.ItemRef, _ slack.GetReactionsParameters) ([]slack.ItemReaction, error) {
+	c.items = append(c.items, item)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return append([]slack.ItemReaction(nil), c.reactions...), nil
+}
@@ -4,54 +4,276 @@
 package signature
 
 import (
-	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/slack-go/slack"
+	"github.com/pkg/errors"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	"github.com/genkami/go-slack-event-router/internal/routerutils"
+)
+
+const (
+	headerTimestamp = "X-Slack-Request-Timestamp"
+	headerSignature = "X-Slack-Signature"
+)
+
+// DefaultTolerance is the default value of how old request timestamps may be, which is recommended by Slack.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingHeader indicates that a request does not have either a signature or a timestamp.
+	ErrMissingHeader = errors.New("missing signature or timestamp header")
+
+	// ErrMalformedHeader indicates that a request has a signature or a timestamp that can't be parsed.
+	ErrMalformedHeader = errors.New("malformed signature or timestamp header")
+
+	// ErrExpired indicates that the timestamp of a request is too old (or too new).
+	ErrExpired = errors.New("timestamp is too old")
+
+	// ErrMismatch indicates that a request is not signed with any of the signing secrets.
+	ErrMismatch = errors.New("signature mismatch")
+
+	// ErrBodyConsumed indicates that the body of a request has been consumed (e.g. by `r.ParseForm()`) before the verification.
+	ErrBodyConsumed = errors.New("request body has been consumed before signature verification")
 )
 
+// Verifier verifies request signatures.
+//
+// This can be used to verify requests outside of http.Handler, e.g. in AWS Lambda functions.
+type Verifier struct {
+	// SigningSecrets are signing secrets.
+	// A request is considered to be valid if it is signed with any of them.
+	SigningSecrets []string
+
+	// Tolerance is the maximum difference between the request timestamp and the current time.
+	// If this is zero, DefaultTolerance is used.
+	Tolerance time.Duration
+}
+
+// Verify verifies the signature of a request with the given headers and body.
+//
+// It returns an error that equals to one of ErrMissingHeader, ErrMalformedHeader, ErrExpired, and ErrMismatch in the sense of `errors.Is`
+// if the request is invalid.
+func (v *Verifier) Verify(h http.Header, body []byte, now time.Time) error {
+	strSignature := h.Get(headerSignature)
+	if strSignature == "" {
+		return errors.WithMessagef(ErrMissingHeader, "%s is not set", headerSignature)
+	}
+	strTimestamp := h.Get(headerTimestamp)
+	if strTimestamp == "" {
+		return errors.WithMessagef(ErrMissingHeader, "%s is not set", headerTimestamp)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(strSignature, "v0="))
+	if err != nil {
+		return errors.WithMessagef(ErrMalformedHeader, "%s: %s", headerSignature, err.Error())
+	}
+	timestamp, err := strconv.ParseInt(strTimestamp, 10, 64)
+	if err != nil {
+		return errors.WithMessagef(ErrMalformedHeader, "%s: %s", headerTimestamp, err.Error())
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	diff := now.Sub(time.Unix(timestamp, 0))
+	if diff > tolerance || diff < -tolerance {
+		return errors.WithMessagef(ErrExpired, "%s differs from the current time by %s (tolerance: %s)", headerTimestamp, diff, tolerance)
+	}
+
+	for _, secret := range v.SigningSecrets {
+		hash := hmac.New(sha256.New, []byte(secret))
+		_, _ = hash.Write([]byte(fmt.Sprintf("v0:%s:", strTimestamp)))
+		_, _ = hash.Write(body)
+		if hmac.Equal(hash.Sum(nil), signature) {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
 // Middleware is an `http.Handler` middleware that automatically verifies request signatures.
+//
+// Signatures are computed over the raw bodies, so the middleware always verifies the exact bytes it receives,
+// both for JSON bodies (Events API) and form bodies (interactions and slash commands); it never re-encodes parsed forms,
+// which would change the order of keys or the escaping. For the same reason, handlers in front of the middleware
+// must not read the body, e.g. with `r.ParseForm()` or `r.FormValue()`, since it consumes the body.
+// If the body has been parsed as a form before the verification, the middleware responds with Internal Server Error,
+// and OnError is called with an error that equals to ErrBodyConsumed in the sense of `errors.Is`.
+// Handlers behind the middleware can read the body again.
 type Middleware struct {
 	// Secret is a signing secret.
 	//
 	// You can find this value by following this instruction: https://api.slack.com/authentication/verifying-requests-from-slack#signing_secrets_admin_page
 	SigningSecret string
 
-	// If set to true, the middleware puts error details to the response body when it fails verification.
+	// SigningSecrets are additional signing secrets.
+	// A request is considered to be valid if it is signed with any of SigningSecret and SigningSecrets.
+	//
+	// This is useful to rotate signing secrets without downtime.
+	// Note that the cost of verification increases in proportion to the number of the secrets, since the middleware tries all of them.
+	SigningSecrets []string
+
+	// SigningSecretFunc returns an additional signing secret, if set.
+	// It is called for each request, so that secrets can be rotated without restarting the process.
+	// Wrap it with CacheSecret if it is expensive, e.g. it fetches the secret from a vault.
+	//
+	// If it returns an error, the middleware responds with Internal Server Error.
+	SigningSecretFunc func() ([]byte, error)
+
+	// Tolerance is the maximum difference between the request timestamp and the current time.
+	// If this is zero, DefaultTolerance is used.
+	Tolerance time.Duration
+
+	// Clock returns the current time, which is compared with request timestamps.
+	// If this is nil, time.Now is used. This is useful to make tests deterministic.
+	Clock func() time.Time
+
+	// If set to true, the middleware puts error details to the response body when it fails verification,
+	// e.g. which header is missing or how old the timestamp is.
+	// Otherwise the response body is empty so as not to give any hints to attackers.
 	VerboseResponse bool
 
 	// Handler is an internal handler to perform actual request processing.
 	Handler http.Handler
+
+	// OnError is called with the cause when the middleware rejects a request, if set.
+	OnError func(*http.Request, error)
+
+	// StartSpan is called before the verification with SpanName, if set, e.g. to start a tracing span.
+	// The returned function is called with the result of the verification before Handler is called.
+	StartSpan func(ctx context.Context, name string) func(error)
 }
 
+// SpanName is the name given to Middleware.StartSpan.
+const SpanName = "slack.verify_signature"
+
 func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	verifier, err := slack.NewSecretsVerifier(r.Header, m.SigningSecret)
+	var endSpan func(error)
+	if m.StartSpan != nil {
+		endSpan = m.StartSpan(r.Context(), SpanName)
+	}
+	status, reason, err := m.check(r)
+	if endSpan != nil {
+		endSpan(err)
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		m.onError(r, err)
+		w.WriteHeader(status)
 		if m.VerboseResponse {
-			fmt.Fprintf(w, "failed to initialize verifier: %s", err.Error())
+			fmt.Fprintf(w, "%s: %s", reason, err.Error())
 		}
 		return
 	}
-	tee := io.TeeReader(r.Body, &verifier)
-	body, err := ioutil.ReadAll(tee)
+	m.Handler.ServeHTTP(w, r)
+}
+
+// check verifies the request. If it fails, it returns an appropriate status code and the reason along with the error.
+func (m *Middleware) check(r *http.Request) (int, string, error) {
+	if r.PostForm != nil && !routerutils.IsBodyBuffered(r) {
+		return http.StatusInternalServerError, "failed to read request", ErrBodyConsumed
+	}
+	body, err := routerutils.ReadBody(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		if m.VerboseResponse {
-			fmt.Fprintf(w, "failed to read response: %s", err.Error())
-		}
-		return
+		return routererrors.HTTPStatus(err), "failed to read request", err
 	}
-	if err := verifier.Ensure(); err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		if m.VerboseResponse {
-			fmt.Fprintf(w, "verification failed: %s", err.Error())
+	secrets, err := m.secrets()
+	if err != nil {
+		return http.StatusInternalServerError, "failed to get signing secret", err
+	}
+	if status, err := m.verify(secrets, r.Header, body, m.now()); err != nil {
+		return status, "verification failed", err
+	}
+	return http.StatusOK, "", nil
+}
+
+// verify verifies the signature of the request and returns an appropriate status code if it fails.
+func (m *Middleware) verify(secrets []string, h http.Header, body []byte, now time.Time) (int, error) {
+	v := &Verifier{SigningSecrets: secrets, Tolerance: m.Tolerance}
+	err := v.Verify(h, body, now)
+	switch {
+	case err == nil:
+		return http.StatusOK, nil
+	case errors.Is(err, ErrMismatch):
+		return http.StatusUnauthorized, err
+	default:
+		return http.StatusBadRequest, err
+	}
+}
+
+func (m *Middleware) now() time.Time {
+	if m.Clock != nil {
+		return m.Clock()
+	}
+	return time.Now()
+}
+
+func (m *Middleware) secrets() ([]string, error) {
+	secrets := make([]string, 0, len(m.SigningSecrets)+2)
+	if m.SigningSecret != "" {
+		secrets = append(secrets, m.SigningSecret)
+	}
+	secrets = append(secrets, m.SigningSecrets...)
+	if m.SigningSecretFunc != nil {
+		secret, err := m.SigningSecretFunc()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get signing secret")
 		}
-		return
+		if len(secret) == 0 {
+			return nil, errors.New("SigningSecretFunc returned an empty signing secret")
+		}
+		secrets = append(secrets, string(secret))
 	}
-	r.Body = ioutil.NopCloser(bytes.NewReader(body))
-	m.Handler.ServeHTTP(w, r)
+	return secrets, nil
+}
+
+func (m *Middleware) onError(r *http.Request, err error) {
+	if m.OnError != nil {
+		m.OnError(r, err)
+	}
+}
+
+// CacheSecret wraps f so that the secret returned from f is reused for ttl.
+//
+// Errors returned from f are not cached, so f is called again for the next request.
+// Note that it may take up to ttl for a rotated secret to take effect.
+// If f is nil or ttl is not positive, f is returned as is.
+func CacheSecret(f func() ([]byte, error), ttl time.Duration) func() ([]byte, error) {
+	if f == nil || ttl <= 0 {
+		return f
+	}
+	c := &secretCache{f: f, ttl: ttl}
+	return c.get
+}
+
+type secretCache struct {
+	mu        sync.Mutex
+	f         func() ([]byte, error)
+	ttl       time.Duration
+	secret    []byte
+	expiresAt time.Time
+}
+
+func (c *secretCache) get() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.secret != nil && now.Before(c.expiresAt) {
+		return c.secret, nil
+	}
+	secret, err := c.f()
+	if err != nil {
+		return nil, err
+	}
+	c.secret = secret
+	c.expiresAt = now.Add(c.ttl)
+	return secret, nil
 }
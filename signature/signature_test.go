@@ -2,6 +2,7 @@ package signature_test
 
 import (
 	"bytes"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"time"
@@ -14,6 +15,77 @@ import (
 )
 
 var _ = Describe("Signature", func() {
+	Describe("Verifier", func() {
+		var (
+			token    = "THE_TOKEN"
+			content  = []byte(`{"body": "this is a request body"}`)
+			now      = time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+			verifier *signature.Verifier
+		)
+
+		BeforeEach(func() {
+			verifier = &signature.Verifier{SigningSecrets: []string{token}}
+		})
+
+		Context("when the signature is valid", func() {
+			It("returns nil", func() {
+				h := http.Header{}
+				err := testutils.AddSignature(h, []byte(token), content, now)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(h, content, now)).To(Succeed())
+			})
+		})
+
+		Context("when the headers are missing", func() {
+			It("returns ErrMissingHeader", func() {
+				h := http.Header{}
+				err := verifier.Verify(h, content, now)
+				Expect(errors.Is(err, signature.ErrMissingHeader)).To(BeTrue())
+			})
+		})
+
+		Context("when the signature is malformed", func() {
+			It("returns ErrMalformedHeader", func() {
+				h := http.Header{}
+				err := testutils.AddSignature(h, []byte(token), content, now)
+				Expect(err).NotTo(HaveOccurred())
+				h.Set(testutils.HeaderSignature, "WRONG_HEADER")
+				err = verifier.Verify(h, content, now)
+				Expect(errors.Is(err, signature.ErrMalformedHeader)).To(BeTrue())
+			})
+		})
+
+		Context("when the timestamp is too old", func() {
+			It("returns ErrExpired", func() {
+				h := http.Header{}
+				err := testutils.AddSignature(h, []byte(token), content, now.Add(-1*time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				err = verifier.Verify(h, content, now)
+				Expect(errors.Is(err, signature.ErrExpired)).To(BeTrue())
+			})
+		})
+
+		Context("when the request is signed with a wrong token", func() {
+			It("returns ErrMismatch", func() {
+				h := http.Header{}
+				err := testutils.AddSignature(h, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, now)
+				Expect(err).NotTo(HaveOccurred())
+				err = verifier.Verify(h, content, now)
+				Expect(errors.Is(err, signature.ErrMismatch)).To(BeTrue())
+			})
+		})
+
+		Context("when the body is tampered", func() {
+			It("returns ErrMismatch", func() {
+				h := http.Header{}
+				err := testutils.AddSignature(h, []byte(token), content, now)
+				Expect(err).NotTo(HaveOccurred())
+				err = verifier.Verify(h, []byte(`{"body": "this is a tampered body"}`), now)
+				Expect(errors.Is(err, signature.ErrMismatch)).To(BeTrue())
+			})
+		})
+	})
+
 	Describe("Middleware", func() {
 		var (
 			token        = "THE_TOKEN"
@@ -45,6 +117,52 @@ var _ = Describe("Signature", func() {
 			})
 		})
 
+		Context("when OnError is set and the request may be signed with wrong token", func() {
+			It("calls OnError", func() {
+				var gotError error
+				middleware.OnError = func(_ *http.Request, err error) {
+					gotError = err
+				}
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(gotError).To(HaveOccurred())
+			})
+		})
+
+		Context("when more than one secrets are given and the request is signed with the second one", func() {
+			It("calls the inner handler", func() {
+				middleware.SigningSecrets = []string{"THE_NEW_TOKEN"}
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte("THE_NEW_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when more than one secrets are given and the request is signed with none of them", func() {
+			It("responds with Unauthorized", func() {
+				middleware.SigningSecrets = []string{"THE_NEW_TOKEN"}
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
 		Context("when the request is not signed", func() {
 			It("responds with BadRequest", func() {
 				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
@@ -53,6 +171,7 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(ContainSubstring("X-Slack-Signature is not set"))
 			})
 		})
 
@@ -67,6 +186,7 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(ContainSubstring("malformed signature or timestamp header"))
 			})
 		})
 
@@ -80,6 +200,7 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(w.Body.String()).To(ContainSubstring("signature mismatch"))
 			})
 		})
 
@@ -94,6 +215,7 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(ContainSubstring("X-Slack-Request-Timestamp is not set"))
 			})
 		})
 
@@ -107,6 +229,247 @@ var _ = Describe("Signature", func() {
 				middleware.ServeHTTP(w, req)
 				resp := w.Result()
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(w.Body.String()).To(ContainSubstring("timestamp is too old"))
+			})
+		})
+
+		Context("when the timestamp is just inside the default tolerance", func() {
+			It("calls the inner handler", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-4*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is just outside the default tolerance", func() {
+			It("responds with BadRequest", func() {
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-6*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the timestamp is just inside the configured tolerance", func() {
+			It("calls the inner handler", func() {
+				middleware.Tolerance = 30 * time.Minute
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-29*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the timestamp is just outside the configured tolerance", func() {
+			It("responds with BadRequest", func() {
+				middleware.Tolerance = 30 * time.Minute
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-31*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when Clock is set and the timestamp is exactly at the tolerance", func() {
+			It("calls the inner handler", func() {
+				now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+				middleware.Clock = func() time.Time { return now }
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, now.Add(-signature.DefaultTolerance))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when Clock is set and the timestamp is one second older than the tolerance", func() {
+			It("responds with BadRequest", func() {
+				now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+				middleware.Clock = func() time.Time { return now }
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, now.Add(-signature.DefaultTolerance-time.Second))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the body has been parsed as a form before the verification", func() {
+			It("responds with InternalServerError without re-encoding the form", func() {
+				var gotError error
+				middleware.OnError = func(_ *http.Request, err error) {
+					gotError = err
+				}
+				formContent := []byte("payload=%7B%7D&token=abc")
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(formContent))
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				err = testutils.AddSignature(req.Header, []byte(token), formContent, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(req.ParseForm()).To(Succeed())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(errors.Is(gotError, signature.ErrBodyConsumed)).To(BeTrue())
+			})
+		})
+
+		Context("when VerboseResponse is not set and the verification fails", func() {
+			It("responds with an empty body", func() {
+				middleware.VerboseResponse = false
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte("OOPS_I_MISTOOK_THE_TOKEN"), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(w.Body.String()).To(BeEmpty())
+			})
+		})
+
+		Context("when the tolerance is tightened", func() {
+			It("responds with BadRequest", func() {
+				middleware.Tolerance = 10 * time.Second
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now().Add(-1*time.Minute))
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				resp := w.Result()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when SigningSecretFunc is set", func() {
+			It("verifies requests with the secret returned from the function", func() {
+				current := "FIRST_TOKEN"
+				middleware.SigningSecret = ""
+				middleware.SigningSecretFunc = func() ([]byte, error) {
+					return []byte(current), nil
+				}
+				serve := func(secret string) int {
+					req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+					Expect(err).NotTo(HaveOccurred())
+					err = testutils.AddSignature(req.Header, []byte(secret), content, time.Now())
+					Expect(err).NotTo(HaveOccurred())
+					w := httptest.NewRecorder()
+					middleware.ServeHTTP(w, req)
+					return w.Result().StatusCode
+				}
+				Expect(serve("FIRST_TOKEN")).To(Equal(http.StatusOK))
+				current = "SECOND_TOKEN"
+				Expect(serve("SECOND_TOKEN")).To(Equal(http.StatusOK))
+				Expect(serve("FIRST_TOKEN")).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("when SigningSecretFunc returns an error", func() {
+			It("responds with InternalServerError", func() {
+				var gotError error
+				middleware.OnError = func(_ *http.Request, err error) {
+					gotError = err
+				}
+				middleware.SigningSecretFunc = func() ([]byte, error) {
+					return nil, errors.New("vault is sealed")
+				}
+				req, err := http.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(content))
+				Expect(err).NotTo(HaveOccurred())
+				err = testutils.AddSignature(req.Header, []byte(token), content, time.Now())
+				Expect(err).NotTo(HaveOccurred())
+				w := httptest.NewRecorder()
+				middleware.ServeHTTP(w, req)
+				Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(gotError).To(MatchError(MatchRegexp("vault is sealed")))
+			})
+		})
+	})
+
+	Describe("CacheSecret", func() {
+		var (
+			numCalled int
+			secret    string
+			f         = func() ([]byte, error) {
+				numCalled++
+				return []byte(secret), nil
+			}
+		)
+		BeforeEach(func() {
+			numCalled = 0
+			secret = "FIRST_TOKEN"
+		})
+
+		Context("when the cache is fresh", func() {
+			It("returns the cached secret", func() {
+				cached := signature.CacheSecret(f, time.Hour)
+				Expect(cached()).To(Equal([]byte("FIRST_TOKEN")))
+				secret = "SECOND_TOKEN"
+				Expect(cached()).To(Equal([]byte("FIRST_TOKEN")))
+				Expect(numCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the cache expires", func() {
+			It("calls the function again", func() {
+				cached := signature.CacheSecret(f, 10*time.Millisecond)
+				Expect(cached()).To(Equal([]byte("FIRST_TOKEN")))
+				secret = "SECOND_TOKEN"
+				time.Sleep(20 * time.Millisecond)
+				Expect(cached()).To(Equal([]byte("SECOND_TOKEN")))
+				Expect(numCalled).To(Equal(2))
+			})
+		})
+
+		Context("when the function returns an error", func() {
+			It("does not cache the error", func() {
+				fail := true
+				cached := signature.CacheSecret(func() ([]byte, error) {
+					numCalled++
+					if fail {
+						return nil, errors.New("vault is sealed")
+					}
+					return []byte("THE_TOKEN"), nil
+				}, time.Hour)
+				_, err := cached()
+				Expect(err).To(HaveOccurred())
+				fail = false
+				Expect(cached()).To(Equal([]byte("THE_TOKEN")))
+				Expect(numCalled).To(Equal(2))
+			})
+		})
+
+		Context("when ttl is zero", func() {
+			It("calls the function every time", func() {
+				cached := signature.CacheSecret(f, 0)
+				_, _ = cached()
+				_, _ = cached()
+				Expect(numCalled).To(Equal(2))
 			})
 		})
 	})
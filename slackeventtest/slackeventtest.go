@@ -0,0 +1,204 @@
+// Package slackeventtest provides helpers to test handlers with requests that look like the ones sent from Slack.
+//
+// The requests are signed in the same way as Slack does, so they can be passed to Routers with signature verification enabled.
+//
+//	callback := slackeventtest.NewShortcutCallback("shortcut_create_task")
+//	req, err := slackeventtest.NewInteractionRequest(signingSecret, callback)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	w := httptest.NewRecorder()
+//	router.ServeHTTP(w, req)
+package slackeventtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	"github.com/genkami/go-slack-event-router/internal/testutils"
+)
+
+// URL is the URL that requests built by this package are sent to.
+const URL = "http://example.com/slack"
+
+// RequestOption configures requests built by this package.
+type RequestOption interface {
+	apply(*requestConfig)
+}
+
+type requestOptionFunc func(*requestConfig)
+
+func (f requestOptionFunc) apply(c *requestConfig) {
+	f(c)
+}
+
+type requestConfig struct {
+	timestamp time.Time
+	tamper    bool
+	unsigned  bool
+}
+
+// WithTimestamp signs requests as if they were sent at t.
+// This is useful to build requests with expired timestamps, e.g. `WithTimestamp(time.Now().Add(-time.Hour))`.
+// If this is not set, the current time is used.
+func WithTimestamp(t time.Time) RequestOption {
+	return requestOptionFunc(func(c *requestConfig) {
+		c.timestamp = t
+	})
+}
+
+// WithTamperedSignature makes requests have well-formed but wrong signatures, as if their bodies were tampered after they were signed.
+func WithTamperedSignature() RequestOption {
+	return requestOptionFunc(func(c *requestConfig) {
+		c.tamper = true
+	})
+}
+
+// Unsigned makes requests have no signatures. This is useful to test Routers with InsecureSkipVerification.
+func Unsigned() RequestOption {
+	return requestOptionFunc(func(c *requestConfig) {
+		c.unsigned = true
+	})
+}
+
+// NewRequest builds a POST request with the given body and Content-Type, signed with signingSecret.
+func NewRequest(signingSecret string, contentType string, body []byte, opts ...RequestOption) (*http.Request, error) {
+	c := &requestConfig{timestamp: time.Now()}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	req, err := http.NewRequest(http.MethodPost, URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.unsigned {
+		return req, nil
+	}
+	signedBody := body
+	if c.tamper {
+		signedBody = append(append([]byte(nil), body...), "tampered"...)
+	}
+	if err := testutils.AddSignature(req.Header, []byte(signingSecret), signedBody, c.timestamp); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// NewInteractionRequest builds a request that delivers the given interaction callback, signed with signingSecret.
+func NewInteractionRequest(signingSecret string, callback *slack.InteractionCallback, opts ...RequestOption) (*http.Request, error) {
+	payload, err := json.Marshal(callback)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("payload", string(payload))
+	return NewRequest(signingSecret, "application/x-www-form-urlencoded", []byte(form.Encode()), opts...)
+}
+
+// NewEventRequest builds a request that delivers the given inner event (e.g. *slackevents.MessageEvent) of the Events API, signed with signingSecret.
+//
+// The inner event is wrapped with an `event_callback`. Note that its `type` field must be set, since the Routers dispatch events by it.
+func NewEventRequest(signingSecret string, innerEvent interface{}, opts ...RequestOption) (*http.Request, error) {
+	body, err := NewEventCallback(innerEvent)
+	if err != nil {
+		return nil, err
+	}
+	return NewRequest(signingSecret, "application/json", body, opts...)
+}
+
+// NewCommandRequest builds a request that delivers the given slash command, signed with signingSecret.
+func NewCommandRequest(signingSecret string, cmd *slack.SlashCommand, opts ...RequestOption) (*http.Request, error) {
+	form := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			form.Set(key, value)
+		}
+	}
+	set("token", cmd.Token)
+	set("team_id", cmd.TeamID)
+	set("team_domain", cmd.TeamDomain)
+	set("enterprise_id", cmd.EnterpriseID)
+	set("enterprise_name", cmd.EnterpriseName)
+	set("channel_id", cmd.ChannelID)
+	set("channel_name", cmd.ChannelName)
+	set("user_id", cmd.UserID)
+	set("user_name", cmd.UserName)
+	set("command", cmd.Command)
+	set("text", cmd.Text)
+	set("response_url", cmd.ResponseURL)
+	set("trigger_id", cmd.TriggerID)
+	set("api_app_id", cmd.APIAppID)
+	return NewRequest(signingSecret, "application/x-www-form-urlencoded", []byte(form.Encode()), opts...)
+}
+
+// NewEventCallback builds the body of an `event_callback` of the Events API that wraps the given inner event.
+func NewEventCallback(innerEvent interface{}) ([]byte, error) {
+	inner, err := json.Marshal(innerEvent)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return json.Marshal(&struct {
+		Type      string          `json:"type"`
+		EventID   string          `json:"event_id"`
+		EventTime int64           `json:"event_time"`
+		Event     json.RawMessage `json:"event"`
+	}{
+		Type:      slackevents.CallbackEvent,
+		EventID:   fmt.Sprintf("Ev%d", now.UnixNano()),
+		EventTime: now.Unix(),
+		Event:     inner,
+	})
+}
+
+// NewMessageEvent builds a `message` event posted by the user in the channel.
+func NewMessageEvent(channel, user, text string) *slackevents.MessageEvent {
+	ts := timestamp(time.Now())
+	return &slackevents.MessageEvent{
+		Type:           string(slackevents.Message),
+		Channel:        channel,
+		User:           user,
+		Text:           text,
+		TimeStamp:      ts,
+		EventTimeStamp: json.Number(ts),
+	}
+}
+
+// NewBlockActionsCallback builds a `block_actions` callback with the given block actions.
+func NewBlockActionsCallback(actions ...*slack.BlockAction) *slack.InteractionCallback {
+	return &slack.InteractionCallback{
+		Type:           slack.InteractionTypeBlockActions,
+		ActionTs:       timestamp(time.Now()),
+		ActionCallback: slack.ActionCallbacks{BlockActions: actions},
+	}
+}
+
+// NewShortcutCallback builds a `shortcut` (global shortcut) callback with the given callback ID.
+func NewShortcutCallback(callbackID string) *slack.InteractionCallback {
+	return &slack.InteractionCallback{
+		Type:       slack.InteractionTypeShortcut,
+		CallbackID: callbackID,
+		ActionTs:   timestamp(time.Now()),
+	}
+}
+
+// NewViewSubmissionCallback builds a `view_submission` callback of the given view.
+func NewViewSubmissionCallback(view slack.View) *slack.InteractionCallback {
+	return &slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		View: view,
+	}
+}
+
+func timestamp(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10) + "." + fmt.Sprintf("%06d", t.Nanosecond()/1000)
+}
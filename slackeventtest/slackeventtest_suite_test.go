@@ -0,0 +1,13 @@
+package slackeventtest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlackeventtest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Slackeventtest Suite")
+}
@@ -0,0 +1,155 @@
+package slackeventtest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+
+	eventrouter "github.com/genkami/go-slack-event-router"
+	"github.com/genkami/go-slack-event-router/command"
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/message"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/slackeventtest"
+)
+
+var _ = Describe("Slackeventtest", func() {
+	var (
+		token = "THE_TOKEN"
+	)
+
+	Describe("NewRequest", func() {
+		verify := func(req *http.Request) error {
+			body, err := ioutil.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			v := &signature.Verifier{SigningSecrets: []string{token}}
+			return v.Verify(req.Header, body, time.Now())
+		}
+
+		It("builds a request with a valid signature", func() {
+			req, err := slackeventtest.NewRequest(token, "application/json", []byte(`{}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(verify(req)).To(Succeed())
+		})
+
+		Context("when WithTimestamp is given", func() {
+			It("builds a request with the given timestamp", func() {
+				req, err := slackeventtest.NewRequest(token, "application/json", []byte(`{}`), slackeventtest.WithTimestamp(time.Now().Add(-time.Hour)))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verify(req)).To(MatchError(signature.ErrExpired))
+			})
+		})
+
+		Context("when WithTamperedSignature is given", func() {
+			It("builds a request with a wrong signature", func() {
+				req, err := slackeventtest.NewRequest(token, "application/json", []byte(`{}`), slackeventtest.WithTamperedSignature())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verify(req)).To(MatchError(signature.ErrMismatch))
+			})
+		})
+
+		Context("when Unsigned is given", func() {
+			It("builds a request without a signature", func() {
+				req, err := slackeventtest.NewRequest(token, "application/json", []byte(`{}`), slackeventtest.Unsigned())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verify(req)).To(MatchError(signature.ErrMissingHeader))
+			})
+		})
+	})
+
+	Describe("NewInteractionRequest", func() {
+		It("builds a request that the interaction router accepts", func() {
+			r, err := ir.New(ir.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var matched *slack.BlockAction
+			r.OnBlockAction("BLOCK_ID", "ACTION_ID", ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				matched = ir.MatchedAction(ctx)
+				return nil
+			}))
+			callback := slackeventtest.NewBlockActionsCallback(&slack.BlockAction{BlockID: "BLOCK_ID", ActionID: "ACTION_ID", Value: "approve"})
+			req, err := slackeventtest.NewInteractionRequest(token, callback)
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(matched).NotTo(BeNil())
+			Expect(matched.Value).To(Equal("approve"))
+		})
+
+		It("builds a request that the interaction router rejects when the signature is tampered", func() {
+			r, err := ir.New(ir.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			req, err := slackeventtest.NewInteractionRequest(token, slackeventtest.NewShortcutCallback("shortcut_create_task"), slackeventtest.WithTamperedSignature())
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("builds a view_submission request", func() {
+			r, err := ir.New(ir.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var callbackID string
+			r.OnViewSubmission("create_task", ir.ViewSubmissionHandlerFunc(func(_ context.Context, callback *slack.InteractionCallback) (*slack.ViewSubmissionResponse, error) {
+				callbackID = callback.View.CallbackID
+				return nil, nil
+			}))
+			req, err := slackeventtest.NewInteractionRequest(token, slackeventtest.NewViewSubmissionCallback(slack.View{CallbackID: "create_task"}))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(callbackID).To(Equal("create_task"))
+		})
+	})
+
+	Describe("NewEventRequest", func() {
+		It("builds a request that the event router accepts", func() {
+			r, err := eventrouter.New(eventrouter.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *slackevents.MessageEvent
+			r.OnMessage(message.HandlerFunc(func(_ context.Context, e *slackevents.MessageEvent) error {
+				received = e
+				return nil
+			}))
+			req, err := slackeventtest.NewEventRequest(token, slackeventtest.NewMessageEvent("C12345", "U12345", "hello"))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.Channel).To(Equal("C12345"))
+			Expect(received.User).To(Equal("U12345"))
+			Expect(received.Text).To(Equal("hello"))
+			Expect(received.TimeStamp).NotTo(BeEmpty())
+		})
+	})
+
+	Describe("NewCommandRequest", func() {
+		It("builds a request that the command router accepts", func() {
+			r, err := command.New(command.WithSigningSecret(token))
+			Expect(err).NotTo(HaveOccurred())
+			var received *slack.SlashCommand
+			r.OnCommand("/deploy", command.HandlerFunc(func(_ context.Context, cmd *slack.SlashCommand) (*slack.Msg, error) {
+				received = cmd
+				return nil, nil
+			}))
+			req, err := slackeventtest.NewCommandRequest(token, &slack.SlashCommand{Command: "/deploy", Text: "api", UserID: "U12345"})
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(received).NotTo(BeNil())
+			Expect(received.Text).To(Equal("api"))
+			Expect(received.UserID).To(Equal("U12345"))
+		})
+	})
+})
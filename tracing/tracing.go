@@ -0,0 +1,139 @@
+// Package tracing provides OpenTelemetry instrumentation for routers.
+//
+// This is a separate package so that applications that do not use OpenTelemetry do not have to depend on it.
+//
+//	r, err := interactionrouter.New(
+//		interactionrouter.WithSigningSecret("YOUR_SIGNING_SECRET"),
+//		interactionrouter.WithSpanStarter(tracing.SpanStarter()),
+//	)
+//	// ...
+//	r.Use(tracing.InteractionMiddleware())
+//	http.Handle("/slack/actions", tracing.Middleware(r))
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	routererrors "github.com/genkami/go-slack-event-router/errors"
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+)
+
+const instrumentationName = "github.com/genkami/go-slack-event-router/tracing"
+
+// Attribute keys set on spans.
+const (
+	InteractionTypeKey = attribute.Key("slack.interaction.type")
+	CallbackIDKey      = attribute.Key("slack.interaction.callback_id")
+)
+
+// Option configures the instrumentation.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+// WithTracerProvider sets a TracerProvider to create spans.
+//
+// If this is not set, the global TracerProvider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return optionFunc(func(c *config) {
+		c.tracerProvider = tp
+	})
+}
+
+// WithPropagator sets a propagator to extract trace context from request headers.
+//
+// If this is not set, the global propagator is used.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return optionFunc(func(c *config) {
+		c.propagator = p
+	})
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, o := range opts {
+		o.apply(c)
+	}
+	return c
+}
+
+// Middleware wraps h (typically `*eventrouter.Router` or `*interactionrouter.Router`) so that each request starts a span.
+//
+// The span covers everything the router does, including signature verification, parsing and dispatching.
+// Trace context propagated from request headers is used as the parent of the span.
+func Middleware(h http.Handler, opts ...Option) http.Handler {
+	c := newConfig(opts)
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := c.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(ctx, "slack.request", trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		h.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// SpanStarter returns a function that starts child spans around the stages of processing requests,
+// i.e. signature verification and payload decoding. Give it to `interactionrouter.WithSpanStarter`.
+//
+// The spans are named after the stages, and record errors of the stages as their status.
+func SpanStarter(opts ...Option) func(ctx context.Context, name string) func(error) {
+	c := newConfig(opts)
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	return func(ctx context.Context, name string) func(error) {
+		_, span := tracer.Start(ctx, name)
+		return func(err error) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	}
+}
+
+// InteractionMiddleware returns an `interactionrouter.Middleware` that starts a span around dispatching handlers.
+//
+// The span records the interaction type and the callback ID as attributes, and the error returned from handlers as its status.
+// The context passed to handlers carries the span, so that downstream calls join the trace.
+func InteractionMiddleware(opts ...Option) ir.Middleware {
+	c := newConfig(opts)
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	return func(h ir.Handler) ir.Handler {
+		return ir.HandlerFunc(func(ctx context.Context, callback *slack.InteractionCallback) error {
+			ctx, span := tracer.Start(ctx, "slack.interaction", trace.WithAttributes(
+				InteractionTypeKey.String(string(callback.Type)),
+				CallbackIDKey.String(callback.CallbackID),
+			))
+			defer span.End()
+			err := h.HandleInteraction(ctx, callback)
+			if err != nil && !errors.Is(err, routererrors.NotInterested) {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	ir "github.com/genkami/go-slack-event-router/interactionrouter"
+	"github.com/genkami/go-slack-event-router/internal/testutils"
+	"github.com/genkami/go-slack-event-router/signature"
+	"github.com/genkami/go-slack-event-router/tracing"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		recorder *tracetest.SpanRecorder
+		tp       *sdktrace.TracerProvider
+		r        *ir.Router
+		payload  = `{"type": "shortcut", "callback_id": "shortcut_create_task"}`
+	)
+	BeforeEach(func() {
+		var err error
+		recorder = tracetest.NewSpanRecorder()
+		tp = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		r, err = ir.New(ir.InsecureSkipVerification())
+		Expect(err).NotTo(HaveOccurred())
+		r.Use(tracing.InteractionMiddleware(tracing.WithTracerProvider(tp)))
+	})
+
+	newRequest := func() *http.Request {
+		form := url.Values{}
+		form.Set("payload", payload)
+		req := httptest.NewRequest(http.MethodPost, "/slack/actions", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	Context("when a request is processed", func() {
+		It("creates spans and passes the span to handlers", func() {
+			var handlerSpan trace.SpanContext
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(ctx context.Context, _ *slack.InteractionCallback) error {
+				handlerSpan = trace.SpanContextFromContext(ctx)
+				return nil
+			}))
+			h := tracing.Middleware(r, tracing.WithTracerProvider(tp))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newRequest())
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			interactionSpan, requestSpan := spans[0], spans[1]
+			Expect(requestSpan.Name()).To(Equal("slack.request"))
+			Expect(interactionSpan.Name()).To(Equal("slack.interaction"))
+			Expect(interactionSpan.Parent().SpanID()).To(Equal(requestSpan.SpanContext().SpanID()))
+			Expect(interactionSpan.Attributes()).To(ContainElements(
+				tracing.InteractionTypeKey.String("shortcut"),
+				tracing.CallbackIDKey.String("shortcut_create_task"),
+			))
+			Expect(interactionSpan.Status().Code).To(Equal(codes.Unset))
+			Expect(handlerSpan.SpanID()).To(Equal(interactionSpan.SpanContext().SpanID()))
+		})
+	})
+
+	Context("when the handler returns an error", func() {
+		It("records the error as the span status", func() {
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return errors.New("something wrong happened")
+			}))
+			h := tracing.Middleware(r, tracing.WithTracerProvider(tp))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newRequest())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+			Expect(spans[0].Status().Description).To(Equal("something wrong happened"))
+		})
+	})
+
+	Context("when SpanStarter is given to the Router", func() {
+		var (
+			secret = "THE_SECRET"
+			signed = func(secret string) *http.Request {
+				req := newRequest()
+				form := url.Values{}
+				form.Set("payload", payload)
+				Expect(testutils.AddSignature(req.Header, []byte(secret), []byte(form.Encode()), time.Now())).To(Succeed())
+				return req
+			}
+		)
+		BeforeEach(func() {
+			var err error
+			r, err = ir.New(ir.WithSigningSecret(secret), ir.WithSpanStarter(tracing.SpanStarter(tracing.WithTracerProvider(tp))))
+			Expect(err).NotTo(HaveOccurred())
+			r.On(slack.InteractionTypeShortcut, ir.HandlerFunc(func(_ context.Context, _ *slack.InteractionCallback) error {
+				return nil
+			}))
+		})
+
+		It("creates child spans around signature verification and payload decoding", func() {
+			h := tracing.Middleware(r, tracing.WithTracerProvider(tp))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, signed(secret))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(3))
+			verifySpan, decodeSpan, requestSpan := spans[0], spans[1], spans[2]
+			Expect(verifySpan.Name()).To(Equal(signature.SpanName))
+			Expect(decodeSpan.Name()).To(Equal(ir.DecodeSpanName))
+			Expect(requestSpan.Name()).To(Equal("slack.request"))
+			Expect(verifySpan.Parent().SpanID()).To(Equal(requestSpan.SpanContext().SpanID()))
+			Expect(decodeSpan.Parent().SpanID()).To(Equal(requestSpan.SpanContext().SpanID()))
+			Expect(verifySpan.Status().Code).To(Equal(codes.Unset))
+			Expect(decodeSpan.Status().Code).To(Equal(codes.Unset))
+		})
+
+		It("records failures of the verification", func() {
+			h := tracing.Middleware(r, tracing.WithTracerProvider(tp))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, signed("WRONG_SECRET"))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusUnauthorized))
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name()).To(Equal(signature.SpanName))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		})
+	})
+
+	Context("when the request has a propagated trace context", func() {
+		It("uses it as the parent", func() {
+			propagator := propagation.TraceContext{}
+			h := tracing.Middleware(r, tracing.WithTracerProvider(tp), tracing.WithPropagator(propagator))
+			req := newRequest()
+			req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			spans := recorder.Ended()
+			requestSpan := spans[len(spans)-1]
+			Expect(requestSpan.SpanContext().TraceID().String()).To(Equal("0af7651916cd43dd8448eb211c80319c"))
+			Expect(requestSpan.Parent().SpanID().String()).To(Equal("b7ad6b7169203331"))
+		})
+	})
+})